@@ -0,0 +1,265 @@
+package keyvalues
+
+import (
+	"bytes"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// benchmarkVMF returns a map laid out as Hammer writes it, with a world of
+// solids brushes of six sides and entities point entities with outputs
+func benchmarkVMF(solids int, entities int) []byte {
+	var buf bytes.Buffer
+	buf.WriteString("versioninfo\n{\n\t\"editorversion\" \"400\"\n\t\"editorbuild\" \"8864\"\n\t\"mapversion\" \"12\"\n\t\"formatversion\" \"100\"\n\t\"prefab\" \"0\"\n}\n")
+	buf.WriteString("visgroups\n{\n}\nviewsettings\n{\n\t\"bSnapToGrid\" \"1\"\n\t\"bShowGrid\" \"1\"\n\t\"nGridSpacing\" \"64\"\n}\n")
+	buf.WriteString("world\n{\n\t\"id\" \"1\"\n\t\"mapversion\" \"12\"\n\t\"classname\" \"worldspawn\"\n\t\"skyname\" \"sky_day01_01\"\n\t\"maxpropscreenwidth\" \"-1\"\n")
+	id := 2
+	for s := 0; s < solids; s++ {
+		buf.WriteString("\tsolid\n\t{\n\t\t\"id\" \"" + strconv.Itoa(id) + "\"\n")
+		id++
+		for side := 0; side < 6; side++ {
+			x, y := strconv.Itoa(s*64), strconv.Itoa(side*64)
+			buf.WriteString("\t\tside\n\t\t{\n")
+			buf.WriteString("\t\t\t\"id\" \"" + strconv.Itoa(id) + "\"\n")
+			buf.WriteString("\t\t\t\"plane\" \"(" + x + " " + y + " 64) (" + x + " -" + y + " 64) (-" + x + " -" + y + " 64)\"\n")
+			buf.WriteString("\t\t\t\"material\" \"DEV/DEV_MEASUREGENERIC01B\"\n")
+			buf.WriteString("\t\t\t\"uaxis\" \"[1 0 0 0] 0.25\"\n\t\t\t\"vaxis\" \"[0 -1 0 0] 0.25\"\n")
+			buf.WriteString("\t\t\t\"rotation\" \"0\"\n\t\t\t\"lightmapscale\" \"16\"\n\t\t\t\"smoothing_groups\" \"0\"\n\t\t}\n")
+			id++
+		}
+		buf.WriteString("\t\teditor\n\t\t{\n\t\t\t\"color\" \"0 148 205\"\n\t\t\t\"visgroupshown\" \"1\"\n\t\t\t\"visgroupautoshown\" \"1\"\n\t\t}\n\t}\n")
+	}
+	buf.WriteString("}\n")
+	for e := 0; e < entities; e++ {
+		buf.WriteString("entity\n{\n\t\"id\" \"" + strconv.Itoa(id) + "\"\n\t\"classname\" \"light\"\n")
+		buf.WriteString("\t\"_light\" \"255 255 255 200\"\n\t\"_lightHDR\" \"-1 -1 -1 1\"\n\t\"style\" \"0\"\n")
+		buf.WriteString("\t\"origin\" \"" + strconv.Itoa(e*16) + " 128 64.5\"\n")
+		buf.WriteString("\tconnections\n\t{\n\t\t\"OnTrigger\" \"door,Open,,0,-1\"\n\t}\n")
+		buf.WriteString("\teditor\n\t{\n\t\t\"color\" \"220 30 220\"\n\t\t\"visgroupshown\" \"1\"\n\t\t\"logicalpos\" \"[0 500]\"\n\t}\n}\n")
+		id++
+	}
+	buf.WriteString("cameras\n{\n\t\"activecamera\" \"-1\"\n}\ncordon\n{\n\t\"mins\" \"(-1024 -1024 -1024)\"\n\t\"maxs\" \"(1024 1024 1024)\"\n\t\"active\" \"0\"\n}\n")
+	return buf.Bytes()
+}
+
+const benchmarkVMT = `"LightmappedGeneric"
+{
+	// base material
+	"$basetexture" "concrete/concretefloor001a"
+	"$bumpmap" "concrete/concretefloor001a_normal"
+	"$surfaceprop" "concrete"
+	"$envmap" "env_cubemap"
+	"$envmaptint" "[.3 .3 .3]"
+	"$envmapcontrast" "1"
+	"$normalmapalphaenvmapmask" 1
+	"%keywords" "tides"
+	"$detail" "detail/noise_detail_01"
+	"$detailscale" "4.283"
+	"$detailblendfactor" ".5"
+
+	"Proxies"
+	{
+		"TextureScroll"
+		{
+			"texturescrollvar" "$baseTextureTransform"
+			"texturescrollrate" 0.05
+			"texturescrollangle" 90
+		}
+	}
+}
+`
+
+const benchmarkGameInfo = `"GameInfo"
+{
+	game	"Half-Life 2"
+	title	"HALF-LIFE'"
+	title2	"== episode two =="
+	type	singleplayer_only
+	developer	"Valve"
+	developer_url	"http://www.valvesoftware.com"
+	icon	"resource/icon"
+	nodifficulty	0
+	hidden_maps
+	{
+		"test_speakers"	1
+		"test_hardware"	1
+	}
+
+	FileSystem
+	{
+		SteamAppId	220
+		ToolsAppId	211
+
+		SearchPaths
+		{
+			game+mod	hl2/hl2_sound_vo_english.vpk
+			game+mod	hl2/hl2_pak.vpk
+			game	|all_source_engine_paths|hl2/hl2_textures.vpk
+			game	|all_source_engine_paths|hl2/hl2_sound_misc.vpk
+			game	|all_source_engine_paths|hl2/hl2_misc.vpk
+			platform	|all_source_engine_paths|platform/platform_misc.vpk
+			mod+mod_write+default_write_path	|gameinfo_path|.
+			game+game_write	hl2
+			gamebin	hl2/bin
+			game	|all_source_engine_paths|hl2
+			platform	|all_source_engine_paths|platform
+		}
+	}
+}
+`
+
+func benchmarkRead(b *testing.B, data []byte) {
+	b.SetBytes(int64(len(data)))
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		reader := NewReader(bytes.NewReader(data))
+		if _, err := reader.Read(); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkReader_Read_VMF(b *testing.B) {
+	benchmarkRead(b, benchmarkVMF(500, 200))
+}
+
+func BenchmarkReader_Read_VMT(b *testing.B) {
+	benchmarkRead(b, []byte(benchmarkVMT))
+}
+
+func BenchmarkReader_Read_GameInfo(b *testing.B) {
+	benchmarkRead(b, []byte(benchmarkGameInfo))
+}
+
+func BenchmarkParser_Next_VMF(b *testing.B) {
+	data := benchmarkVMF(500, 200)
+	b.SetBytes(int64(len(data)))
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		parser := NewParser(bytes.NewReader(data))
+		if err := parser.Parse(func(event Event) error {
+			return nil
+		}); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkKeyValue_Find(b *testing.B) {
+	reader := NewReader(strings.NewReader(benchmarkGameInfo))
+	kv, err := reader.Read()
+	if err != nil {
+		b.Fatal(err)
+	}
+	fileSystem, err := kv.Find("FileSystem")
+	if err != nil {
+		b.Fatal(err)
+	}
+	searchPaths, err := fileSystem.Find("SearchPaths")
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err = searchPaths.Find("Platform"); err != nil {
+			b.Fatal(err)
+		}
+		if _, err = kv.Find("developer_url"); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkKeyValue_FindAll(b *testing.B) {
+	reader := NewReader(bytes.NewReader(benchmarkVMF(100, 0)))
+	kv, err := reader.Read()
+	if err != nil {
+		b.Fatal(err)
+	}
+	world, err := kv.Find("world")
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err = world.FindAll("solid"); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// BenchmarkKeyValue_FindLargeBlock looks up keys in a map with thousands of
+// entities, where scanning the root block for each lookup is slowest
+func BenchmarkKeyValue_FindLargeBlock(b *testing.B) {
+	reader := NewReader(bytes.NewReader(benchmarkVMF(10, 10000)))
+	kv, err := reader.Read()
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err = kv.Find("cameras"); err != nil {
+			b.Fatal(err)
+		}
+		if _, err = kv.Find("Cordon"); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkKeyValue_FindAllLargeBlock(b *testing.B) {
+	reader := NewReader(bytes.NewReader(benchmarkVMF(10000, 0)))
+	kv, err := reader.Read()
+	if err != nil {
+		b.Fatal(err)
+	}
+	world, err := kv.Find("world")
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err = world.FindAll("skyname"); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// TestBenchmarkCorpora checks that the benchmarks read what they are meant to
+func TestBenchmarkCorpora(t *testing.T) {
+	reader := NewReader(bytes.NewReader(benchmarkVMF(3, 2)))
+	kv, err := reader.Read()
+	if err != nil {
+		t.Fatal(err)
+	}
+	world, err := kv.Find("world")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if solids, _ := world.FindAll("solid"); len(solids) != 3 {
+		t.Errorf("expected 3 solids, got %d", len(solids))
+	}
+	if entities, _ := kv.FindAll("entity"); len(entities) != 2 {
+		t.Errorf("expected 2 entities, got %d", len(entities))
+	}
+
+	for _, data := range []string{benchmarkVMT, benchmarkGameInfo} {
+		reader = NewReader(strings.NewReader(data))
+		kv, err = reader.Read()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !kv.HasChildren() || kv.Key() == RootNodeKey {
+			t.Errorf("expected a single root block, got %s", kv.Key())
+		}
+	}
+}
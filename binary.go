@@ -0,0 +1,327 @@
+package keyvalues
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Binary KeyValues type bytes
+const (
+	binaryTypeNone       = byte(0)
+	binaryTypeString     = byte(1)
+	binaryTypeInt        = byte(2)
+	binaryTypeFloat      = byte(3)
+	binaryTypePtr        = byte(4)
+	binaryTypeWString    = byte(5)
+	binaryTypeColor      = byte(6)
+	binaryTypeUint64     = byte(7)
+	binaryTypeEnd        = byte(8)
+	binaryTypeInt64      = byte(10)
+	binaryTypeEndAlt     = byte(11)
+	binaryStringTerminal = byte(0)
+)
+
+// maxBinaryDepth is the deepest that blocks may be nested, as in Valve's
+// KeyValues::ReadAsBinary, so that a crafted stream can't exhaust the stack
+const maxBinaryDepth = 100
+
+// BinaryReader is used for parsing a binary KeyValues stream
+// Every entry is a type byte, a null-terminated key, then a payload that
+// depends on the type. Blocks are terminated by an end type byte.
+// Wide string entries have no payload, as in Valve's tier1 KeyValues, which
+// asserts on them rather than reading or writing any, so they are read as
+// empty ValueWString values.
+type BinaryReader struct {
+	file     io.Reader
+	keyTable []string
+}
+
+// NewBinaryReader Return a new binary KeyValues Reader
+func NewBinaryReader(file io.Reader) BinaryReader {
+	reader := BinaryReader{}
+	reader.file = file
+	return reader
+}
+
+// NewBinaryReaderWithKeyTable Return a new binary KeyValues Reader for
+// streams that store keys as a uint32 index into a table of strings, rather
+// than inline, as newer versions of Steam's appinfo.vdf do
+func NewBinaryReaderWithKeyTable(file io.Reader, keyTable []string) BinaryReader {
+	reader := NewBinaryReader(file)
+	reader.keyTable = keyTable
+	return reader
+}
+
+// Read parses the stream into a KeyValue tree
+// As with Reader, multiple root nodes are contained in a `$root` node.
+func (reader *BinaryReader) Read() (keyvalue KeyValue, err error) {
+	bufReader := bufio.NewReader(reader.file)
+
+	rootNode := KeyValue{
+		key:       RootNodeKey,
+		valueType: ValueArray,
+		parent:    nil,
+	}
+
+	if err = reader.readScope(bufReader, &rootNode, 0); err != nil {
+		return rootNode, err
+	}
+
+	if len(rootNode.children) == 1 {
+		root := rootNode.children[0]
+		root.parent = nil
+		return *root, nil
+	}
+
+	return rootNode, nil
+}
+
+// readScope reads entries into scope until its end type byte.
+// The root scope, at depth 0, may also end at the end of the stream.
+func (reader *BinaryReader) readScope(stream *bufio.Reader, scope *KeyValue, depth int) error {
+	if depth > maxBinaryDepth {
+		return errors.New("binary keyvalues are nested more than " + strconv.Itoa(maxBinaryDepth) + " blocks deep")
+	}
+	for {
+		valueType, err := stream.ReadByte()
+		if err == io.EOF && depth == 0 {
+			return nil
+		}
+		if err != nil {
+			return unexpectedEOF(err)
+		}
+		if valueType == binaryTypeEnd || valueType == binaryTypeEndAlt {
+			return nil
+		}
+
+		key, err := reader.readKey(stream)
+		if err != nil {
+			return err
+		}
+
+		if valueType == binaryTypeNone {
+			child := &KeyValue{
+				key:       key,
+				valueType: ValueArray,
+			}
+			if err = reader.readScope(stream, child, depth+1); err != nil {
+				return err
+			}
+			scope.AddChild(child)
+			continue
+		}
+
+		child, err := readBinaryValue(stream, key, valueType)
+		if err != nil {
+			return err
+		}
+		scope.AddChild(child)
+	}
+}
+
+func (reader *BinaryReader) readKey(stream *bufio.Reader) (string, error) {
+	if reader.keyTable == nil {
+		return readCString(stream)
+	}
+	var idx uint32
+	if err := binary.Read(stream, binary.LittleEndian, &idx); err != nil {
+		return "", unexpectedEOF(err)
+	}
+	if int(idx) >= len(reader.keyTable) {
+		return "", errors.New("key index out of range: " + strconv.Itoa(int(idx)))
+	}
+	return reader.keyTable[idx], nil
+}
+
+// readBinaryValue reads the payload of a single value of the given type
+func readBinaryValue(stream *bufio.Reader, key string, valueType byte) (*KeyValue, error) {
+	var payload [8]byte
+	var value string
+	var kvType ValueType
+
+	switch valueType {
+	case binaryTypeString:
+		str, err := readCString(stream)
+		if err != nil {
+			return nil, err
+		}
+		value, kvType = str, ValueString
+	case binaryTypeWString:
+		kvType = ValueWString
+	case binaryTypeInt:
+		if _, err := io.ReadFull(stream, payload[:4]); err != nil {
+			return nil, unexpectedEOF(err)
+		}
+		value, kvType = strconv.FormatInt(int64(int32(binary.LittleEndian.Uint32(payload[:4]))), 10), ValueInt
+	case binaryTypeFloat:
+		if _, err := io.ReadFull(stream, payload[:4]); err != nil {
+			return nil, unexpectedEOF(err)
+		}
+		f := math.Float32frombits(binary.LittleEndian.Uint32(payload[:4]))
+		value, kvType = strconv.FormatFloat(float64(f), 'f', -1, 32), ValueFloat
+	case binaryTypePtr:
+		if _, err := io.ReadFull(stream, payload[:4]); err != nil {
+			return nil, unexpectedEOF(err)
+		}
+		value, kvType = strconv.FormatUint(uint64(binary.LittleEndian.Uint32(payload[:4])), 10), ValuePtr
+	case binaryTypeColor:
+		if _, err := io.ReadFull(stream, payload[:4]); err != nil {
+			return nil, unexpectedEOF(err)
+		}
+		value, kvType = strconv.Itoa(int(payload[0]))+" "+strconv.Itoa(int(payload[1]))+" "+
+			strconv.Itoa(int(payload[2]))+" "+strconv.Itoa(int(payload[3])), ValueColor
+	case binaryTypeUint64:
+		if _, err := io.ReadFull(stream, payload[:8]); err != nil {
+			return nil, unexpectedEOF(err)
+		}
+		value, kvType = strconv.FormatUint(binary.LittleEndian.Uint64(payload[:8]), 10), ValueUint64
+	case binaryTypeInt64:
+		if _, err := io.ReadFull(stream, payload[:8]); err != nil {
+			return nil, unexpectedEOF(err)
+		}
+		value, kvType = strconv.FormatInt(int64(binary.LittleEndian.Uint64(payload[:8])), 10), ValueInt64
+	default:
+		return nil, errors.New("unknown binary keyvalue type: " + strconv.Itoa(int(valueType)))
+	}
+
+	return NewKeyValuePair(key, value, kvType), nil
+}
+
+// BinaryWriter is used for writing a KeyValue tree in binary format
+type BinaryWriter struct {
+	file io.Writer
+}
+
+// NewBinaryWriter Return a new binary KeyValues Writer
+func NewBinaryWriter(file io.Writer) BinaryWriter {
+	writer := BinaryWriter{}
+	writer.file = file
+	return writer
+}
+
+// Write serializes a KeyValue tree to the underlying stream, followed by
+// an end type byte
+// Values are written with the binary type matching their ValueType. Integer
+// and float values that don't fit in 32 bits are written as strings, as are
+// wide strings, which have no payload in the binary format.
+// As with Writer, a `$root` node is not written itself.
+func (writer *BinaryWriter) Write(keyvalue *KeyValue) error {
+	bufWriter := bufio.NewWriter(writer.file)
+
+	if keyvalue.Key() == RootNodeKey && keyvalue.HasChildren() {
+		children, _ := keyvalue.Children()
+		for _, child := range children {
+			if err := writeBinaryNode(bufWriter, child); err != nil {
+				return err
+			}
+		}
+	} else if err := writeBinaryNode(bufWriter, keyvalue); err != nil {
+		return err
+	}
+
+	if err := bufWriter.WriteByte(binaryTypeEnd); err != nil {
+		return err
+	}
+	return bufWriter.Flush()
+}
+
+func writeBinaryNode(writer *bufio.Writer, node *KeyValue) error {
+	if node.HasChildren() {
+		writeBinaryHeader(writer, binaryTypeNone, node.Key())
+		children, _ := node.Children()
+		for _, child := range children {
+			if err := writeBinaryNode(writer, child); err != nil {
+				return err
+			}
+		}
+		return writer.WriteByte(binaryTypeEnd)
+	}
+
+	value, _ := node.Value()
+	var payload [8]byte
+
+	switch node.Type() {
+	case ValueInt:
+		if i, err := strconv.ParseInt(value, 10, 32); err == nil {
+			writeBinaryHeader(writer, binaryTypeInt, node.Key())
+			binary.LittleEndian.PutUint32(payload[:4], uint32(int32(i)))
+			_, err = writer.Write(payload[:4])
+			return err
+		}
+	case ValueFloat:
+		if f, err := strconv.ParseFloat(value, 32); err == nil {
+			writeBinaryHeader(writer, binaryTypeFloat, node.Key())
+			binary.LittleEndian.PutUint32(payload[:4], math.Float32bits(float32(f)))
+			_, err = writer.Write(payload[:4])
+			return err
+		}
+	case ValuePtr:
+		ptr, err := node.AsPtr()
+		if err != nil {
+			return err
+		}
+		writeBinaryHeader(writer, binaryTypePtr, node.Key())
+		binary.LittleEndian.PutUint32(payload[:4], ptr)
+		_, err = writer.Write(payload[:4])
+		return err
+	case ValueColor:
+		rgba, err := node.AsColor()
+		if err != nil {
+			return err
+		}
+		writeBinaryHeader(writer, binaryTypeColor, node.Key())
+		_, err = writer.Write([]byte{rgba.R, rgba.G, rgba.B, rgba.A})
+		return err
+	case ValueUint64:
+		u, err := node.AsUint64()
+		if err != nil {
+			return err
+		}
+		writeBinaryHeader(writer, binaryTypeUint64, node.Key())
+		binary.LittleEndian.PutUint64(payload[:8], u)
+		_, err = writer.Write(payload[:8])
+		return err
+	case ValueInt64:
+		i, err := node.AsInt64()
+		if err != nil {
+			return err
+		}
+		writeBinaryHeader(writer, binaryTypeInt64, node.Key())
+		binary.LittleEndian.PutUint64(payload[:8], uint64(i))
+		_, err = writer.Write(payload[:8])
+		return err
+	}
+
+	writeBinaryHeader(writer, binaryTypeString, node.Key())
+	_, err := writer.WriteString(value + string(binaryStringTerminal))
+	return err
+}
+
+// writeBinaryHeader writes the type byte and key of an entry.
+// Errors are left for the caller's next write, as bufio.Writer keeps them.
+func writeBinaryHeader(writer *bufio.Writer, valueType byte, key string) {
+	writer.WriteByte(valueType)
+	writer.WriteString(key)
+	writer.WriteByte(binaryStringTerminal)
+}
+
+// readCString reads a null-terminated string
+func readCString(stream *bufio.Reader) (string, error) {
+	str, err := stream.ReadString(binaryStringTerminal)
+	if err != nil {
+		return "", unexpectedEOF(err)
+	}
+	return strings.TrimSuffix(str, string(binaryStringTerminal)), nil
+}
+
+func unexpectedEOF(err error) error {
+	if err == io.EOF {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
@@ -0,0 +1,165 @@
+package keyvalues
+
+import (
+	"bytes"
+	"image/color"
+	"io"
+	"testing"
+)
+
+func TestBinaryReader_Read(t *testing.T) {
+	data := []byte("\x00foo\x00" +
+		"\x01bar\x00hello\x00" +
+		"\x02baz\x00\x2a\x00\x00\x00" +
+		"\x03bat\x00\x00\x00\xc0\x3f" +
+		"\x00egg\x00" +
+		"\x07big\x00\xff\xff\xff\xff\xff\xff\xff\xff" +
+		"\x06tint\x00\xff\x80\x00\x40" +
+		"\x08" +
+		"\x08" +
+		"\x08")
+
+	reader := NewBinaryReader(bytes.NewReader(data))
+	kv, err := reader.Read()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if kv.Key() != "foo" {
+		t.Errorf("unexpected root key: %s", kv.Key())
+	}
+
+	bar, _ := kv.Find("bar")
+	if val, err := bar.AsString(); err != nil || val != "hello" {
+		t.Error("unexpected string value")
+	}
+	baz, _ := kv.Find("baz")
+	if val, err := baz.AsInt(); err != nil || val != 42 {
+		t.Error("unexpected int value")
+	}
+	bat, _ := kv.Find("bat")
+	if val, err := bat.AsFloat(); err != nil || val != 1.5 {
+		t.Error("unexpected float value")
+	}
+	egg, _ := kv.Find("egg")
+	big, _ := egg.Find("big")
+	if val, err := big.AsUint64(); err != nil || val != 18446744073709551615 {
+		t.Error("unexpected uint64 value")
+	}
+	tint, _ := egg.Find("tint")
+	if val, err := tint.AsColor(); err != nil || val != (color.RGBA{R: 255, G: 128, B: 0, A: 64}) {
+		t.Error("unexpected color value")
+	}
+}
+
+func TestBinaryReader_Read_Truncated(t *testing.T) {
+	reader := NewBinaryReader(bytes.NewReader([]byte("\x00foo\x00\x02baz\x00\x2a")))
+	if _, err := reader.Read(); err != io.ErrUnexpectedEOF {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+// TestBinaryReader_Read_WString reads a wide string as Valve's
+// KeyValues::WriteAsBinary writes one: a type byte and key with no payload
+func TestBinaryReader_Read_WString(t *testing.T) {
+	reader := NewBinaryReader(bytes.NewReader([]byte("\x00foo\x00\x05wide\x00\x01bar\x00hello\x00\x08\x08")))
+	kv, err := reader.Read()
+	if err != nil {
+		t.Fatal(err)
+	}
+	wide, err := kv.Find("wide")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if val, _ := wide.Value(); wide.Type() != ValueWString || val != "" {
+		t.Errorf("unexpected wide string: %s %q", wide.Type(), val)
+	}
+	bar, _ := kv.Find("bar")
+	if val, err := bar.AsString(); err != nil || val != "hello" {
+		t.Error("unexpected string value after a wide string")
+	}
+}
+
+func TestBinaryReader_Read_TooDeep(t *testing.T) {
+	data := bytes.Repeat([]byte("\x00a\x00"), maxBinaryDepth+2)
+	data = append(data, bytes.Repeat([]byte{binaryTypeEnd}, maxBinaryDepth+2)...)
+	reader := NewBinaryReader(bytes.NewReader(data))
+	if _, err := reader.Read(); err == nil {
+		t.Error("expected error, but received none")
+	}
+
+	data = bytes.Repeat([]byte("\x00a\x00"), maxBinaryDepth)
+	data = append(data, bytes.Repeat([]byte{binaryTypeEnd}, maxBinaryDepth)...)
+	reader = NewBinaryReader(bytes.NewReader(data))
+	if _, err := reader.Read(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestBinaryReaderWithKeyTable_Read(t *testing.T) {
+	data := []byte("\x00\x01\x00\x00\x00" +
+		"\x01\x00\x00\x00\x00hello\x00" +
+		"\x08\x08")
+	reader := NewBinaryReaderWithKeyTable(bytes.NewReader(data), []string{"bar", "foo"})
+	kv, err := reader.Read()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if kv.Key() != "foo" {
+		t.Errorf("unexpected root key: %s", kv.Key())
+	}
+	if bar, err := kv.Find("bar"); err != nil || bar == nil {
+		t.Error("key from table not found")
+	}
+}
+
+func TestBinaryWriter_Write(t *testing.T) {
+	kv := NewKeyValueArray("foo",
+		NewKeyValue("bar", "hello"),
+		NewKeyValue("baz", "42"),
+		NewKeyValue("bat", "1.5"),
+		NewKeyValue("huge", "99999999999"),
+		NewKeyValuePair("ptr", "1234", ValuePtr),
+		NewKeyValuePair("wide", "héllo", ValueWString),
+		NewKeyValuePair("tint", "255 128 0 64", ValueColor),
+		NewKeyValuePair("big", "18446744073709551615", ValueUint64),
+		NewKeyValuePair("signed", "-5000000000", ValueInt64),
+		NewKeyValueArray("egg"))
+
+	var buf bytes.Buffer
+	writer := NewBinaryWriter(&buf)
+	if err := writer.Write(kv); err != nil {
+		t.Fatal(err)
+	}
+
+	reader := NewBinaryReader(&buf)
+	result, err := reader.Read()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for key, expected := range map[string]ValueType{
+		"bar":    ValueString,
+		"baz":    ValueInt,
+		"bat":    ValueFloat,
+		"huge":   ValueString,
+		"ptr":    ValuePtr,
+		"wide":   ValueString,
+		"tint":   ValueColor,
+		"big":    ValueUint64,
+		"signed": ValueInt64,
+		"egg":    ValueArray,
+	} {
+		node, err := result.Find(key)
+		if err != nil {
+			t.Error(err)
+			continue
+		}
+		if node.Type() != expected {
+			t.Errorf("unexpected type for %s. expected %s, received: %s", key, expected, node.Type())
+		}
+		original, _ := kv.Find(key)
+		originalValue, _ := original.Value()
+		if value, _ := node.Value(); node.Type() != ValueArray && value != originalValue {
+			t.Errorf("unexpected value for %s. expected %s, received: %s", key, originalValue, value)
+		}
+	}
+}
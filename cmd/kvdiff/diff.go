@@ -0,0 +1,200 @@
+package main
+
+import (
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/galaco/KeyValues"
+)
+
+// options control what counts as a difference
+type options struct {
+	ignoreOrder bool
+	ignoreSpace bool
+	ignoreCase  bool
+}
+
+// differ compares two trees, writing each difference as a line
+type differ struct {
+	options
+	out   io.Writer
+	found bool
+	err   error
+}
+
+// diffTrees writes the differences between the top level nodes of two files
+// to out, and returns whether there were any
+func diffTrees(out io.Writer, a []*keyvalues.KeyValue, b []*keyvalues.KeyValue, opts options) (bool, error) {
+	d := &differ{options: opts, out: out}
+	d.diffChildren("", a, b)
+	return d.found, d.err
+}
+
+// diffChildren pairs the children of two blocks by key, the nth child with a
+// key in a with the nth with that key in b, and compares each pair
+func (d *differ) diffChildren(path string, a []*keyvalues.KeyValue, b []*keyvalues.KeyValue) {
+	countA, countB := d.countKeys(a), d.countKeys(b)
+
+	var keysA, keysB []string
+	seen := map[string]int{}
+	for _, child := range a {
+		key := d.normalizeKey(child.Key())
+		idx := seen[key]
+		seen[key]++
+		keysA = append(keysA, key+"\x00"+strconv.Itoa(idx))
+
+		childPath := d.childPath(path, child.Key(), idx, countA[key], countB[key])
+		matches := d.nthWithKey(b, key, idx)
+		if matches == nil {
+			d.writeNode("-", childPath, child)
+			continue
+		}
+		d.diffNodes(childPath, child, matches)
+	}
+
+	seen = map[string]int{}
+	for _, child := range b {
+		key := d.normalizeKey(child.Key())
+		idx := seen[key]
+		seen[key]++
+		keysB = append(keysB, key+"\x00"+strconv.Itoa(idx))
+
+		if idx >= countA[key] {
+			d.writeNode("+", d.childPath(path, child.Key(), idx, countA[key], countB[key]), child)
+		}
+	}
+
+	if d.ignoreOrder {
+		return
+	}
+	// keys in both blocks should appear in the same order
+	inB := map[string]bool{}
+	for _, key := range keysB {
+		inB[key] = true
+	}
+	inA := map[string]bool{}
+	var commonA, commonB []string
+	for _, key := range keysA {
+		inA[key] = true
+		if inB[key] {
+			commonA = append(commonA, key)
+		}
+	}
+	for _, key := range keysB {
+		if inA[key] {
+			commonB = append(commonB, key)
+		}
+	}
+	for idx := range commonA {
+		if commonA[idx] != commonB[idx] {
+			name := path
+			if name == "" {
+				name = "/"
+			}
+			d.writeLine("~ " + name + " order of keys changed")
+			return
+		}
+	}
+}
+
+// diffNodes compares two nodes with the same key
+func (d *differ) diffNodes(path string, a *keyvalues.KeyValue, b *keyvalues.KeyValue) {
+	if a.HasChildren() != b.HasChildren() {
+		d.writeNode("-", path, a)
+		d.writeNode("+", path, b)
+		return
+	}
+	if a.HasChildren() {
+		childrenA, _ := a.Children()
+		childrenB, _ := b.Children()
+		d.diffChildren(path, childrenA, childrenB)
+		return
+	}
+	valueA, _ := a.Value()
+	valueB, _ := b.Value()
+	if d.normalizeValue(valueA) != d.normalizeValue(valueB) {
+		d.writeNode("-", path, a)
+		d.writeNode("+", path, b)
+	}
+}
+
+// writeNode writes a value, or every value in a block, with a prefix
+func (d *differ) writeNode(prefix string, path string, node *keyvalues.KeyValue) {
+	if !node.HasChildren() {
+		value, _ := node.Value()
+		d.writeLine(prefix + " " + path + " " + strconv.Quote(value))
+		return
+	}
+	children, _ := node.Children()
+	if len(children) == 0 {
+		d.writeLine(prefix + " " + path + " {}")
+		return
+	}
+	counts := d.countKeys(children)
+	seen := map[string]int{}
+	for _, child := range children {
+		key := d.normalizeKey(child.Key())
+		idx := seen[key]
+		seen[key]++
+		d.writeNode(prefix, d.childPath(path, child.Key(), idx, counts[key], 0), child)
+	}
+}
+
+func (d *differ) writeLine(line string) {
+	d.found = true
+	if d.err == nil {
+		_, d.err = io.WriteString(d.out, line+"\n")
+	}
+}
+
+// childPath returns the path of a child, in the form kvq accepts. The index
+// of a key is only included when it is repeated in either block.
+func (d *differ) childPath(path string, key string, idx int, countA int, countB int) string {
+	if strings.ContainsAny(key, "/[]*?\"\\") || key == "" || strings.TrimSpace(key) != key {
+		key = strconv.Quote(key)
+	}
+	if countA > 1 || countB > 1 {
+		key += "[" + strconv.Itoa(idx) + "]"
+	}
+	if path == "" {
+		return key
+	}
+	return path + "/" + key
+}
+
+func (d *differ) countKeys(nodes []*keyvalues.KeyValue) map[string]int {
+	counts := map[string]int{}
+	for _, node := range nodes {
+		counts[d.normalizeKey(node.Key())]++
+	}
+	return counts
+}
+
+// nthWithKey returns the nth node with key, or nil
+func (d *differ) nthWithKey(nodes []*keyvalues.KeyValue, key string, n int) *keyvalues.KeyValue {
+	for _, node := range nodes {
+		if d.normalizeKey(node.Key()) != key {
+			continue
+		}
+		if n == 0 {
+			return node
+		}
+		n--
+	}
+	return nil
+}
+
+func (d *differ) normalizeKey(key string) string {
+	if d.ignoreCase {
+		return strings.ToLower(key)
+	}
+	return key
+}
+
+func (d *differ) normalizeValue(value string) string {
+	if d.ignoreSpace {
+		return strings.Join(strings.Fields(value), " ")
+	}
+	return value
+}
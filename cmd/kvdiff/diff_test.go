@@ -0,0 +1,80 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/galaco/KeyValues"
+)
+
+func sampleTrees() ([]*keyvalues.KeyValue, []*keyvalues.KeyValue) {
+	a := keyvalues.NewKeyValueArray("GameInfo",
+		keyvalues.NewKeyValue("game", "Half-Life 2"),
+		keyvalues.NewKeyValueArray("FileSystem",
+			keyvalues.NewKeyValue("SteamAppId", "220"),
+			keyvalues.NewKeyValueArray("SearchPaths",
+				keyvalues.NewKeyValue("Game", "hl2"))),
+		keyvalues.NewKeyValue("removed", "1"))
+	b := keyvalues.NewKeyValueArray("GameInfo",
+		keyvalues.NewKeyValueArray("filesystem",
+			keyvalues.NewKeyValue("SteamAppId", " 240"),
+			keyvalues.NewKeyValueArray("SearchPaths",
+				keyvalues.NewKeyValue("Game", "hl2"),
+				keyvalues.NewKeyValue("Game", "episodic"))),
+		keyvalues.NewKeyValue("game", "Half-Life 2"),
+		keyvalues.NewKeyValueArray("added/block"))
+	return []*keyvalues.KeyValue{a}, []*keyvalues.KeyValue{b}
+}
+
+func TestDiffTrees(t *testing.T) {
+	a, b := sampleTrees()
+
+	var buf bytes.Buffer
+	found, err := diffTrees(&buf, a, b, options{ignoreCase: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := `- GameInfo/FileSystem/SteamAppId "220"
++ GameInfo/FileSystem/SteamAppId " 240"
++ GameInfo/FileSystem/SearchPaths/Game[1] "episodic"
+- GameInfo/removed "1"
++ GameInfo/"added/block" {}
+~ GameInfo order of keys changed
+`
+	if !found || buf.String() != expected {
+		t.Errorf("unexpected diff:\n%s", buf.String())
+	}
+
+	buf.Reset()
+	if _, err = diffTrees(&buf, a, b, options{}); err != nil {
+		t.Fatal(err)
+	}
+	expected = `- GameInfo/FileSystem/SteamAppId "220"
+- GameInfo/FileSystem/SearchPaths/Game "hl2"
+- GameInfo/removed "1"
++ GameInfo/filesystem/SteamAppId " 240"
++ GameInfo/filesystem/SearchPaths/Game[0] "hl2"
++ GameInfo/filesystem/SearchPaths/Game[1] "episodic"
++ GameInfo/"added/block" {}
+`
+	if buf.String() != expected {
+		t.Errorf("unexpected diff:\n%s", buf.String())
+	}
+}
+
+func TestDiffTrees_Ignore(t *testing.T) {
+	a := keyvalues.NewKeyValueArray("root", keyvalues.NewKeyValue("a", "x  y"), keyvalues.NewKeyValue("b", "1"))
+	b := keyvalues.NewKeyValueArray("root", keyvalues.NewKeyValue("b", "1"), keyvalues.NewKeyValue("a", " x y"))
+
+	var buf bytes.Buffer
+	found, err := diffTrees(&buf, []*keyvalues.KeyValue{a}, []*keyvalues.KeyValue{b}, options{ignoreOrder: true, ignoreSpace: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if found {
+		t.Errorf("expected no differences, got:\n%s", buf.String())
+	}
+	if found, _ = diffTrees(&buf, []*keyvalues.KeyValue{a}, []*keyvalues.KeyValue{a}, options{}); found {
+		t.Error("expected no differences between a tree and itself")
+	}
+}
@@ -0,0 +1,110 @@
+// Command kvdiff prints the differences between two KeyValues files.
+//
+// Usage:
+//
+//	kvdiff [flags] old new
+//
+// Either file may be - for standard input.
+//
+// Files are compared as trees, so formatting and comments don't matter. The
+// nth child with a key in a block of the old file is compared with the nth
+// child with that key in the same block of the new file. Each difference is
+// printed as a line, with a path in the form kvq accepts:
+//
+//	$ kvdiff hl2/gameinfo.txt ep2/gameinfo.txt
+//	- GameInfo/FileSystem/SteamAppId "220"
+//	+ GameInfo/FileSystem/SteamAppId "420"
+//	+ GameInfo/FileSystem/SearchPaths/Game[2] "episodic"
+//	~ GameInfo/FileSystem order of keys changed
+//
+// Added and removed blocks are printed as each of their values.
+//
+// The flags are:
+//
+//	-ignore-order
+//		don't report keys that changed order within a block
+//	-ignore-space
+//		compare values with runs of whitespace collapsed, and leading and
+//		trailing whitespace removed
+//	-ignore-case
+//		compare keys ignoring case, as Find does
+//
+// kvdiff exits with status 0 if the files are the same, 1 if they differ and
+// 2 on any error.
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+
+	"github.com/galaco/KeyValues"
+)
+
+var (
+	ignoreOrder = flag.Bool("ignore-order", false, "don't report keys that changed order within a block")
+	ignoreSpace = flag.Bool("ignore-space", false, "compare values with whitespace collapsed and trimmed")
+	ignoreCase  = flag.Bool("ignore-case", false, "compare keys ignoring case, as Find does")
+)
+
+func main() {
+	flag.Usage = func() {
+		fmt.Fprintln(os.Stderr, "usage: kvdiff [flags] old new")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() != 2 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	a, err := readTops(flag.Arg(0))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+	b, err := readTops(flag.Arg(1))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+
+	found, err := diffTrees(os.Stdout, a, b, options{
+		ignoreOrder: *ignoreOrder,
+		ignoreSpace: *ignoreSpace,
+		ignoreCase:  *ignoreCase,
+	})
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+	if found {
+		os.Exit(1)
+	}
+}
+
+// readTops reads a file, and returns its top level nodes
+func readTops(filename string) ([]*keyvalues.KeyValue, error) {
+	var src []byte
+	var err error
+	if filename == "-" {
+		src, err = ioutil.ReadAll(os.Stdin)
+	} else {
+		src, err = ioutil.ReadFile(filename)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	reader := keyvalues.NewReader(bytes.NewReader(src))
+	tree, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", filename, err)
+	}
+	if tree.Key() == keyvalues.RootNodeKey {
+		return tree.Children()
+	}
+	return []*keyvalues.KeyValue{&tree}, nil
+}
@@ -0,0 +1,181 @@
+package main
+
+import (
+	"bytes"
+	"strconv"
+	"strings"
+)
+
+// diffContext is the number of unchanged lines shown around each change
+const diffContext = 3
+
+// maxDiffCells bounds the size of the table used to find the longest common
+// subsequence of the changed lines. Beyond it, every changed line is shown
+// as removed and added.
+const maxDiffCells = 1 << 24
+
+// diffOp is a single line of an edit script: ' ' for a line in both files,
+// '-' for a removed line and '+' for an added line
+type diffOp struct {
+	kind byte
+	line string
+}
+
+// unifiedDiff returns a unified diff from a to b, or nil if they are equal
+func unifiedDiff(name string, a []byte, b []byte) []byte {
+	if bytes.Equal(a, b) {
+		return nil
+	}
+	ops := diffLines(splitLines(a), splitLines(b))
+
+	var buf bytes.Buffer
+	buf.WriteString("--- " + name + ".orig\n+++ " + name + "\n")
+
+	// the line numbers in a and b before each op
+	oldLines := make([]int, len(ops)+1)
+	newLines := make([]int, len(ops)+1)
+	for idx, op := range ops {
+		oldLines[idx+1], newLines[idx+1] = oldLines[idx], newLines[idx]
+		if op.kind != '+' {
+			oldLines[idx+1]++
+		}
+		if op.kind != '-' {
+			newLines[idx+1]++
+		}
+	}
+
+	for idx := 0; idx < len(ops); {
+		if ops[idx].kind == ' ' {
+			idx++
+			continue
+		}
+		start := idx - diffContext
+		if start < 0 {
+			start = 0
+		}
+		end := idx
+		for end < len(ops) {
+			if ops[end].kind != ' ' {
+				end++
+				continue
+			}
+			run := end
+			for run < len(ops) && ops[run].kind == ' ' {
+				run++
+			}
+			// changes separated by little enough context share a hunk
+			if run == len(ops) || run-end > 2*diffContext {
+				end += diffContext
+				if end > len(ops) {
+					end = len(ops)
+				}
+				break
+			}
+			end = run
+		}
+
+		buf.WriteString("@@ -" + hunkRange(oldLines[start], oldLines[end]) +
+			" +" + hunkRange(newLines[start], newLines[end]) + " @@\n")
+		for _, op := range ops[start:end] {
+			buf.WriteByte(op.kind)
+			buf.WriteString(op.line)
+			if !strings.HasSuffix(op.line, "\n") {
+				buf.WriteString("\n\\ No newline at end of file\n")
+			}
+		}
+		idx = end
+	}
+	return buf.Bytes()
+}
+
+// hunkRange formats the lines after start, up to end, as a hunk range
+func hunkRange(start int, end int) string {
+	count := end - start
+	if count == 0 {
+		return strconv.Itoa(start) + ",0"
+	}
+	if count == 1 {
+		return strconv.Itoa(start + 1)
+	}
+	return strconv.Itoa(start+1) + "," + strconv.Itoa(count)
+}
+
+// diffLines returns an edit script from a to b, using the longest common
+// subsequence of the lines between their common prefix and suffix
+func diffLines(a []string, b []string) []diffOp {
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+
+	var ops []diffOp
+	for _, line := range a[:prefix] {
+		ops = append(ops, diffOp{kind: ' ', line: line})
+	}
+
+	midA, midB := a[prefix:len(a)-suffix], b[prefix:len(b)-suffix]
+	if len(midA)*len(midB) > maxDiffCells {
+		for _, line := range midA {
+			ops = append(ops, diffOp{kind: '-', line: line})
+		}
+		for _, line := range midB {
+			ops = append(ops, diffOp{kind: '+', line: line})
+		}
+	} else {
+		// lcs[i][j] is the length of the longest common subsequence of
+		// midA[i:] and midB[j:]
+		lcs := make([][]int, len(midA)+1)
+		for i := range lcs {
+			lcs[i] = make([]int, len(midB)+1)
+		}
+		for i := len(midA) - 1; i >= 0; i-- {
+			for j := len(midB) - 1; j >= 0; j-- {
+				if midA[i] == midB[j] {
+					lcs[i][j] = lcs[i+1][j+1] + 1
+				} else if lcs[i+1][j] >= lcs[i][j+1] {
+					lcs[i][j] = lcs[i+1][j]
+				} else {
+					lcs[i][j] = lcs[i][j+1]
+				}
+			}
+		}
+		i, j := 0, 0
+		for i < len(midA) || j < len(midB) {
+			switch {
+			case i < len(midA) && j < len(midB) && midA[i] == midB[j]:
+				ops = append(ops, diffOp{kind: ' ', line: midA[i]})
+				i++
+				j++
+			case j == len(midB) || i < len(midA) && lcs[i+1][j] >= lcs[i][j+1]:
+				ops = append(ops, diffOp{kind: '-', line: midA[i]})
+				i++
+			default:
+				ops = append(ops, diffOp{kind: '+', line: midB[j]})
+				j++
+			}
+		}
+	}
+
+	for _, line := range a[len(a)-suffix:] {
+		ops = append(ops, diffOp{kind: ' ', line: line})
+	}
+	return ops
+}
+
+// splitLines splits data into lines, keeping their line breaks
+func splitLines(data []byte) []string {
+	var lines []string
+	for len(data) > 0 {
+		end := bytes.IndexByte(data, '\n')
+		if end < 0 {
+			end = len(data) - 1
+		}
+		lines = append(lines, string(data[:end+1]))
+		data = data[end+1:]
+	}
+	return lines
+}
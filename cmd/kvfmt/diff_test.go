@@ -0,0 +1,34 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestUnifiedDiff(t *testing.T) {
+	a := "1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n11\n12\n"
+	b := "1\n2\nthree\n4\n5\n6\n7\n8\n9\n10\n11\n12\n13"
+
+	expected := `--- a.vmt.orig
++++ a.vmt
+@@ -1,6 +1,6 @@
+ 1
+ 2
+-3
++three
+ 4
+ 5
+ 6
+@@ -10,3 +10,4 @@
+ 10
+ 11
+ 12
++13
+\ No newline at end of file
+`
+	if diff := string(unifiedDiff("a.vmt", []byte(a), []byte(b))); diff != expected {
+		t.Errorf("unexpected diff:\n%s", diff)
+	}
+	if unifiedDiff("a.vmt", []byte(a), []byte(a)) != nil {
+		t.Error("expected no diff for equal files")
+	}
+}
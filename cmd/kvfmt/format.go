@@ -0,0 +1,262 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/galaco/KeyValues"
+)
+
+const tabWidth = 4
+
+// escapeCell is the raw tabwriter escape byte, which string(tabwriter.Escape)
+// would encode as UTF-8
+var escapeCell = string([]byte{tabwriter.Escape})
+
+// format parses src with keyvalues.Reader, and returns it in canonical form:
+// every key and value quoted, tab indentation, the values of consecutive keys
+// aligned, braces on their own lines and \n line endings.
+// Comments are kept with the line they come before or end. Files with content
+// the Reader doesn't keep, such as conditionals, are rejected rather than
+// losing it.
+func format(src []byte) ([]byte, error) {
+	src = normalizeLineEndings(src)
+	layout, err := scan(src)
+	if err != nil {
+		return nil, err
+	}
+	tree := parse(src)
+
+	var buf bytes.Buffer
+	p := &printer{
+		writer: tabwriter.NewWriter(&buf, 0, tabWidth, 1, '\t', tabwriter.StripEscape),
+		layout: layout,
+	}
+	if tree.Key() == keyvalues.RootNodeKey {
+		children, _ := tree.Children()
+		for idx, child := range children {
+			if idx > 0 {
+				p.writer.Write([]byte("\n"))
+			}
+			p.writeNode(child, 0)
+		}
+	} else {
+		p.writeNode(tree, 0)
+	}
+	p.writeComments("", layout.footer)
+	if err = p.writer.Flush(); err != nil {
+		return nil, err
+	}
+	if p.next != len(layout.lines) {
+		return nil, errors.New("comments could not be matched to the parsed contents")
+	}
+
+	// the result must read back as the same tree, with the same comments
+	before, err := tree.ToJSON(keyvalues.JSONLossless)
+	if err != nil {
+		return nil, err
+	}
+	after, err := parse(buf.Bytes()).ToJSON(keyvalues.JSONLossless)
+	if err != nil {
+		return nil, err
+	}
+	if !bytes.Equal(before, after) {
+		return nil, errors.New("formatting would change the parsed contents")
+	}
+	formatted, err := scan(buf.Bytes())
+	if err != nil {
+		return nil, err
+	}
+	if !equalStrings(layout.comments(), formatted.comments()) {
+		return nil, errors.New("formatting would change the comments")
+	}
+	return buf.Bytes(), nil
+}
+
+// parse reads src into a tree
+func parse(src []byte) *keyvalues.KeyValue {
+	reader := keyvalues.NewReader(bytes.NewReader(src))
+	tree, _ := reader.Read()
+	return &tree
+}
+
+// printer writes a tree, with the comments of the source lines that each
+// line it writes came from
+type printer struct {
+	writer *tabwriter.Writer
+	layout *layout
+	next   int
+}
+
+// writeNode writes node and its children. Indentation and the separator
+// between a key and its value are written as tabwriter cells, and text as
+// escaped cells, so that values are aligned and tabs in text are kept.
+func (p *printer) writeNode(node *keyvalues.KeyValue, depth int) {
+	indent := strings.Repeat("\t", depth)
+	if !node.HasChildren() {
+		value, _ := node.Value()
+		p.writeLine(indent, indent, escape(node.Key())+"\t"+escape(value))
+		return
+	}
+
+	p.writeLine(indent, indent, escape(node.Key()))
+	p.writeLine(indent, indent, "{")
+	children, _ := node.Children()
+	for _, child := range children {
+		p.writeNode(child, depth+1)
+	}
+	// comments before a closing brace belong to the block's contents
+	p.writeLine(indent+"\t", indent, "}")
+}
+
+// writeLine writes the comments before the next source line at
+// commentIndent, and then text with the comment that ended the line
+func (p *printer) writeLine(commentIndent string, indent string, text string) {
+	var line lineComments
+	if p.next < len(p.layout.lines) {
+		line = p.layout.lines[p.next]
+	}
+	p.next++
+
+	p.writeComments(commentIndent, line.leading)
+	if line.trailing != "" {
+		text += " " + escapeCell + line.trailing + escapeCell
+	}
+	p.writer.Write([]byte(indent + text + "\n"))
+}
+
+func (p *printer) writeComments(indent string, comments []string) {
+	for _, comment := range comments {
+		p.writer.Write([]byte(indent + escapeCell + comment + escapeCell + "\n"))
+	}
+}
+
+// escape quotes text, and escapes it from tabwriter
+func escape(text string) string {
+	return escapeCell + "\"" + text + "\"" + escapeCell
+}
+
+func normalizeLineEndings(src []byte) []byte {
+	src = bytes.ReplaceAll(src, []byte("\r\n"), []byte("\n"))
+	return bytes.ReplaceAll(src, []byte("\r"), []byte("\n"))
+}
+
+// lineComments are the comments of a key or brace line: the comment lines
+// before it, and the comment at its end
+type lineComments struct {
+	leading  []string
+	trailing string
+}
+
+// layout is the comments of a file, by the key and brace lines they belong to
+type layout struct {
+	lines []lineComments
+	// footer is the comments after the last key or brace
+	footer []string
+}
+
+// comments returns every comment in the file, in order
+func (l *layout) comments() (comments []string) {
+	for _, line := range l.lines {
+		comments = append(comments, line.leading...)
+		if line.trailing != "" {
+			comments = append(comments, line.trailing)
+		}
+	}
+	return append(comments, l.footer...)
+}
+
+// scan returns the comments of src, or an error for the first line with
+// content that keyvalues.Reader would drop or misread
+func scan(src []byte) (*layout, error) {
+	result := &layout{}
+	var leading []string
+	for idx, line := range strings.Split(string(src), "\n") {
+		tokens, comment, ok := tokenize(line)
+		if !ok {
+			return nil, lineError(idx, "unterminated quoted string")
+		}
+		for _, token := range tokens {
+			if (token == "{" || token == "}") && len(tokens) > 1 {
+				return nil, lineError(idx, "braces must be on their own line")
+			}
+			if strings.HasPrefix(token, "[$") || strings.HasPrefix(token, "[!$") {
+				return nil, lineError(idx, "conditionals are not kept by the reader")
+			}
+			if token != "}" && strings.Contains(token, "}") {
+				return nil, lineError(idx, "the reader ends a block at any closing brace, even in quotes")
+			}
+		}
+		if len(tokens) > 2 {
+			return nil, lineError(idx, "more than one value for a key")
+		}
+
+		if len(tokens) == 0 {
+			if comment != "" {
+				leading = append(leading, comment)
+			}
+			continue
+		}
+		result.lines = append(result.lines, lineComments{leading: leading, trailing: comment})
+		leading = nil
+	}
+	result.footer = leading
+	return result, nil
+}
+
+// tokenize splits a line into quoted strings and unquoted words, and the
+// comment that ends it, returning false if a quoted string isn't closed
+func tokenize(line string) (tokens []string, comment string, ok bool) {
+	for idx := 0; idx < len(line); {
+		switch {
+		case line[idx] == ' ' || line[idx] == '\t':
+			idx++
+		case strings.HasPrefix(line[idx:], "//"):
+			return tokens, strings.TrimRight(line[idx:], " \t"), true
+		case line[idx] == '"':
+			end := idx + 1
+			for end < len(line) && line[end] != '"' {
+				if line[end] == '\\' {
+					end++
+				}
+				end++
+			}
+			if end >= len(line) {
+				return nil, "", false
+			}
+			tokens = append(tokens, line[idx:end+1])
+			idx = end + 1
+		case line[idx] == '{' || line[idx] == '}':
+			tokens = append(tokens, line[idx:idx+1])
+			idx++
+		default:
+			end := idx
+			for end < len(line) && !strings.ContainsRune(" \t\"{}", rune(line[end])) &&
+				!strings.HasPrefix(line[end:], "//") {
+				end++
+			}
+			tokens = append(tokens, line[idx:end])
+			idx = end
+		}
+	}
+	return tokens, "", true
+}
+
+func equalStrings(a []string, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for idx := range a {
+		if a[idx] != b[idx] {
+			return false
+		}
+	}
+	return true
+}
+
+func lineError(idx int, message string) error {
+	return errors.New("line " + strconv.Itoa(idx+1) + ": " + message)
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestFormat(t *testing.T) {
+	src := "\"root\"\r\n{\r\n  name \"test\"\r\n\t\"longer_key\"   1\r\n\t\"child\"\r\n\t{\r\n\t\"a\" \"b\"\r\n\t}\r\n}"
+	expected := "\"root\"\n{\n\t\"name\"\t\t\t\"test\"\n\t\"longer_key\"\t\"1\"\n\t\"child\"\n\t{\n\t\t\"a\"\t\"b\"\n\t}\n}\n"
+
+	res, err := format([]byte(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(res) != expected {
+		t.Errorf("unexpected result:\n%s", res)
+	}
+
+	again, err := format(res)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(again) != string(res) {
+		t.Errorf("formatting is not idempotent:\n%s", again)
+	}
+}
+
+func TestFormat_MultipleRoots(t *testing.T) {
+	res, err := format([]byte("\"a\"\n{\n\"x\" \"1\"\n}\n\"b\"\n{\n}\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := "\"a\"\n{\n\t\"x\"\t\"1\"\n}\n\n\"b\"\n{\n}\n"
+	if string(res) != expected {
+		t.Errorf("unexpected result:\n%s", res)
+	}
+}
+
+func TestFormat_Unsupported(t *testing.T) {
+	samples := map[string]string{
+		"conditional":  "\"root\"\n{\n\t\"a\" \"b\" [$WIN32]\n}\n",
+		"brace":        "\"root\" {\n\t\"a\" \"b\"\n}\n",
+		"quote":        "\"root\"\n{\n\t\"a\" \"b\n}\n",
+		"quoted brace": "\"root\"\n{\n\t\"a\" \"}\"\n}\n",
+	}
+	for name, sample := range samples {
+		if _, err := format([]byte(sample)); err == nil {
+			t.Errorf("expected error for %s", name)
+		}
+	}
+}
+
+func TestFormat_Comments(t *testing.T) {
+	src := "// header\n\"root\" // root\n{\n  // first\n  \"a\"   \"b\" // note\n\"url\" \"http://example.com\"\n// last\n}\n// footer\n"
+	expected := "// header\n\"root\" // root\n{\n\t// first\n\t\"a\"\t\t\"b\" // note\n\t\"url\"\t\"http://example.com\"\n\t// last\n}\n// footer\n"
+
+	res, err := format([]byte(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(res) != expected {
+		t.Errorf("unexpected result:\n%s", res)
+	}
+
+	again, err := format(res)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(again) != string(res) {
+		t.Errorf("formatting is not idempotent:\n%s", again)
+	}
+}
@@ -0,0 +1,149 @@
+// Command kvfmt formats KeyValues files.
+//
+// Without flags, kvfmt prints the formatted files to standard output. Given
+// a directory, it formats every file inside it with one of the -ext
+// extensions. Given no paths, it formats standard input.
+//
+// Usage:
+//
+//	kvfmt [flags] [path ...]
+//
+// The flags are:
+//
+//	-l
+//		list files whose formatting differs from kvfmt's
+//	-d
+//		print diffs instead of the formatted files
+//	-w
+//		write the formatted files back to their source files
+//	-ext
+//		comma separated extensions of the files to format in directories
+//
+// Comments are kept with the line they come before or end. Files with
+// conditionals, or other content that the Reader does not keep, are reported
+// as errors and left unchanged.
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+var (
+	list       = flag.Bool("l", false, "list files whose formatting differs from kvfmt's")
+	doDiff     = flag.Bool("d", false, "print diffs instead of the formatted files")
+	write      = flag.Bool("w", false, "write the formatted files back to their source files")
+	extensions = flag.String("ext", ".vmt,.vmf,.vdf,.acf,.res,.txt", "comma separated extensions of the files to format in directories")
+)
+
+var exitCode = 0
+
+func main() {
+	flag.Usage = func() {
+		fmt.Fprintln(os.Stderr, "usage: kvfmt [flags] [path ...]")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if flag.NArg() == 0 {
+		if *write {
+			fmt.Fprintln(os.Stderr, "kvfmt: cannot use -w with standard input")
+			os.Exit(2)
+		}
+		if err := processFile("<standard input>", os.Stdin, os.Stdout); err != nil {
+			report(err)
+		}
+		os.Exit(exitCode)
+	}
+
+	for _, path := range flag.Args() {
+		info, err := os.Stat(path)
+		if err != nil {
+			report(err)
+			continue
+		}
+		if info.IsDir() {
+			walkDir(path)
+			continue
+		}
+		if err = processPath(path); err != nil {
+			report(err)
+		}
+	}
+	os.Exit(exitCode)
+}
+
+func walkDir(dir string) {
+	allowed := map[string]bool{}
+	for _, ext := range strings.Split(*extensions, ",") {
+		allowed[strings.ToLower(strings.TrimSpace(ext))] = true
+	}
+	filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			report(err)
+			return nil
+		}
+		if info.IsDir() || !allowed[strings.ToLower(filepath.Ext(path))] {
+			return nil
+		}
+		if err = processPath(path); err != nil {
+			report(err)
+		}
+		return nil
+	})
+}
+
+func processPath(path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	return processFile(path, file, os.Stdout)
+}
+
+// processFile formats a single file, and lists, diffs, writes or prints it
+// as the flags ask
+func processFile(filename string, in io.Reader, out io.Writer) error {
+	src, err := ioutil.ReadAll(in)
+	if err != nil {
+		return err
+	}
+	res, err := format(src)
+	if err != nil {
+		return fmt.Errorf("%s: %v", filename, err)
+	}
+
+	if !bytes.Equal(src, res) {
+		if *list {
+			fmt.Fprintln(out, filename)
+		}
+		if *write {
+			info, err := os.Stat(filename)
+			if err != nil {
+				return err
+			}
+			if err = ioutil.WriteFile(filename, res, info.Mode().Perm()); err != nil {
+				return err
+			}
+		}
+		if *doDiff {
+			out.Write(unifiedDiff(filename, src, res))
+		}
+	}
+	if !*list && !*write && !*doDiff {
+		_, err = out.Write(res)
+	}
+	return err
+}
+
+func report(err error) {
+	fmt.Fprintln(os.Stderr, err)
+	exitCode = 2
+}
@@ -0,0 +1,350 @@
+package main
+
+import (
+	"bytes"
+	"go/format"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/galaco/KeyValues"
+	"github.com/galaco/KeyValues/schema"
+)
+
+// fieldKind is how a field's value is read
+type fieldKind int
+
+const kindString = fieldKind(0)
+const kindInt32 = fieldKind(1)
+const kindFloat32 = fieldKind(2)
+const kindInt64 = fieldKind(3)
+const kindUint64 = fieldKind(4)
+const kindUint32 = fieldKind(5)
+const kindStruct = fieldKind(6)
+const kindNode = fieldKind(7)
+
+// helpers are the parse functions of each leaf kind. parse reads value into
+// val, which convert turns into the field's type.
+var helpers = map[fieldKind]struct {
+	name    string
+	goType  string
+	parse   string
+	convert string
+}{
+	kindString:  {"parseString", "string", "", ""},
+	kindInt32:   {"parseInt32", "int32", "strconv.ParseInt(value, 10, 32)", "int32(val)"},
+	kindFloat32: {"parseFloat32", "float32", "strconv.ParseFloat(value, 32)", "float32(val)"},
+	kindInt64:   {"parseInt64", "int64", "strconv.ParseInt(value, 10, 64)", "val"},
+	kindUint64:  {"parseUint64", "uint64", "strconv.ParseUint(value, 10, 64)", "val"},
+	kindUint32:  {"parseUint32", "uint32", "strconv.ParseUint(value, 10, 32)", "uint32(val)"},
+}
+
+// structType is a generated struct, for a block
+type structType struct {
+	name   string
+	key    string
+	fields []*field
+}
+
+// field is a field of a generated struct, for a key of its block
+type field struct {
+	name     string
+	key      string
+	kind     fieldKind
+	repeated bool
+	required bool
+	child    *structType
+}
+
+// generator turns a schema into Go source
+type generator struct {
+	pkg     string
+	types   []*structType
+	names   map[string]bool
+	helpers map[fieldKind]bool
+	// tops are the structs of top level blocks, that get a Read function
+	tops []*structType
+}
+
+// generate returns Go source with a struct for each block in s, a function
+// that builds each from its block, and a Read function for each top level
+// block
+func generate(pkg string, s *schema.Schema) ([]byte, error) {
+	g := &generator{
+		pkg:     pkg,
+		names:   map[string]bool{},
+		helpers: map[fieldKind]bool{},
+	}
+	// top level blocks are named first, so that they keep their own names
+	var tops []*schema.Key
+	var names []string
+	for _, key := range uniqueKeys(s) {
+		if key.Children != nil {
+			tops = append(tops, key)
+			names = append(names, g.typeName("", key.Name))
+		}
+	}
+	for idx, key := range tops {
+		g.tops = append(g.tops, g.addStruct(names[idx], key))
+	}
+
+	var buf bytes.Buffer
+	g.writeHeader(&buf)
+	for _, t := range g.types {
+		g.writeStruct(&buf, t)
+	}
+	for _, t := range g.tops {
+		g.writeRead(&buf, t)
+	}
+	g.writeHelpers(&buf)
+	return format.Source(buf.Bytes())
+}
+
+// addStruct declares the struct of a block key, and of every block in it
+func (g *generator) addStruct(name string, key *schema.Key) *structType {
+	t := &structType{name: name, key: key.Name}
+	g.types = append(g.types, t)
+
+	fieldNames := map[string]bool{"Unknown": true}
+	for _, child := range uniqueKeys(key.Children) {
+		f := &field{
+			name:     uniqueName(fieldNames, exportedName(child.Name)),
+			key:      child.Name,
+			kind:     leafKind(child),
+			repeated: child.MaxCount == schema.Unlimited || child.MaxCount > 1,
+			required: child.Required || child.MinCount > 0,
+		}
+		if child.Children != nil {
+			f.kind = kindStruct
+			f.child = g.addStruct(g.typeName(t.name, child.Name), child)
+		}
+		if f.kind != kindStruct && f.kind != kindNode {
+			g.helpers[f.kind] = true
+		}
+		t.fields = append(t.fields, f)
+	}
+	return t
+}
+
+// typeName returns an unused type name for a block, prefixed by its
+// parent's name if its own is taken
+func (g *generator) typeName(parent string, key string) string {
+	name := exportedName(key)
+	if g.names[name] || g.names["Read"+name] {
+		name = parent + name
+	}
+	name = uniqueName(g.names, name)
+	g.names["Read"+name] = true
+	return name
+}
+
+func (g *generator) writeHeader(buf *bytes.Buffer) {
+	buf.WriteString("// Code generated by kvgen. DO NOT EDIT.\n\npackage " + g.pkg + "\n\nimport (\n\t\"errors\"\n")
+	if len(g.tops) > 0 {
+		buf.WriteString("\t\"io\"\n")
+	}
+	for kind := range g.helpers {
+		if kind != kindString {
+			buf.WriteString("\t\"strconv\"\n")
+			break
+		}
+	}
+	buf.WriteString("\t\"strings\"\n\n\t\"github.com/galaco/KeyValues\"\n)\n")
+}
+
+func (g *generator) writeStruct(buf *bytes.Buffer, t *structType) {
+	buf.WriteString("\n// " + t.name + " is read from a " + strconv.Quote(t.key) + " block\n")
+	buf.WriteString("type " + t.name + " struct {\n")
+	for _, f := range t.fields {
+		goType := "*keyvalues.KeyValue"
+		switch f.kind {
+		case kindStruct:
+			goType = "*" + f.child.name
+		case kindNode:
+		default:
+			goType = helpers[f.kind].goType
+		}
+		if f.repeated {
+			goType = "[]" + goType
+		}
+		buf.WriteString("\t" + f.name + " " + goType + " `kv:" + strconv.Quote(f.key) + "`\n")
+	}
+	buf.WriteString("\t// Unknown holds keys that aren't part of the model\n")
+	buf.WriteString("\tUnknown []*keyvalues.KeyValue `kv:\"-\"`\n}\n")
+
+	var required []*field
+	buf.WriteString("\n// " + t.name + "FromKeyValue builds a " + t.name + " from a " + strconv.Quote(t.key) + " block\n")
+	buf.WriteString("func " + t.name + "FromKeyValue(kv *keyvalues.KeyValue) (*" + t.name + ", error) {\n")
+	buf.WriteString("children, err := kv.Children()\nif err != nil {\nreturn nil, errors.New(" + strconv.Quote(t.key+": ") + " + err.Error())\n}\n")
+	buf.WriteString("out := &" + t.name + "{}\n")
+	for _, f := range t.fields {
+		if f.required {
+			required = append(required, f)
+		}
+	}
+	if len(required) > 0 {
+		buf.WriteString("seen := map[string]bool{}\n")
+	}
+	buf.WriteString("for _, child := range children {\n")
+	buf.WriteString("key := strings.ToLower(child.Key())\n")
+	if len(required) > 0 {
+		buf.WriteString("seen[key] = true\n")
+	}
+	buf.WriteString("switch key {\n")
+	for _, f := range t.fields {
+		buf.WriteString("case " + strconv.Quote(strings.ToLower(f.key)) + ":\n")
+		read, goType := "", "*keyvalues.KeyValue"
+		switch f.kind {
+		case kindStruct:
+			read, goType = f.child.name+"FromKeyValue(child)", "*"+f.child.name
+		case kindNode:
+		default:
+			read, goType = helpers[f.kind].name+"(child)", helpers[f.kind].goType
+		}
+		switch {
+		case f.kind == kindNode && f.repeated:
+			buf.WriteString("out." + f.name + " = append(out." + f.name + ", child)\n")
+		case f.kind == kindNode:
+			buf.WriteString("out." + f.name + " = child\n")
+		case f.repeated:
+			buf.WriteString("var value " + goType + "\nvalue, err = " + read + "\n")
+			buf.WriteString("out." + f.name + " = append(out." + f.name + ", value)\n")
+		default:
+			buf.WriteString("out." + f.name + ", err = " + read + "\n")
+		}
+	}
+	buf.WriteString("default:\nout.Unknown = append(out.Unknown, child)\n}\n")
+	buf.WriteString("if err != nil {\nreturn nil, errors.New(" + strconv.Quote(t.key+"/") + " + err.Error())\n}\n}\n")
+	for _, f := range required {
+		buf.WriteString("if !seen[" + strconv.Quote(strings.ToLower(f.key)) + "] {\n")
+		buf.WriteString("return nil, errors.New(" + strconv.Quote(t.key+": missing "+f.key) + ")\n}\n")
+	}
+	buf.WriteString("return out, nil\n}\n")
+}
+
+// writeRead writes a function that reads a top level block from a stream
+func (g *generator) writeRead(buf *bytes.Buffer, t *structType) {
+	buf.WriteString("\n// Read" + t.name + " reads a " + t.name + " from a KeyValues stream\n")
+	buf.WriteString("func Read" + t.name + "(file io.Reader) (*" + t.name + ", error) {\n")
+	buf.WriteString("reader := keyvalues.NewReader(file)\nkv, err := reader.Read()\nif err != nil {\nreturn nil, err\n}\n")
+	buf.WriteString("if !strings.EqualFold(kv.Key(), " + strconv.Quote(t.key) + ") {\n")
+	buf.WriteString("found, err := kv.Find(" + strconv.Quote(t.key) + ")\nif err != nil {\n")
+	buf.WriteString("return nil, errors.New(" + strconv.Quote(t.key+" key not found") + ")\n}\n")
+	buf.WriteString("return " + t.name + "FromKeyValue(found)\n}\n")
+	buf.WriteString("return " + t.name + "FromKeyValue(&kv)\n}\n")
+}
+
+func (g *generator) writeHelpers(buf *bytes.Buffer) {
+	var kinds []int
+	for kind := range g.helpers {
+		kinds = append(kinds, int(kind))
+	}
+	sort.Ints(kinds)
+	for _, kind := range kinds {
+		h := helpers[fieldKind(kind)]
+		zero := "0"
+		if fieldKind(kind) == kindString {
+			zero = "\"\""
+		}
+		buf.WriteString("\nfunc " + h.name + "(child *keyvalues.KeyValue) (" + h.goType + ", error) {\n")
+		buf.WriteString("value, err := child.Value()\nif err != nil {\nreturn " + zero + ", keyError(child, err)\n}\n")
+		if h.parse == "" {
+			buf.WriteString("return value, nil\n}\n")
+			continue
+		}
+		buf.WriteString("val, err := " + strings.Replace(h.parse, "value", "strings.TrimSpace(value)", 1) + "\n")
+		buf.WriteString("if err != nil {\nreturn 0, keyError(child, err)\n}\nreturn " + h.convert + ", nil\n}\n")
+	}
+	if len(kinds) > 0 {
+		buf.WriteString("\nfunc keyError(child *keyvalues.KeyValue, err error) error {\n")
+		buf.WriteString("return errors.New(child.Key() + \": \" + err.Error())\n}\n")
+	}
+}
+
+// leafKind returns the kind of a key that isn't a declared block
+func leafKind(key *schema.Key) fieldKind {
+	if len(key.Types) == 0 {
+		return kindString
+	}
+	kind := -1
+	for _, valueType := range key.Types {
+		var next fieldKind
+		switch valueType {
+		case keyvalues.ValueArray:
+			return kindNode
+		case keyvalues.ValueInt:
+			next = kindInt32
+		case keyvalues.ValueFloat:
+			next = kindFloat32
+		case keyvalues.ValueInt64:
+			next = kindInt64
+		case keyvalues.ValueUint64:
+			next = kindUint64
+		case keyvalues.ValuePtr:
+			next = kindUint32
+		default:
+			next = kindString
+		}
+		switch {
+		case kind < 0 || fieldKind(kind) == next:
+			kind = int(next)
+		case fieldKind(kind) == kindInt32 && next == kindFloat32 || fieldKind(kind) == kindFloat32 && next == kindInt32:
+			kind = int(kindFloat32)
+		default:
+			return kindString
+		}
+	}
+	return fieldKind(kind)
+}
+
+// uniqueKeys returns the keys of s that can be fields: the first of keys
+// that only differ by case, and none with wildcards
+func uniqueKeys(s *schema.Schema) (keys []*schema.Key) {
+	seen := map[string]bool{}
+	for _, key := range s.Keys {
+		lower := strings.ToLower(key.Name)
+		if seen[lower] || strings.ContainsAny(key.Name, "*?[") {
+			continue
+		}
+		seen[lower] = true
+		keys = append(keys, key)
+	}
+	return keys
+}
+
+// exportedName turns a key into an exported Go identifier, e.g. clip_size
+// into ClipSize and $basetexture into Basetexture
+func exportedName(key string) string {
+	var name strings.Builder
+	upper := true
+	for _, r := range key {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			upper = true
+			continue
+		}
+		if upper {
+			r = unicode.ToUpper(r)
+			upper = false
+		}
+		name.WriteRune(r)
+	}
+	if name.Len() == 0 {
+		return "Key"
+	}
+	if first := []rune(name.String())[0]; !unicode.IsLetter(first) || !unicode.IsUpper(first) {
+		return "K" + name.String()
+	}
+	return name.String()
+}
+
+// uniqueName returns name, with a number added if it is already in names,
+// and adds it to names
+func uniqueName(names map[string]bool, name string) string {
+	unique := name
+	for idx := 2; names[unique]; idx++ {
+		unique = name + strconv.Itoa(idx)
+	}
+	names[unique] = true
+	return unique
+}
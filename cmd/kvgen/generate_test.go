@@ -0,0 +1,107 @@
+package main
+
+import (
+	"go/parser"
+	"go/token"
+	"strings"
+	"testing"
+
+	"github.com/galaco/KeyValues"
+	"github.com/galaco/KeyValues/schema"
+)
+
+func TestGenerate(t *testing.T) {
+	s := &schema.Schema{
+		Keys: []*schema.Key{
+			{Name: "#base"},
+			{Name: "WeaponData", Children: &schema.Schema{
+				Keys: []*schema.Key{
+					{Name: "printname", Required: true},
+					{Name: "clip_size", Types: []keyvalues.ValueType{keyvalues.ValueInt}},
+					{Name: "bucket", MaxCount: schema.Unlimited, Types: []keyvalues.ValueType{keyvalues.ValueInt, keyvalues.ValueFloat}},
+					{Name: "Bucket", Types: []keyvalues.ValueType{keyvalues.ValueString}},
+					{Name: "$model*"},
+					{Name: "unknown", Types: []keyvalues.ValueType{keyvalues.ValueString, keyvalues.ValueArray}},
+					{Name: "Data", Children: &schema.Schema{}},
+				},
+			}},
+			{Name: "Data", Children: &schema.Schema{
+				Keys: []*schema.Key{{Name: "id", Types: []keyvalues.ValueType{keyvalues.ValueUint64}}},
+			}},
+		},
+	}
+
+	src, err := generate("weapons", s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = parser.ParseFile(token.NewFileSet(), "gen.go", src, 0); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []string{
+		"package weapons",
+		"Printname string `kv:\"printname\"`",
+		"ClipSize int32 `kv:\"clip_size\"`",
+		"Bucket []float32 `kv:\"bucket\"`",
+		"Unknown2 *keyvalues.KeyValue `kv:\"unknown\"`",
+		"Data *WeaponDataData `kv:\"Data\"`",
+		"type WeaponDataData struct",
+		"Id uint64 `kv:\"id\"`",
+		"func WeaponDataFromKeyValue(kv *keyvalues.KeyValue) (*WeaponData, error)",
+		"return nil, errors.New(\"WeaponData: missing printname\")",
+		"func ReadWeaponData(file io.Reader) (*WeaponData, error)",
+		"func ReadData(file io.Reader) (*Data, error)",
+		"func parseUint64(child *keyvalues.KeyValue) (uint64, error)",
+	}
+	for _, e := range expected {
+		if !strings.Contains(normalizeSpace(string(src)), normalizeSpace(e)) {
+			t.Errorf("expected generated code to contain %s", e)
+		}
+	}
+	for _, unexpected := range []string{"Model", "Base"} {
+		if strings.Contains(string(src), unexpected) {
+			t.Errorf("unexpected %s in generated code", unexpected)
+		}
+	}
+}
+
+func TestExportedName(t *testing.T) {
+	samples := map[string]string{
+		"printname":    "Printname",
+		"clip_size":    "ClipSize",
+		"$basetexture": "Basetexture",
+		"SteamAppId":   "SteamAppId",
+		"3d_sky":       "K3dSky",
+		"%":            "Key",
+	}
+	for key, expected := range samples {
+		if name := exportedName(key); name != expected {
+			t.Errorf("%s: expected %s, got %s", key, expected, name)
+		}
+	}
+}
+
+func TestLeafKind(t *testing.T) {
+	samples := []struct {
+		types    []keyvalues.ValueType
+		expected fieldKind
+	}{
+		{nil, kindString},
+		{[]keyvalues.ValueType{keyvalues.ValueInt}, kindInt32},
+		{[]keyvalues.ValueType{keyvalues.ValueInt, keyvalues.ValueFloat}, kindFloat32},
+		{[]keyvalues.ValueType{keyvalues.ValueInt, keyvalues.ValueColor}, kindString},
+		{[]keyvalues.ValueType{keyvalues.ValuePtr}, kindUint32},
+		{[]keyvalues.ValueType{keyvalues.ValueInt64}, kindInt64},
+		{[]keyvalues.ValueType{keyvalues.ValueString, keyvalues.ValueArray}, kindNode},
+	}
+	for _, sample := range samples {
+		if kind := leafKind(&schema.Key{Types: sample.types}); kind != sample.expected {
+			t.Errorf("%v: expected %d, got %d", sample.types, sample.expected, kind)
+		}
+	}
+}
+
+func normalizeSpace(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
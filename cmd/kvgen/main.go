@@ -0,0 +1,118 @@
+// Command kvgen generates Go structs, and the code to read them, for a
+// KeyValues format.
+//
+// Usage:
+//
+//	kvgen [flags] file ...
+//
+// The format is either inferred from a corpus of sample files, as
+// schema.Infer does, or read from a single schema file written as
+// schema.Parse describes with -schema.
+//
+// Each block gets a struct, with a field for each of its keys tagged with
+// the key's name, and an Unknown field for keys that aren't part of it.
+// Repeated keys become slices. Each struct gets a NameFromKeyValue function
+// that builds it from its block, and returns an error for invalid values and
+// missing required keys. Top level blocks also get a ReadName function that
+// reads them from a stream.
+//
+// The flags are:
+//
+//	-schema
+//		read the file as a schema instead of inferring one from samples
+//	-package
+//		package name of the generated code (default $GOPACKAGE, or main)
+//	-o
+//		write the generated code here instead of to standard output
+//
+// kvgen can be run by go generate, e.g.
+//
+//	//go:generate kvgen -schema -o weapon_gen.go weapon.schema
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+
+	"github.com/galaco/KeyValues"
+	"github.com/galaco/KeyValues/schema"
+)
+
+var (
+	fromSchema = flag.Bool("schema", false, "read the file as a schema instead of inferring one from samples")
+	pkg        = flag.String("package", "", "package name of the generated code (default $GOPACKAGE, or main)")
+	output     = flag.String("o", "", "write the generated code here instead of to standard output")
+)
+
+func main() {
+	flag.Usage = func() {
+		fmt.Fprintln(os.Stderr, "usage: kvgen [flags] file ...")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() == 0 || *fromSchema && flag.NArg() != 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	if *pkg == "" {
+		*pkg = os.Getenv("GOPACKAGE")
+	}
+	if *pkg == "" {
+		*pkg = "main"
+	}
+
+	var samples []*keyvalues.KeyValue
+	for _, filename := range flag.Args() {
+		tree, err := readFile(filename)
+		if err != nil {
+			fail(err)
+		}
+		samples = append(samples, tree)
+	}
+
+	var s *schema.Schema
+	if *fromSchema {
+		var err error
+		if s, err = schema.Parse(samples[0]); err != nil {
+			fail(fmt.Errorf("%s: %v", flag.Arg(0), err))
+		}
+	} else {
+		s = schema.Infer(samples...)
+	}
+
+	src, err := generate(*pkg, s)
+	if err != nil {
+		fail(err)
+	}
+	if *output == "" {
+		_, err = os.Stdout.Write(src)
+	} else {
+		err = ioutil.WriteFile(*output, src, 0644)
+	}
+	if err != nil {
+		fail(err)
+	}
+}
+
+func readFile(filename string) (*keyvalues.KeyValue, error) {
+	src, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	reader := keyvalues.NewReader(bytes.NewReader(src))
+	tree, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", filename, err)
+	}
+	return &tree, nil
+}
+
+func fail(err error) {
+	fmt.Fprintln(os.Stderr, "kvgen:", err)
+	os.Exit(2)
+}
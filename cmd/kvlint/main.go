@@ -0,0 +1,196 @@
+// Command kvlint reports likely problems in KeyValues files.
+//
+// Given a directory, it checks every file inside it with one of the -ext
+// extensions. Given no paths, it checks standard input.
+//
+// Usage:
+//
+//	kvlint [flags] [path ...]
+//
+// The flags are:
+//
+//	-enable
+//		comma separated checks to run, instead of all of them
+//	-disable
+//		comma separated checks to skip
+//	-json
+//		print the problems as a JSON array
+//	-ext
+//		comma separated extensions of the files to check in directories
+//
+// Each problem is printed as file:line:column: check: message. The checks
+// are syntax, duplicate-key, key-case, braces, conditional, empty-block,
+// numeric-quoting and base.
+//
+// kvlint exits with status 1 if it finds any problem, and 2 if a file can't
+// be read or a flag is invalid.
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/galaco/KeyValues/lint"
+)
+
+var (
+	enable     = flag.String("enable", "", "comma separated checks to run, instead of all of them")
+	disable    = flag.String("disable", "", "comma separated checks to skip")
+	asJSON     = flag.Bool("json", false, "print the problems as a JSON array")
+	extensions = flag.String("ext", ".vmt,.vmf,.vdf,.acf,.res,.txt", "comma separated extensions of the files to check in directories")
+)
+
+var exitCode = 0
+
+func main() {
+	flag.Usage = func() {
+		fmt.Fprintln(os.Stderr, "usage: kvlint [flags] [path ...]")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	checks, err := selectChecks(*enable, *disable)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "kvlint:", err)
+		os.Exit(2)
+	}
+
+	var problems []lint.Problem
+	if flag.NArg() == 0 {
+		found, err := lintFile("<standard input>", os.Stdin, checks)
+		if err != nil {
+			report(err)
+		}
+		problems = append(problems, found...)
+	}
+	for _, path := range flag.Args() {
+		info, err := os.Stat(path)
+		if err != nil {
+			report(err)
+			continue
+		}
+		if info.IsDir() {
+			problems = append(problems, walkDir(path, checks)...)
+			continue
+		}
+		problems = append(problems, lintPath(path, checks)...)
+	}
+
+	if err = printProblems(os.Stdout, problems, *asJSON); err != nil {
+		report(err)
+	}
+	if len(problems) > 0 && exitCode == 0 {
+		exitCode = 1
+	}
+	os.Exit(exitCode)
+}
+
+// selectChecks returns the checks named in enable, or every check if it is
+// empty, without those named in disable
+func selectChecks(enable string, disable string) ([]lint.Check, error) {
+	known := map[lint.Check]bool{}
+	for _, check := range lint.AllChecks {
+		known[check] = true
+	}
+	parse := func(list string) (map[lint.Check]bool, error) {
+		checks := map[lint.Check]bool{}
+		for _, name := range strings.Split(list, ",") {
+			name = strings.TrimSpace(name)
+			if name == "" {
+				continue
+			}
+			if !known[lint.Check(name)] {
+				return nil, fmt.Errorf("unknown check %q", name)
+			}
+			checks[lint.Check(name)] = true
+		}
+		return checks, nil
+	}
+
+	enabled, err := parse(enable)
+	if err != nil {
+		return nil, err
+	}
+	disabled, err := parse(disable)
+	if err != nil {
+		return nil, err
+	}
+
+	var checks []lint.Check
+	for _, check := range lint.AllChecks {
+		if (len(enabled) == 0 || enabled[check]) && !disabled[check] {
+			checks = append(checks, check)
+		}
+	}
+	return checks, nil
+}
+
+func walkDir(dir string, checks []lint.Check) (problems []lint.Problem) {
+	allowed := map[string]bool{}
+	for _, ext := range strings.Split(*extensions, ",") {
+		allowed[strings.ToLower(strings.TrimSpace(ext))] = true
+	}
+	filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			report(err)
+			return nil
+		}
+		if info.IsDir() || !allowed[strings.ToLower(filepath.Ext(path))] {
+			return nil
+		}
+		problems = append(problems, lintPath(path, checks)...)
+		return nil
+	})
+	return problems
+}
+
+func lintPath(path string, checks []lint.Check) []lint.Problem {
+	file, err := os.Open(path)
+	if err != nil {
+		report(err)
+		return nil
+	}
+	defer file.Close()
+	problems, err := lintFile(path, file, checks)
+	if err != nil {
+		report(err)
+	}
+	return problems
+}
+
+func lintFile(filename string, in io.Reader, checks []lint.Check) ([]lint.Problem, error) {
+	src, err := ioutil.ReadAll(in)
+	if err != nil {
+		return nil, err
+	}
+	return lint.Lint(filename, src, checks), nil
+}
+
+// printProblems writes problems one per line, or as a JSON array
+func printProblems(out io.Writer, problems []lint.Problem, asJSON bool) error {
+	if asJSON {
+		if problems == nil {
+			problems = []lint.Problem{}
+		}
+		encoder := json.NewEncoder(out)
+		encoder.SetIndent("", "\t")
+		return encoder.Encode(problems)
+	}
+	for _, problem := range problems {
+		if _, err := fmt.Fprintln(out, problem.String()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func report(err error) {
+	fmt.Fprintln(os.Stderr, err)
+	exitCode = 2
+}
@@ -0,0 +1,105 @@
+// Command kvmerge merges KeyValues files, using the Patch and Replace
+// methods of KeyValue.
+//
+// Usage:
+//
+//	kvmerge [flags] base overlay [overlay ...]
+//
+// Each overlay is merged into the result of the ones before it, and the
+// merged file is printed. Any file may be - for standard input.
+//
+// An overlay's root key must match the base's root key, unless it is one of
+// the reserved root keys patch or replace, which merge into any root.
+//
+// The flags are:
+//
+//	-mode
+//		patch to only add keys missing from the base, replace to also
+//		overwrite values the base has, or auto to patch overlays with a
+//		patch root key and replace with the rest (default auto)
+//	-o
+//		write the merged file here instead of to standard output
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+
+	"github.com/galaco/KeyValues"
+)
+
+var (
+	mode   = flag.String("mode", modeAuto, "patch, replace or auto to choose from the overlay's root key")
+	output = flag.String("o", "", "write the merged file here instead of to standard output")
+)
+
+func main() {
+	flag.Usage = func() {
+		fmt.Fprintln(os.Stderr, "usage: kvmerge [flags] base overlay [overlay ...]")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() < 2 {
+		flag.Usage()
+		os.Exit(2)
+	}
+	if *mode != modeAuto && *mode != modePatch && *mode != modeReplace {
+		fail(fmt.Errorf("unknown merge mode: %s", *mode))
+	}
+
+	merged, err := readFile(flag.Arg(0))
+	if err != nil {
+		fail(err)
+	}
+	for _, filename := range flag.Args()[1:] {
+		overlay, err := readFile(filename)
+		if err != nil {
+			fail(err)
+		}
+		if merged, err = merge(merged, overlay, *mode); err != nil {
+			fail(fmt.Errorf("%s: %v", filename, err))
+		}
+	}
+
+	var buf bytes.Buffer
+	writer := keyvalues.NewWriter(&buf)
+	if err = writer.Write(merged); err != nil {
+		fail(err)
+	}
+	if *output == "" {
+		_, err = os.Stdout.Write(buf.Bytes())
+	} else {
+		err = ioutil.WriteFile(*output, buf.Bytes(), 0644)
+	}
+	if err != nil {
+		fail(err)
+	}
+}
+
+func readFile(filename string) (*keyvalues.KeyValue, error) {
+	var src []byte
+	var err error
+	if filename == "-" {
+		src, err = ioutil.ReadAll(os.Stdin)
+	} else {
+		src, err = ioutil.ReadFile(filename)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	reader := keyvalues.NewReader(bytes.NewReader(src))
+	tree, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", filename, err)
+	}
+	return &tree, nil
+}
+
+func fail(err error) {
+	fmt.Fprintln(os.Stderr, "kvmerge:", err)
+	os.Exit(2)
+}
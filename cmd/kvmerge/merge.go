@@ -0,0 +1,38 @@
+package main
+
+import (
+	"errors"
+
+	"github.com/galaco/KeyValues"
+)
+
+const modeAuto = "auto"
+const modePatch = "patch"
+const modeReplace = "replace"
+
+// merge merges overlay into base with KeyValue.Patch or KeyValue.Replace.
+// In auto mode an overlay with a patch root key is patched in, and any other
+// overlay replaces, including one with a replace root key.
+func merge(base *keyvalues.KeyValue, overlay *keyvalues.KeyValue, mode string) (*keyvalues.KeyValue, error) {
+	if mode == modeAuto {
+		mode = modeReplace
+		if overlay.Key() == modePatch {
+			mode = modePatch
+		}
+	}
+
+	var merged keyvalues.KeyValue
+	var err error
+	switch mode {
+	case modePatch:
+		merged, err = overlay.Patch(base)
+	case modeReplace:
+		merged, err = overlay.Replace(base)
+	default:
+		return nil, errors.New("unknown merge mode: " + mode)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &merged, nil
+}
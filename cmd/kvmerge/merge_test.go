@@ -0,0 +1,55 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/galaco/KeyValues"
+)
+
+func sampleBase() *keyvalues.KeyValue {
+	return keyvalues.NewKeyValueArray("GameInfo",
+		keyvalues.NewKeyValue("game", "Half-Life 2"),
+		keyvalues.NewKeyValueArray("FileSystem",
+			keyvalues.NewKeyValue("SteamAppId", "220")))
+}
+
+func TestMerge(t *testing.T) {
+	samples := []struct {
+		mode     string
+		overlay  *keyvalues.KeyValue
+		expected string
+	}{
+		{modeReplace, keyvalues.NewKeyValueArray("GameInfo", keyvalues.NewKeyValue("game", "Episode One")), "Episode One"},
+		{modePatch, keyvalues.NewKeyValueArray("GameInfo", keyvalues.NewKeyValue("game", "Episode One")), "Half-Life 2"},
+		{modeAuto, keyvalues.NewKeyValueArray("GameInfo", keyvalues.NewKeyValue("game", "Episode One")), "Episode One"},
+		{modeAuto, keyvalues.NewKeyValueArray("patch", keyvalues.NewKeyValue("game", "Episode One")), "Half-Life 2"},
+		{modeAuto, keyvalues.NewKeyValueArray("replace", keyvalues.NewKeyValue("game", "Episode One")), "Episode One"},
+	}
+	for _, sample := range samples {
+		merged, err := merge(sampleBase(), sample.overlay, sample.mode)
+		if err != nil {
+			t.Errorf("%s: %s", sample.mode, err)
+			continue
+		}
+		game, err := merged.Find("game")
+		if err != nil {
+			t.Error(err)
+			continue
+		}
+		if value, _ := game.Value(); value != sample.expected {
+			t.Errorf("%s: expected %s, got %s", sample.mode, sample.expected, value)
+		}
+		if _, err = merged.Find("FileSystem"); err != nil {
+			t.Error(err)
+		}
+	}
+}
+
+func TestMerge_Errors(t *testing.T) {
+	if _, err := merge(sampleBase(), keyvalues.NewKeyValueArray("Other"), modeAuto); err == nil {
+		t.Error("expected error for mismatched root keys")
+	}
+	if _, err := merge(sampleBase(), keyvalues.NewKeyValueArray("GameInfo"), "unknown"); err == nil {
+		t.Error("expected error for unknown mode")
+	}
+}
@@ -0,0 +1,148 @@
+// Command kvq prints the parts of KeyValues files selected by a query.
+//
+// Usage:
+//
+//	kvq [flags] query [file ...]
+//
+// Given no files, kvq reads standard input.
+//
+// A query is a list of keys separated by /, starting with a top level key of
+// the file. Keys ignore case like Find does, may use * and ? wildcards, and may
+// be quoted to match /, [ or wildcard characters literally. ** matches any
+// number of nested blocks. A key may be followed by filters:
+//
+//	[n]		the nth match under each parent, counting from 0
+//	[key]		blocks with a child key
+//	[key=value]	blocks with a child key with that value
+//	[key!=value]	blocks without a child key with that value
+//
+// For example:
+//
+//	kvq 'GameInfo/FileSystem/SteamAppId' gameinfo.txt
+//	kvq 'GameInfo/FileSystem/SearchPaths/Game[0]' gameinfo.txt
+//	kvq '**/solid[id=2]/side/material' map.vmf
+//
+// Values are printed one per line, and blocks as KeyValues text.
+//
+// The flags are:
+//
+//	-json
+//		print each match as JSON, in the friendly form of ToJSON
+//	-kv
+//		print values as KeyValues text too, with their keys
+//
+// kvq exits with status 1 if nothing matched, and 2 on any error.
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+
+	"github.com/galaco/KeyValues"
+)
+
+var (
+	asJSON = flag.Bool("json", false, "print each match as JSON, in the friendly form of ToJSON")
+	asKV   = flag.Bool("kv", false, "print values as KeyValues text too, with their keys")
+)
+
+func main() {
+	flag.Usage = func() {
+		fmt.Fprintln(os.Stderr, "usage: kvq [flags] query [file ...]")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	q, err := compile(flag.Arg(0))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "kvq:", err)
+		os.Exit(2)
+	}
+
+	var matched bool
+	exitCode := 0
+	run := func(name string, in io.Reader) {
+		found, err := queryFile(q, in, os.Stdout)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %v\n", name, err)
+			exitCode = 2
+		}
+		matched = matched || found
+	}
+
+	if flag.NArg() == 1 {
+		run("<standard input>", os.Stdin)
+	}
+	for _, filename := range flag.Args()[1:] {
+		file, err := os.Open(filename)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			exitCode = 2
+			continue
+		}
+		run(filename, file)
+		file.Close()
+	}
+
+	if !matched && exitCode == 0 {
+		exitCode = 1
+	}
+	os.Exit(exitCode)
+}
+
+// queryFile reads a file, and prints the nodes that q selects from it.
+// It returns whether anything matched.
+func queryFile(q query, in io.Reader, out io.Writer) (bool, error) {
+	src, err := ioutil.ReadAll(in)
+	if err != nil {
+		return false, err
+	}
+	reader := keyvalues.NewReader(bytes.NewReader(src))
+	tree, err := reader.Read()
+	if err != nil {
+		return false, err
+	}
+
+	tops := []*keyvalues.KeyValue{&tree}
+	if tree.Key() == keyvalues.RootNodeKey {
+		tops, _ = tree.Children()
+	}
+
+	matches := q.eval(tops)
+	for _, match := range matches {
+		if err = printNode(out, match); err != nil {
+			return true, err
+		}
+	}
+	return len(matches) > 0, nil
+}
+
+func printNode(out io.Writer, node *keyvalues.KeyValue) error {
+	switch {
+	case *asJSON:
+		data, err := node.ToJSON(keyvalues.JSONFriendly)
+		if err != nil {
+			return err
+		}
+		_, err = out.Write(append(data, '\n'))
+		return err
+	case node.HasChildren() || *asKV:
+		writer := keyvalues.NewWriter(out)
+		return writer.Write(node)
+	default:
+		value, err := node.Value()
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintln(out, value)
+		return err
+	}
+}
@@ -0,0 +1,254 @@
+package main
+
+import (
+	"errors"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/galaco/KeyValues"
+)
+
+// step is a single /-separated segment of a query
+type step struct {
+	// pattern is matched against keys ignoring case, as a path.Match
+	// pattern unless literal is set
+	pattern    string
+	literal    bool
+	descendant bool
+	predicates []predicate
+}
+
+// predicate filters the nodes matched by a step.
+// index is used when key is empty.
+type predicate struct {
+	index    int
+	key      string
+	value    string
+	hasValue bool
+	negate   bool
+}
+
+// query is a compiled query expression
+type query []step
+
+// compile parses a query expression: keys separated by /, where a key may be
+// quoted, may use * and ? wildcards, and may be followed by any number of
+// [n], [key], [key=value] and [key!=value] filters. ** matches any number of
+// nested blocks.
+func compile(expr string) (query, error) {
+	expr = strings.TrimPrefix(strings.TrimSpace(expr), "/")
+	if expr == "" {
+		return nil, errors.New("empty query")
+	}
+
+	var q query
+	for pos := 0; pos <= len(expr); {
+		var s step
+		var err error
+		if s.pattern, s.literal, pos, err = scanKey(expr, pos, "/["); err != nil {
+			return nil, err
+		}
+		for pos < len(expr) && expr[pos] == '[' {
+			var p predicate
+			if p, pos, err = scanPredicate(expr, pos+1); err != nil {
+				return nil, err
+			}
+			s.predicates = append(s.predicates, p)
+		}
+		if pos < len(expr) && expr[pos] != '/' {
+			return nil, errors.New("unexpected " + strconv.Quote(expr[pos:pos+1]) + " at offset " + strconv.Itoa(pos))
+		}
+		pos++
+
+		if s.pattern == "**" && !s.literal {
+			if len(s.predicates) > 0 {
+				return nil, errors.New("** cannot be filtered")
+			}
+			s = step{descendant: true}
+		} else if s.pattern == "" && !s.literal {
+			return nil, errors.New("empty key in query")
+		}
+		q = append(q, s)
+	}
+	return q, nil
+}
+
+// scanKey reads a quoted or unquoted key starting at pos, that ends at any
+// of the stop characters
+func scanKey(expr string, pos int, stop string) (key string, quoted bool, end int, err error) {
+	if pos < len(expr) && expr[pos] == '"' {
+		end = pos + 1
+		for end < len(expr) && expr[end] != '"' {
+			if expr[end] == '\\' {
+				end++
+			}
+			end++
+		}
+		if end >= len(expr) {
+			return "", false, 0, errors.New("unterminated quoted key")
+		}
+		key, err = strconv.Unquote(expr[pos : end+1])
+		return key, true, end + 1, err
+	}
+	end = pos
+	for end < len(expr) && !strings.ContainsRune(stop, rune(expr[end])) {
+		end++
+	}
+	return strings.TrimSpace(expr[pos:end]), false, end, nil
+}
+
+// scanPredicate reads a predicate starting after its opening bracket
+func scanPredicate(expr string, pos int) (p predicate, end int, err error) {
+	key, quoted, pos, err := scanKey(expr, pos, "!=]")
+	if err != nil {
+		return p, 0, err
+	}
+	if !quoted {
+		if index, err := strconv.Atoi(key); err == nil {
+			if index < 0 {
+				return p, 0, errors.New("negative index in query")
+			}
+			p.index = index
+			end, err = closeBracket(expr, pos)
+			return p, end, err
+		}
+	}
+	if key == "" {
+		return p, 0, errors.New("empty filter in query")
+	}
+	p.key = key
+
+	if strings.HasPrefix(expr[pos:], "!=") {
+		p.negate = true
+		pos++
+	}
+	if pos < len(expr) && expr[pos] == '=' {
+		p.hasValue = true
+		if p.value, _, pos, err = scanKey(expr, pos+1, "]"); err != nil {
+			return p, 0, err
+		}
+	}
+	end, err = closeBracket(expr, pos)
+	return p, end, err
+}
+
+// closeBracket returns the offset after the ] at pos
+func closeBracket(expr string, pos int) (int, error) {
+	if pos >= len(expr) || expr[pos] != ']' {
+		return 0, errors.New("expected ] at offset " + strconv.Itoa(pos))
+	}
+	return pos + 1, nil
+}
+
+// eval returns the nodes that the query selects from tops, the top level
+// nodes of a file. The first step matches the top level nodes themselves.
+func (q query) eval(tops []*keyvalues.KeyValue) []*keyvalues.KeyValue {
+	// the context starts as a virtual parent of the top level nodes
+	context := []*keyvalues.KeyValue{nil}
+	children := func(node *keyvalues.KeyValue) []*keyvalues.KeyValue {
+		if node == nil {
+			return tops
+		}
+		children, _ := node.Children()
+		return children
+	}
+
+	for _, s := range q {
+		var next []*keyvalues.KeyValue
+		if s.descendant {
+			var walk func(node *keyvalues.KeyValue)
+			walk = func(node *keyvalues.KeyValue) {
+				next = append(next, node)
+				for _, child := range children(node) {
+					if child.HasChildren() {
+						walk(child)
+					}
+				}
+			}
+			for _, node := range context {
+				walk(node)
+			}
+		} else {
+			for _, node := range context {
+				next = append(next, s.match(children(node))...)
+			}
+		}
+		context = unique(next)
+	}
+
+	var matches []*keyvalues.KeyValue
+	for _, node := range context {
+		if node != nil {
+			matches = append(matches, node)
+		}
+	}
+	return matches
+}
+
+// match returns the candidates that the step selects
+func (s *step) match(candidates []*keyvalues.KeyValue) (matches []*keyvalues.KeyValue) {
+	for _, candidate := range candidates {
+		if s.matchKey(candidate.Key()) {
+			matches = append(matches, candidate)
+		}
+	}
+	for _, p := range s.predicates {
+		matches = p.filter(matches)
+	}
+	return matches
+}
+
+func (s *step) matchKey(key string) bool {
+	if s.literal {
+		return strings.EqualFold(s.pattern, key)
+	}
+	ok, err := path.Match(strings.ToLower(s.pattern), strings.ToLower(key))
+	return ok && err == nil
+}
+
+func (p *predicate) filter(nodes []*keyvalues.KeyValue) (filtered []*keyvalues.KeyValue) {
+	if p.key == "" {
+		if p.index < len(nodes) {
+			return nodes[p.index : p.index+1]
+		}
+		return nil
+	}
+	for _, node := range nodes {
+		if p.test(node) != p.negate {
+			filtered = append(filtered, node)
+		}
+	}
+	return filtered
+}
+
+// test returns whether node has a child with the predicate's key, and value
+// if it has one
+func (p *predicate) test(node *keyvalues.KeyValue) bool {
+	if !node.HasChildren() {
+		return false
+	}
+	children, _ := node.FindAll(p.key)
+	for _, child := range children {
+		if !p.hasValue {
+			return true
+		}
+		if value, err := child.Value(); err == nil && value == p.value {
+			return true
+		}
+	}
+	return false
+}
+
+// unique removes repeated nodes, keeping the first of each
+func unique(nodes []*keyvalues.KeyValue) []*keyvalues.KeyValue {
+	seen := map[*keyvalues.KeyValue]bool{}
+	res := nodes[:0]
+	for _, node := range nodes {
+		if !seen[node] {
+			seen[node] = true
+			res = append(res, node)
+		}
+	}
+	return res
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/galaco/KeyValues"
+)
+
+const sample = `"GameInfo"
+{
+	"game" "Half-Life 2"
+	"FileSystem"
+	{
+		"SteamAppId" "220"
+		"SearchPaths"
+		{
+			"Game" "|gameinfo_path|."
+			"Game" "hl2"
+			"Platform" "platform"
+		}
+	}
+}
+"world"
+{
+	"solid"
+	{
+		"id" "1"
+	}
+	"solid"
+	{
+		"id" "2"
+		"side"
+		{
+			"material" "TOOLS/NODRAW"
+		}
+	}
+}
+`
+
+func readSample(t *testing.T) []*keyvalues.KeyValue {
+	reader := keyvalues.NewReader(bytes.NewBufferString(sample))
+	tree, err := reader.Read()
+	if err != nil {
+		t.Fatal(err)
+	}
+	tops, err := tree.Children()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tops
+}
+
+func TestQuery(t *testing.T) {
+	tops := readSample(t)
+
+	samples := map[string][]string{
+		"GameInfo/FileSystem/SteamAppId":        {"220"},
+		"/gameinfo/filesystem/searchpaths/game": {"|gameinfo_path|.", "hl2"},
+		"GameInfo/FileSystem/SearchPaths/*[1]":  {"hl2"},
+		"GameInfo/*/SearchPaths/P?atform":       {"platform"},
+		"**/material":                           {"TOOLS/NODRAW"},
+		"world/solid[id=2]/side/material":       {"TOOLS/NODRAW"},
+		"world/solid[id!=2]/id":                 {"1"},
+		"world/solid[side]/id":                  {"2"},
+		"world/solid[5]/id":                     nil,
+		"\"GameInfo\"/game":                     {"Half-Life 2"},
+		"**/**/SteamAppId":                      {"220"},
+	}
+	for expr, expected := range samples {
+		q, err := compile(expr)
+		if err != nil {
+			t.Errorf("%s: %s", expr, err)
+			continue
+		}
+		matches := q.eval(tops)
+		if len(matches) != len(expected) {
+			t.Errorf("%s: expected %d matches, got %d", expr, len(expected), len(matches))
+			continue
+		}
+		for idx, match := range matches {
+			if value, _ := match.Value(); value != expected[idx] {
+				t.Errorf("%s: expected %s, got %s", expr, expected[idx], value)
+			}
+		}
+	}
+}
+
+func TestQuery_Blocks(t *testing.T) {
+	q, err := compile("**/solid")
+	if err != nil {
+		t.Fatal(err)
+	}
+	matches := q.eval(readSample(t))
+	if len(matches) != 2 {
+		t.Fatalf("expected 2 matches, got %d", len(matches))
+	}
+	if !matches[0].HasChildren() || matches[0].Key() != "solid" {
+		t.Error("expected a solid block")
+	}
+}
+
+func TestCompile_Errors(t *testing.T) {
+	for _, expr := range []string{"", "a//b", "a[", "a[]", "a[0", "a[-1]", "a[b=c]d", "**[0]", "\"a"} {
+		if _, err := compile(expr); err == nil {
+			t.Errorf("expected error for %q", expr)
+		}
+	}
+}
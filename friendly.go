@@ -0,0 +1,168 @@
+package keyvalues
+
+import (
+	"errors"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// toFriendly converts the value of node into friendly form.
+// Friendly form is the tree walk shared by the JSON, map, YAML and TOML
+// converters: a block is an OrderedMap, the values of a key that appears
+// more than once are collected into a []interface{}, and leaves are whatever
+// the converter's leaf function returns, which is never a slice.
+// Keys are matched case-insensitively, as Find matches them, and a block
+// keeps the first spelling of each.
+func toFriendly(node *KeyValue, leaf func(node *KeyValue) interface{}) interface{} {
+	if !node.HasChildren() {
+		return leaf(node)
+	}
+
+	var fields OrderedMap
+	index := map[string]int{}
+	children, _ := node.Children()
+	for _, child := range children {
+		value := toFriendly(child, leaf)
+		folded := strings.ToLower(child.Key())
+		idx, ok := index[folded]
+		if !ok {
+			index[folded] = len(fields)
+			fields = append(fields, MapItem{Key: child.Key(), Value: value})
+			continue
+		}
+		if values, ok := fields[idx].Value.([]interface{}); ok {
+			fields[idx].Value = append(values, value)
+		} else {
+			fields[idx].Value = []interface{}{fields[idx].Value, value}
+		}
+	}
+	if fields == nil {
+		fields = OrderedMap{}
+	}
+	return fields
+}
+
+// toFriendlyRoot converts node into a friendly block. A `$root` node becomes
+// a block of its children; any other node becomes a block containing itself.
+func toFriendlyRoot(node *KeyValue, leaf func(node *KeyValue) interface{}) OrderedMap {
+	if node.Key() == RootNodeKey && node.HasChildren() {
+		return toFriendly(node, leaf).(OrderedMap)
+	}
+	root := NewKeyValueArray(RootNodeKey)
+	root.children = append(root.children, node)
+	return toFriendly(root, leaf).(OrderedMap)
+}
+
+// friendlyTypedLeaf returns the value of a leaf as an int64, uint64, float64
+// or string, according to its ValueType
+func friendlyTypedLeaf(node *KeyValue) interface{} {
+	value, _ := node.Value()
+	switch node.Type() {
+	case ValueInt, ValueInt64:
+		if i, err := strconv.ParseInt(value, 10, 64); err == nil {
+			return i
+		}
+	case ValuePtr, ValueUint64:
+		if u, err := strconv.ParseUint(value, 10, 64); err == nil {
+			return u
+		}
+	case ValueFloat:
+		if f, err := strconv.ParseFloat(value, 64); err == nil {
+			return f
+		}
+	}
+	return value
+}
+
+// fromFriendly converts a friendly value back into KeyValues with key.
+// A slice becomes one KeyValue per item, all with the same key; blocks
+// become KeyValues with children. Integers take the smallest integer type
+// that holds them, booleans become 1 or 0 and nil becomes an empty string.
+func fromFriendly(key string, value interface{}) ([]*KeyValue, error) {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		return fromFriendly(key, sortedMap(v))
+	case OrderedMap:
+		node := NewKeyValueArray(key)
+		for _, field := range v {
+			children, err := fromFriendly(field.Key, field.Value)
+			if err != nil {
+				return nil, err
+			}
+			for _, child := range children {
+				node.AddChild(child)
+			}
+		}
+		return []*KeyValue{node}, nil
+	case []interface{}:
+		var nodes []*KeyValue
+		for _, item := range v {
+			if _, ok := item.([]interface{}); ok {
+				return nil, errors.New("nested arrays cannot be converted to keyvalues: " + key)
+			}
+			children, err := fromFriendly(key, item)
+			if err != nil {
+				return nil, err
+			}
+			nodes = append(nodes, children...)
+		}
+		return nodes, nil
+	case nil:
+		return []*KeyValue{NewKeyValuePair(key, "", ValueString)}, nil
+	case bool:
+		if v {
+			return []*KeyValue{NewKeyValuePair(key, "1", ValueInt)}, nil
+		}
+		return []*KeyValue{NewKeyValuePair(key, "0", ValueInt)}, nil
+	case string:
+		return []*KeyValue{NewKeyValuePair(key, v, ValueString)}, nil
+	case int:
+		return fromFriendly(key, int64(v))
+	case uint32:
+		return fromFriendly(key, int64(v))
+	case int32:
+		return []*KeyValue{NewKeyValuePair(key, strconv.FormatInt(int64(v), 10), ValueInt)}, nil
+	case int64:
+		if v < math.MinInt32 || v > math.MaxInt32 {
+			return []*KeyValue{NewKeyValuePair(key, strconv.FormatInt(v, 10), ValueInt64)}, nil
+		}
+		return []*KeyValue{NewKeyValuePair(key, strconv.FormatInt(v, 10), ValueInt)}, nil
+	case uint64:
+		if v <= math.MaxInt64 {
+			return fromFriendly(key, int64(v))
+		}
+		return []*KeyValue{NewKeyValuePair(key, strconv.FormatUint(v, 10), ValueUint64)}, nil
+	case float32:
+		return []*KeyValue{NewKeyValuePair(key, formatFriendlyFloat(float64(v), 32), ValueFloat)}, nil
+	case float64:
+		return []*KeyValue{NewKeyValuePair(key, formatFriendlyFloat(v, 64), ValueFloat)}, nil
+	}
+	return nil, errors.New("unsupported value for key " + key)
+}
+
+// fromFriendlyRoot converts a friendly block back into a KeyValue tree.
+// As with Reader, a block with more than one key is contained in a `$root`
+// node.
+func fromFriendlyRoot(fields OrderedMap) (*KeyValue, error) {
+	nodes, err := fromFriendly(RootNodeKey, fields)
+	if err != nil {
+		return nil, err
+	}
+	root := nodes[0]
+	if len(root.children) == 1 {
+		child := root.children[0]
+		child.parent = nil
+		return child, nil
+	}
+	return root, nil
+}
+
+// formatFriendlyFloat formats f so that it is read back as a float
+func formatFriendlyFloat(f float64, bitSize int) string {
+	value := strconv.FormatFloat(f, 'f', -1, bitSize)
+	if !math.IsInf(f, 0) && !math.IsNaN(f) && !strings.Contains(value, ".") {
+		value += ".0"
+	}
+	return value
+}
@@ -0,0 +1,190 @@
+package gameinfo
+
+import (
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+const pathWildcard = "*"
+const extensionVpk = ".vpk"
+
+// FileSystem is a read-only fs.FS that overlays the directories of a
+// gameinfo's search paths, searching them in the same order the engine does.
+// Names are resolved without case sensitivity, as they are on Windows.
+//
+// Only directories are searched; .vpk search paths are skipped. A path
+// ending in /* (such as custom/*) searches every directory inside it, in
+// alphabetical order, like the engine does. Wildcards are expanded when the
+// FileSystem is created.
+type FileSystem struct {
+	paths []SearchPath
+}
+
+// NewFileSystem creates a FileSystem from every search path of info.
+// root and gameInfoDir are used to expand search paths, as in SearchPath.Expand.
+func NewFileSystem(info *GameInfo, root string, gameInfoDir string) *FileSystem {
+	fsys := &FileSystem{}
+	for _, searchPath := range info.ExpandSearchPaths(root, gameInfoDir) {
+		if strings.EqualFold(filepath.Ext(searchPath.Path), extensionVpk) {
+			continue
+		}
+		if filepath.Base(searchPath.Path) != pathWildcard {
+			fsys.paths = append(fsys.paths, searchPath)
+			continue
+		}
+		parent, ok := resolve(filepath.Dir(searchPath.Path), ".")
+		if !ok {
+			continue
+		}
+		entries, err := os.ReadDir(parent)
+		if err != nil {
+			continue
+		}
+		for _, entry := range entries {
+			if entry.IsDir() {
+				fsys.paths = append(fsys.paths, SearchPath{
+					IDs:  searchPath.IDs,
+					Path: filepath.Join(parent, entry.Name()),
+				})
+			}
+		}
+	}
+	return fsys
+}
+
+// WithPathID returns a view of this FileSystem that only searches paths with
+// at least one of the given path IDs, e.g. "game" or "mod"
+func (fsys *FileSystem) WithPathID(ids ...string) *FileSystem {
+	view := &FileSystem{}
+	for _, searchPath := range fsys.paths {
+		for _, id := range ids {
+			if searchPath.HasID(id) {
+				view.paths = append(view.paths, searchPath)
+				break
+			}
+		}
+	}
+	return view
+}
+
+// SearchPaths returns the directories this FileSystem searches, in order
+func (fsys *FileSystem) SearchPaths() []SearchPath {
+	return fsys.paths
+}
+
+// Open opens the first file matching name in search path order
+func (fsys *FileSystem) Open(name string) (fs.File, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrInvalid}
+	}
+	for _, searchPath := range fsys.paths {
+		if resolved, ok := resolve(searchPath.Path, name); ok {
+			return os.Open(resolved)
+		}
+	}
+	return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+}
+
+// Stat returns the FileInfo of the first file matching name in search path order
+func (fsys *FileSystem) Stat(name string) (fs.FileInfo, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "stat", Path: name, Err: fs.ErrInvalid}
+	}
+	for _, searchPath := range fsys.paths {
+		if resolved, ok := resolve(searchPath.Path, name); ok {
+			return os.Stat(resolved)
+		}
+	}
+	return nil, &fs.PathError{Op: "stat", Path: name, Err: fs.ErrNotExist}
+}
+
+// Locate returns the path on disk of the first file matching name in search
+// path order
+func (fsys *FileSystem) Locate(name string) (string, error) {
+	if !fs.ValidPath(name) {
+		return "", &fs.PathError{Op: "locate", Path: name, Err: fs.ErrInvalid}
+	}
+	for _, searchPath := range fsys.paths {
+		if resolved, ok := resolve(searchPath.Path, name); ok {
+			return resolved, nil
+		}
+	}
+	return "", &fs.PathError{Op: "locate", Path: name, Err: fs.ErrNotExist}
+}
+
+// ReadDir lists a directory merged across every search path.
+// Where more than one search path has an entry of the same name, ignoring
+// case, the entry from the first search path is returned.
+func (fsys *FileSystem) ReadDir(name string) ([]fs.DirEntry, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: fs.ErrInvalid}
+	}
+	found := false
+	seen := map[string]bool{}
+	var merged []fs.DirEntry
+	for _, searchPath := range fsys.paths {
+		resolved, ok := resolve(searchPath.Path, name)
+		if !ok {
+			continue
+		}
+		entries, err := os.ReadDir(resolved)
+		if err != nil {
+			continue
+		}
+		found = true
+		for _, entry := range entries {
+			key := strings.ToLower(entry.Name())
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+			merged = append(merged, entry)
+		}
+	}
+	if !found {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: fs.ErrNotExist}
+	}
+	sort.Slice(merged, func(i, j int) bool {
+		return merged[i].Name() < merged[j].Name()
+	})
+	return merged, nil
+}
+
+// resolve finds name inside dir on disk, matching each element of name
+// without case sensitivity when there is no exact match
+func resolve(dir string, name string) (string, bool) {
+	if _, err := os.Stat(dir); err != nil {
+		return "", false
+	}
+	current := dir
+	if name == "." {
+		return current, true
+	}
+	for _, element := range strings.Split(path.Clean(name), "/") {
+		next := filepath.Join(current, element)
+		if _, err := os.Lstat(next); err == nil {
+			current = next
+			continue
+		}
+		entries, err := os.ReadDir(current)
+		if err != nil {
+			return "", false
+		}
+		matched := false
+		for _, entry := range entries {
+			if strings.EqualFold(entry.Name(), element) {
+				current = filepath.Join(current, entry.Name())
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return "", false
+		}
+	}
+	return current, true
+}
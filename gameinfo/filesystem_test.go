@@ -0,0 +1,106 @@
+package gameinfo
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const sampleFileSystemGameInfo = `"GameInfo"
+{
+	game	"Counter-Strike Source"
+	FileSystem
+	{
+		SteamAppId	240
+		SearchPaths
+		{
+			game+mod	|gameinfo_path|custom/*
+			game+mod	cstrike/cstrike_pak.vpk
+			game+mod	|gameinfo_path|.
+			game		|all_source_engine_paths|hl2
+		}
+	}
+}
+`
+
+func writeFiles(t *testing.T, root string, files map[string]string) {
+	for name, data := range files {
+		name = filepath.Join(root, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(name), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(name, []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func newTestFileSystem(t *testing.T) *FileSystem {
+	root := t.TempDir()
+	writeFiles(t, root, map[string]string{
+		"cstrike/custom/pack/materials/Foo/Custom.vmt": "custom",
+		"cstrike/Materials/Foo/Bar.vmt":                "cstrike",
+		"hl2/materials/foo/bar.vmt":                    "hl2",
+		"hl2/materials/foo/other.vmt":                  "hl2",
+	})
+	info, err := Read(strings.NewReader(sampleFileSystemGameInfo))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return NewFileSystem(info, root, "cstrike")
+}
+
+func TestFileSystem_Open(t *testing.T) {
+	fsys := newTestFileSystem(t)
+	if len(fsys.SearchPaths()) != 3 {
+		t.Fatalf("unexpected search paths: %v", fsys.SearchPaths())
+	}
+
+	for name, expected := range map[string]string{
+		"materials/foo/bar.vmt":    "cstrike",
+		"MATERIALS/FOO/OTHER.VMT":  "hl2",
+		"materials/foo/custom.vmt": "custom",
+	} {
+		data, err := fs.ReadFile(fsys, name)
+		if err != nil {
+			t.Error(err)
+			continue
+		}
+		if string(data) != expected {
+			t.Errorf("unexpected file for %s. expected %s, received: %s", name, expected, data)
+		}
+	}
+
+	if _, err := fsys.Open("materials/foo/missing.vmt"); err == nil {
+		t.Error("expected error, but received none")
+	}
+	if _, err := fsys.Open("../cstrike/materials/foo/bar.vmt"); err == nil {
+		t.Error("expected error, but received none")
+	}
+}
+
+func TestFileSystem_WithPathID(t *testing.T) {
+	fsys := newTestFileSystem(t).WithPathID("mod")
+	if _, err := fsys.Open("materials/foo/other.vmt"); err == nil {
+		t.Error("mod view found file only in a game path")
+	}
+	if _, err := fsys.Stat("materials/foo/bar.vmt"); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestFileSystem_ReadDir(t *testing.T) {
+	entries, err := fs.ReadDir(newTestFileSystem(t), "materials/foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, entry := range entries {
+		names = append(names, entry.Name())
+	}
+	if strings.Join(names, ",") != "Bar.vmt,Custom.vmt,other.vmt" {
+		t.Errorf("unexpected directory entries: %v", names)
+	}
+}
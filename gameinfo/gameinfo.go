@@ -0,0 +1,248 @@
+package gameinfo
+
+import (
+	"errors"
+	"io"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/galaco/KeyValues"
+)
+
+const keyGameInfo = "gameinfo"
+const keyFileSystem = "filesystem"
+const keySearchPaths = "searchpaths"
+
+// TokenGameInfoPath expands to the directory that contains gameinfo.txt
+const TokenGameInfoPath = "|gameinfo_path|"
+
+// TokenAllSourceEnginePaths expands to the root directory of the game
+// installation, that holds every game directory
+const TokenAllSourceEnginePaths = "|all_source_engine_paths|"
+
+const pathIDSeparator = "+"
+
+// GameInfo is a typed model of a gameinfo.txt
+// Keys that aren't part of the model are kept in Unknown.
+type GameInfo struct {
+	Game        string
+	Title       string
+	Title2      string
+	Type        string
+	Developer   string
+	Icon        string
+	NoModels    bool
+	NoHIModel   bool
+	NoCrosshair bool
+	HasPortals  bool
+	SteamAppID  int
+	ToolsAppID  int
+	SearchPaths []SearchPath
+	Unknown     []*keyvalues.KeyValue
+	// FileSystemUnknown holds keys of the FileSystem block that aren't part of
+	// the model
+	FileSystemUnknown []*keyvalues.KeyValue
+}
+
+// SearchPath is a single entry of the FileSystem SearchPaths block
+// The entry's key is a "+" separated list of the path IDs that can find files
+// in this path, e.g. "game+mod+vgui".
+type SearchPath struct {
+	IDs  []string
+	Path string
+}
+
+// Read parses a gameinfo.txt stream
+func Read(file io.Reader) (*GameInfo, error) {
+	reader := keyvalues.NewReader(file)
+	kv, err := reader.Read()
+	if err != nil {
+		return nil, err
+	}
+	return FromKeyValue(&kv)
+}
+
+// FromKeyValue builds a GameInfo from a parsed KeyValue tree.
+// Search paths are kept in the order they are written, which is the order
+// the engine searches them in.
+func FromKeyValue(kv *keyvalues.KeyValue) (*GameInfo, error) {
+	root := kv
+	if strings.ToLower(kv.Key()) != keyGameInfo {
+		found, err := kv.Find(keyGameInfo)
+		if err != nil {
+			return nil, errors.New("gameinfo key not found")
+		}
+		root = found
+	}
+	if !root.HasChildren() {
+		return nil, errors.New("gameinfo has no children")
+	}
+
+	info := &GameInfo{}
+	children, _ := root.Children()
+	for _, child := range children {
+		var err error
+		switch strings.ToLower(child.Key()) {
+		case "game":
+			info.Game, err = child.Value()
+		case "title":
+			info.Title, err = child.Value()
+		case "title2":
+			info.Title2, err = child.Value()
+		case "type":
+			info.Type, err = child.Value()
+		case "developer":
+			info.Developer, err = child.Value()
+		case "icon":
+			info.Icon, err = child.Value()
+		case "nomodels":
+			info.NoModels, err = parseBool(child)
+		case "nohimodel":
+			info.NoHIModel, err = parseBool(child)
+		case "nocrosshair":
+			info.NoCrosshair, err = parseBool(child)
+		case "hasportals":
+			info.HasPortals, err = parseBool(child)
+		case keyFileSystem:
+			err = info.readFileSystem(child)
+		default:
+			info.Unknown = append(info.Unknown, child)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return info, nil
+}
+
+func (info *GameInfo) readFileSystem(fileSystem *keyvalues.KeyValue) error {
+	children, err := fileSystem.Children()
+	if err != nil {
+		return err
+	}
+	for _, child := range children {
+		switch strings.ToLower(child.Key()) {
+		case "steamappid":
+			info.SteamAppID, err = parseInt(child)
+		case "toolsappid":
+			info.ToolsAppID, err = parseInt(child)
+		case keySearchPaths:
+			err = info.readSearchPaths(child)
+		default:
+			info.FileSystemUnknown = append(info.FileSystemUnknown, child)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (info *GameInfo) readSearchPaths(searchPaths *keyvalues.KeyValue) error {
+	children, err := searchPaths.Children()
+	if err != nil {
+		return err
+	}
+	for _, child := range children {
+		value, err := child.Value()
+		if err != nil {
+			return errors.New("search path " + child.Key() + " has no value")
+		}
+		info.SearchPaths = append(info.SearchPaths, SearchPath{
+			IDs:  splitPathIDs(child.Key()),
+			Path: value,
+		})
+	}
+	return nil
+}
+
+// SearchPathsFor returns every search path that has the given path ID.
+// Path IDs are not case sensitive.
+func (info *GameInfo) SearchPathsFor(id string) (paths []SearchPath) {
+	for _, path := range info.SearchPaths {
+		if path.HasID(id) {
+			paths = append(paths, path)
+		}
+	}
+	return paths
+}
+
+// ExpandSearchPaths returns every search path with its path expanded.
+// See SearchPath.Expand.
+func (info *GameInfo) ExpandSearchPaths(root string, gameInfoDir string) []SearchPath {
+	paths := make([]SearchPath, len(info.SearchPaths))
+	for idx, path := range info.SearchPaths {
+		paths[idx] = SearchPath{
+			IDs:  path.IDs,
+			Path: path.Expand(root, gameInfoDir),
+		}
+	}
+	return paths
+}
+
+// HasID returns whether this path can be searched with the given path ID
+func (path *SearchPath) HasID(id string) bool {
+	id = strings.ToLower(id)
+	for _, pathID := range path.IDs {
+		if pathID == id {
+			return true
+		}
+	}
+	return false
+}
+
+// Expand returns this search path as a path on disk.
+// root is the directory that the game is installed in, that holds every
+// game directory (e.g. "steamapps/common/Half-Life 2"). gameInfoDir is the
+// directory that contains gameinfo.txt; if it is relative, it is relative to
+// root. Paths without a token are relative to root, as they are in the engine.
+func (path *SearchPath) Expand(root string, gameInfoDir string) string {
+	if !filepath.IsAbs(gameInfoDir) {
+		gameInfoDir = filepath.Join(root, gameInfoDir)
+	}
+
+	value := strings.Replace(path.Path, "\\", "/", -1)
+	lower := strings.ToLower(value)
+	switch {
+	case strings.HasPrefix(lower, TokenGameInfoPath):
+		value = filepath.Join(gameInfoDir, filepath.FromSlash(value[len(TokenGameInfoPath):]))
+	case strings.HasPrefix(lower, TokenAllSourceEnginePaths):
+		value = filepath.Join(root, filepath.FromSlash(value[len(TokenAllSourceEnginePaths):]))
+	case filepath.IsAbs(filepath.FromSlash(value)):
+		value = filepath.Clean(filepath.FromSlash(value))
+	default:
+		value = filepath.Join(root, filepath.FromSlash(value))
+	}
+	return value
+}
+
+// splitPathIDs splits a search path key like "game+mod+vgui" into its
+// lowercase path IDs
+func splitPathIDs(key string) (ids []string) {
+	for _, id := range strings.Split(key, pathIDSeparator) {
+		id = strings.ToLower(strings.TrimSpace(id))
+		if len(id) > 0 {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+func parseInt(node *keyvalues.KeyValue) (int, error) {
+	value, err := node.Value()
+	if err != nil {
+		return 0, err
+	}
+	i, err := strconv.Atoi(strings.TrimSpace(value))
+	if err != nil {
+		return 0, errors.New("invalid value for key " + node.Key() + ": " + value)
+	}
+	return i, nil
+}
+
+func parseBool(node *keyvalues.KeyValue) (bool, error) {
+	i, err := parseInt(node)
+	return i != 0, err
+}
@@ -0,0 +1,87 @@
+package gameinfo
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const sampleGameInfo = `"GameInfo"
+{
+	game	"Counter-Strike Source"
+	title	"COUNTER-STRIKE'"
+	type	multiplayer_only
+	nomodels 1
+	nohimodel 1
+	nocrosshair 0
+	"SupportsDX8"	"1"
+
+	FileSystem
+	{
+		SteamAppId				240
+		ToolsAppId				211
+
+		SearchPaths
+		{
+			game+mod			cstrike/cstrike_pak.vpk
+			game				|all_source_engine_paths|hl2/hl2_textures.vpk
+			game+mod+mod_write+default_write_path		|gameinfo_path|.
+			gamebin				|gameinfo_path|bin
+			game				|all_source_engine_paths|hl2
+			platform			|all_source_engine_paths|platform
+		}
+	}
+}
+`
+
+func TestRead(t *testing.T) {
+	info, err := Read(strings.NewReader(sampleGameInfo))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Game != "Counter-Strike Source" || info.Type != "multiplayer_only" {
+		t.Errorf("unexpected game info: %+v", info)
+	}
+	if !info.NoModels || !info.NoHIModel || info.NoCrosshair {
+		t.Error("unexpected flags")
+	}
+	if info.SteamAppID != 240 || info.ToolsAppID != 211 {
+		t.Errorf("unexpected app ids: %d %d", info.SteamAppID, info.ToolsAppID)
+	}
+	if len(info.Unknown) != 1 || info.Unknown[0].Key() != "SupportsDX8" {
+		t.Error("unknown key was not kept")
+	}
+
+	if len(info.SearchPaths) != 6 {
+		t.Fatalf("unexpected number of search paths: %d", len(info.SearchPaths))
+	}
+	ids := info.SearchPaths[2].IDs
+	if strings.Join(ids, ",") != "game,mod,mod_write,default_write_path" {
+		t.Errorf("unexpected path ids: %v", ids)
+	}
+	if len(info.SearchPathsFor("GAME")) != 4 || len(info.SearchPathsFor("mod")) != 2 {
+		t.Error("unexpected search paths for path id")
+	}
+}
+
+func TestSearchPath_Expand(t *testing.T) {
+	info, err := Read(strings.NewReader(sampleGameInfo))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	root := filepath.FromSlash("/games/css")
+	expected := []string{
+		"/games/css/cstrike/cstrike_pak.vpk",
+		"/games/css/hl2/hl2_textures.vpk",
+		"/games/css/cstrike",
+		"/games/css/cstrike/bin",
+		"/games/css/hl2",
+		"/games/css/platform",
+	}
+	for idx, path := range info.ExpandSearchPaths(root, "cstrike") {
+		if path.Path != filepath.FromSlash(expected[idx]) {
+			t.Errorf("unexpected expanded path. expected %s, received: %s", expected[idx], path.Path)
+		}
+	}
+}
@@ -0,0 +1,103 @@
+package keyvalues
+
+import "strings"
+
+// indexThreshold is the number of children a block needs before its
+// children are looked up by index rather than by scanning them
+const indexThreshold = 16
+
+// childIndex holds a block's children by their lowercased keys.
+// Keys are only folded correctly for ASCII, so blocks with other keys have
+// no map, and are scanned instead.
+type childIndex struct {
+	keys map[string][]*KeyValue
+}
+
+func newChildIndex(children []*KeyValue) *childIndex {
+	index := &childIndex{
+		keys: make(map[string][]*KeyValue, len(children)),
+	}
+	for _, child := range children {
+		index.add(child)
+	}
+	return index
+}
+
+// find returns the indexed children with key, which must be ASCII.
+// The returned slice must not be modified.
+func (index *childIndex) find(key string) []*KeyValue {
+	var buf [64]byte
+	if len(key) > len(buf) {
+		return index.keys[strings.ToLower(key)]
+	}
+	folded := buf[:len(key)]
+	for idx := 0; idx < len(key); idx++ {
+		folded[idx] = lowerASCII(key[idx])
+	}
+	return index.keys[string(folded)]
+}
+
+func (index *childIndex) add(child *KeyValue) {
+	if index.keys == nil {
+		return
+	}
+	if !isASCII(child.key) {
+		index.keys = nil
+		return
+	}
+	folded := strings.ToLower(child.key)
+	index.keys[folded] = append(index.keys[folded], child)
+}
+
+func (index *childIndex) remove(child *KeyValue) {
+	if index.keys == nil {
+		return
+	}
+	folded := strings.ToLower(child.key)
+	children := index.keys[folded]
+	for idx, c := range children {
+		if c == child {
+			children = append(children[:idx], children[idx+1:]...)
+			break
+		}
+	}
+	if len(children) == 0 {
+		delete(index.keys, folded)
+		return
+	}
+	index.keys[folded] = children
+}
+
+// reindex rebuilds the index of node's children from scratch, for changes
+// that AddChild and RemoveChild can't follow. Small blocks have no index.
+func (node *KeyValue) reindex() {
+	node.index = nil
+	if len(node.children) >= indexThreshold {
+		node.index = newChildIndex(node.children)
+	}
+}
+
+// indexed returns the index of node's children, or nil for small blocks and
+// blocks that can only be scanned
+func (node *KeyValue) indexed() *childIndex {
+	if node.index == nil || node.index.keys == nil {
+		return nil
+	}
+	return node.index
+}
+
+func isASCII(s string) bool {
+	for idx := 0; idx < len(s); idx++ {
+		if s[idx] >= 0x80 {
+			return false
+		}
+	}
+	return true
+}
+
+func lowerASCII(c byte) byte {
+	if 'A' <= c && c <= 'Z' {
+		return c + 'a' - 'A'
+	}
+	return c
+}
@@ -0,0 +1,200 @@
+package keyvalues
+
+import (
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// largeBlock returns a block with enough children to be indexed
+func largeBlock() *KeyValue {
+	block := NewKeyValueArray("world")
+	for idx := 0; idx < indexThreshold*2; idx++ {
+		block.AddChild(NewKeyValue("solid", strconv.Itoa(idx)))
+	}
+	block.AddChild(NewKeyValue("SkyName", "sky_day01_01"))
+	return block
+}
+
+func TestKeyValue_FindIndexed(t *testing.T) {
+	block := largeBlock()
+
+	sky, err := block.Find("skyname")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if value, _ := sky.Value(); value != "sky_day01_01" {
+		t.Errorf("unexpected value: %s", value)
+	}
+	if block.indexed() == nil {
+		t.Error("expected AddChild to index the block")
+	}
+
+	solids, err := block.FindAll("SOLID")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(solids) != indexThreshold*2 {
+		t.Fatalf("expected %d solids, got %d", indexThreshold*2, len(solids))
+	}
+	for idx, solid := range solids {
+		if value, _ := solid.Value(); value != strconv.Itoa(idx) {
+			t.Errorf("expected solids in order, got %s at %d", value, idx)
+		}
+	}
+
+	if _, err = block.Find("missing"); err == nil {
+		t.Error("expected error, but received none")
+	}
+}
+
+func TestKeyValue_FindIndexedAfterAddChild(t *testing.T) {
+	block := largeBlock()
+
+	block.AddChild(NewKeyValue("entity", "1"))
+	if _, err := block.Find("Entity"); err != nil {
+		t.Error(err)
+	}
+	solids, _ := block.FindAll("solid")
+	block.AddChild(NewKeyValue("solid", "last"))
+	if more, _ := block.FindAll("solid"); len(more) != len(solids)+1 {
+		t.Errorf("expected %d solids, got %d", len(solids)+1, len(more))
+	}
+}
+
+func TestKeyValue_FindIndexedAfterRemoveChild(t *testing.T) {
+	block := largeBlock()
+
+	if err := block.RemoveChild("skyname"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := block.Find("skyname"); err == nil {
+		t.Error("expected removed key not to be found")
+	}
+	if err := block.RemoveChild("solid"); err != nil {
+		t.Fatal(err)
+	}
+	solid, _ := block.Find("solid")
+	if value, _ := solid.Value(); value != "1" {
+		t.Errorf("expected the first solid to be removed, got %s", value)
+	}
+}
+
+func TestKeyValue_FindIndexedAfterRename(t *testing.T) {
+	block := largeBlock()
+
+	sky, _ := block.Find("skyname")
+	sky.setKey("detailmaterial")
+	if _, err := block.Find("detailmaterial"); err != nil {
+		t.Error(err)
+	}
+	if _, err := block.Find("skyname"); err == nil {
+		t.Error("expected renamed key not to be found")
+	}
+}
+
+func TestKeyValue_FindIndexedAfterPatch(t *testing.T) {
+	block := largeBlock()
+	patch := NewKeyValueArray("patch", NewKeyValue("entity", "1"))
+
+	merged, err := patch.Patch(block)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if merged.indexed() == nil {
+		t.Fatal("expected the merged block to be indexed")
+	}
+	if _, err := merged.Find("entity"); err != nil {
+		t.Error(err)
+	}
+	if _, err := merged.Find("skyname"); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestKeyValue_FindNotIndexed(t *testing.T) {
+	block := NewKeyValueArray("world")
+	for idx := 0; idx < indexThreshold-1; idx++ {
+		block.AddChild(NewKeyValue("solid", strconv.Itoa(idx)))
+	}
+	if block.index != nil {
+		t.Error("expected a small block not to be indexed")
+	}
+	if _, err := block.Find("SOLID"); err != nil {
+		t.Error(err)
+	}
+
+	block.AddChild(NewKeyValue("skyname", "sky_day01_01"))
+	if block.indexed() == nil {
+		t.Fatal("expected the block to be indexed once it is large enough")
+	}
+	if solids, _ := block.FindAll("solid"); len(solids) != indexThreshold-1 {
+		t.Errorf("expected %d solids, got %d", indexThreshold-1, len(solids))
+	}
+}
+
+func TestReader_ReadIndexed(t *testing.T) {
+	var buf strings.Builder
+	buf.WriteString("world\n{\n")
+	for idx := 0; idx < indexThreshold*2; idx++ {
+		buf.WriteString("\tsolid\n\t{\n\t\t\"id\" \"" + strconv.Itoa(idx) + "\"\n\t}\n")
+	}
+	buf.WriteString("\t\"skyname\" \"sky_day01_01\"\n}\nentity\n{\n}\n")
+
+	reader := NewReader(strings.NewReader(buf.String()))
+	root, err := reader.Read()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if root.indexed() != nil {
+		t.Error("expected a small block not to be indexed")
+	}
+	world, _ := root.Find("world")
+	if world.indexed() == nil {
+		t.Fatal("expected the Reader to index a large block")
+	}
+	if sky, _ := world.Find("SkyName"); sky == nil {
+		t.Error("missing skyname")
+	}
+}
+
+// TestKeyValue_FindConcurrent looks up keys from several goroutines at once,
+// which go test -race checks for writes to the tree
+func TestKeyValue_FindConcurrent(t *testing.T) {
+	indexed := largeBlock()
+	scanned := largeBlock()
+	scanned.AddChild(NewKeyValue("Ärger", "1"))
+
+	var wg sync.WaitGroup
+	for idx := 0; idx < 8; idx++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for _, block := range []*KeyValue{indexed, scanned} {
+				if _, err := block.Find("solid"); err != nil {
+					t.Error(err)
+				}
+				if solids, _ := block.FindAll("SOLID"); len(solids) != indexThreshold*2 {
+					t.Errorf("expected %d solids, got %d", indexThreshold*2, len(solids))
+				}
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func TestKeyValue_FindNonASCII(t *testing.T) {
+	block := largeBlock()
+	block.AddChild(NewKeyValue("Ärger", "1"))
+
+	if _, err := block.Find("äRGER"); err != nil {
+		t.Error(err)
+	}
+	if _, err := block.Find("skyname"); err != nil {
+		t.Error(err)
+	}
+	if block.indexed() != nil {
+		t.Error("expected a block with non-ASCII keys to be scanned")
+	}
+}
@@ -0,0 +1,214 @@
+package keyvalues
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"math"
+	"strconv"
+)
+
+// JSONMode selects how a KeyValue tree is represented in JSON
+type JSONMode int
+
+// JSONFriendly represents blocks as JSON objects, with the values of
+// duplicate keys collapsed into an array, and numbers typed according to
+// their ValueType. It is easy to consume, but doesn't keep every detail of a
+// tree: the order of duplicate keys relative to other keys, and the exact
+// text and type of values, may change on a round trip.
+const JSONFriendly = JSONMode(0)
+
+// JSONLossless represents every KeyValue as an object with its key, type and
+// either its value or its children, so that a round trip reproduces the same
+// tree.
+const JSONLossless = JSONMode(1)
+
+// jsonNode is a KeyValue in JSONLossless mode
+type jsonNode struct {
+	Key      string     `json:"key"`
+	Type     ValueType  `json:"type"`
+	Value    string     `json:"value,omitempty"`
+	Children []jsonNode `json:"children,omitempty"`
+}
+
+// ToJSON converts the tree into JSON.
+// In JSONFriendly mode, the result is an object containing this KeyValue, or
+// the children of a `$root` node. In JSONLossless mode, the result is this
+// KeyValue itself.
+func (node *KeyValue) ToJSON(mode JSONMode) ([]byte, error) {
+	switch mode {
+	case JSONFriendly:
+		var buf bytes.Buffer
+		if err := writeFriendlyJSON(&buf, toFriendlyRoot(node, friendlyTypedLeaf)); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	case JSONLossless:
+		return json.Marshal(toJSONNode(node))
+	}
+	return nil, errors.New("unknown json mode: " + strconv.Itoa(int(mode)))
+}
+
+// FromJSON parses JSON written by ToJSON in the same mode.
+// In JSONFriendly mode, any JSON object is accepted: objects become KeyValues
+// with children, arrays become repeated keys, and numbers take the smallest
+// type that holds them. As with Reader, an object with more than one key is
+// contained in a `$root` node.
+func FromJSON(data []byte, mode JSONMode) (*KeyValue, error) {
+	switch mode {
+	case JSONFriendly:
+		decoder := json.NewDecoder(bytes.NewReader(data))
+		decoder.UseNumber()
+		value, err := readFriendlyJSON(decoder)
+		if err != nil {
+			return nil, err
+		}
+		if _, err = decoder.Token(); err != io.EOF {
+			return nil, errors.New("unexpected data after json object")
+		}
+		fields, ok := value.(OrderedMap)
+		if !ok {
+			return nil, errors.New("json root must be an object")
+		}
+		return fromFriendlyRoot(fields)
+	case JSONLossless:
+		var root jsonNode
+		if err := json.Unmarshal(data, &root); err != nil {
+			return nil, err
+		}
+		return fromJSONNode(&root)
+	}
+	return nil, errors.New("unknown json mode: " + strconv.Itoa(int(mode)))
+}
+
+func toJSONNode(node *KeyValue) jsonNode {
+	out := jsonNode{Key: node.Key(), Type: node.Type()}
+	if !node.HasChildren() {
+		out.Value, _ = node.Value()
+		return out
+	}
+	children, _ := node.Children()
+	for _, child := range children {
+		out.Children = append(out.Children, toJSONNode(child))
+	}
+	return out
+}
+
+func fromJSONNode(in *jsonNode) (*KeyValue, error) {
+	switch in.Type {
+	case ValueArray:
+		node := NewKeyValueArray(in.Key)
+		for idx := range in.Children {
+			child, err := fromJSONNode(&in.Children[idx])
+			if err != nil {
+				return nil, err
+			}
+			node.AddChild(child)
+		}
+		return node, nil
+	case ValueString, ValueInt, ValueFloat, ValuePtr, ValueWString, ValueColor, ValueUint64, ValueInt64:
+		if len(in.Children) > 0 {
+			return nil, errors.New("key " + in.Key + " of type " + string(in.Type) + " has children")
+		}
+		return NewKeyValuePair(in.Key, in.Value, in.Type), nil
+	}
+	return nil, errors.New("unknown type for key " + in.Key + ": " + string(in.Type))
+}
+
+func writeFriendlyJSON(buf *bytes.Buffer, value interface{}) error {
+	switch v := value.(type) {
+	case OrderedMap:
+		buf.WriteByte('{')
+		for idx, field := range v {
+			if idx > 0 {
+				buf.WriteByte(',')
+			}
+			key, _ := json.Marshal(field.Key)
+			buf.Write(key)
+			buf.WriteByte(':')
+			if err := writeFriendlyJSON(buf, field.Value); err != nil {
+				return err
+			}
+		}
+		buf.WriteByte('}')
+	case []interface{}:
+		buf.WriteByte('[')
+		for idx, item := range v {
+			if idx > 0 {
+				buf.WriteByte(',')
+			}
+			if err := writeFriendlyJSON(buf, item); err != nil {
+				return err
+			}
+		}
+		buf.WriteByte(']')
+	case float64:
+		if math.IsInf(v, 0) || math.IsNaN(v) {
+			return errors.New("json cannot represent " + strconv.FormatFloat(v, 'f', -1, 64))
+		}
+		buf.WriteString(formatFriendlyFloat(v, 64))
+	case int64:
+		buf.WriteString(strconv.FormatInt(v, 10))
+	case uint64:
+		buf.WriteString(strconv.FormatUint(v, 10))
+	default:
+		encoded, err := json.Marshal(v)
+		if err != nil {
+			return err
+		}
+		buf.Write(encoded)
+	}
+	return nil
+}
+
+// readFriendlyJSON decodes a single JSON value into friendly form, keeping
+// the order of object keys
+func readFriendlyJSON(decoder *json.Decoder) (interface{}, error) {
+	token, err := decoder.Token()
+	if err != nil {
+		return nil, err
+	}
+
+	switch t := token.(type) {
+	case json.Delim:
+		switch t {
+		case '{':
+			fields := OrderedMap{}
+			for decoder.More() {
+				key, err := decoder.Token()
+				if err != nil {
+					return nil, err
+				}
+				value, err := readFriendlyJSON(decoder)
+				if err != nil {
+					return nil, err
+				}
+				fields = append(fields, MapItem{Key: key.(string), Value: value})
+			}
+			_, err = decoder.Token()
+			return fields, err
+		case '[':
+			items := []interface{}{}
+			for decoder.More() {
+				item, err := readFriendlyJSON(decoder)
+				if err != nil {
+					return nil, err
+				}
+				items = append(items, item)
+			}
+			_, err = decoder.Token()
+			return items, err
+		}
+		return nil, errors.New("unexpected json delimiter: " + t.String())
+	case json.Number:
+		if i, err := t.Int64(); err == nil {
+			return i, nil
+		}
+		if u, err := strconv.ParseUint(t.String(), 10, 64); err == nil {
+			return u, nil
+		}
+		return t.Float64()
+	}
+	return token, nil
+}
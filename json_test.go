@@ -0,0 +1,125 @@
+package keyvalues
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+const sampleJSONKeyValues = `"root"
+{
+	"name"	"test"
+	"count"	"3"
+	"scale"	"0.5"
+	"solid"	"1"
+	"child"
+	{
+		"id"	"007"
+	}
+	"solid"	"2"
+}
+`
+
+func readJSONSample(t *testing.T) *KeyValue {
+	reader := NewReader(strings.NewReader(sampleJSONKeyValues))
+	kv, err := reader.Read()
+	if err != nil {
+		t.Fatal(err)
+	}
+	kv.AddChild(NewKeyValuePair("steamid", "76561197960287930", ValueUint64))
+	return &kv
+}
+
+func TestKeyValue_ToJSON_Friendly(t *testing.T) {
+	kv := readJSONSample(t)
+	data, err := kv.ToJSON(JSONFriendly)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := `{"root":{"name":"test","count":3,"scale":0.5,"solid":[1,2],"child":{"id":7},"steamid":76561197960287930}}`
+	if string(data) != expected {
+		t.Errorf("unexpected json: %s", data)
+	}
+}
+
+func TestKeyValue_ToJSON_FriendlyCaseInsensitive(t *testing.T) {
+	kv := NewKeyValueArray("root",
+		NewKeyValue("Solid", "1"),
+		NewKeyValue("solid", "2"),
+		NewKeyValue("SOLID", "3"))
+	data, err := kv.ToJSON(JSONFriendly)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := `{"root":{"Solid":[1,2,3]}}`
+	if string(data) != expected {
+		t.Errorf("unexpected json: %s", data)
+	}
+}
+
+func TestFromJSON_Friendly(t *testing.T) {
+	data := []byte(`{"root":{"name":"test","count":3,"scale":1.0,"solid":[1,2],"child":{"enabled":true}},"other":{}}`)
+	kv, err := FromJSON(data, JSONFriendly)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if kv.Key() != RootNodeKey {
+		t.Fatalf("expected $root node, got %s", kv.Key())
+	}
+	root, _ := kv.Find("root")
+	if count, _ := root.Find("count"); count.Type() != ValueInt {
+		t.Errorf("unexpected count type: %s", count.Type())
+	}
+	if scale, _ := root.Find("scale"); scale.Type() != ValueFloat {
+		t.Errorf("unexpected scale type: %s", scale.Type())
+	}
+	if solids, _ := root.FindAll("solid"); len(solids) != 2 {
+		t.Errorf("unexpected number of solids: %d", len(solids))
+	}
+	child, _ := root.Find("child")
+	if enabled, _ := child.Find("enabled"); enabled == nil {
+		t.Error("missing enabled key")
+	} else if value, _ := enabled.Value(); value != "1" {
+		t.Errorf("unexpected bool value: %s", value)
+	}
+
+	if _, err = FromJSON([]byte(`{"a":[[1]]}`), JSONFriendly); err == nil {
+		t.Error("expected error for nested arrays")
+	}
+	if _, err = FromJSON([]byte(`[1]`), JSONFriendly); err == nil {
+		t.Error("expected error for json root that isn't an object")
+	}
+}
+
+func TestKeyValue_ToJSON_Lossless(t *testing.T) {
+	kv := readJSONSample(t)
+	data, err := kv.ToJSON(JSONLossless)
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, err := FromJSON(data, JSONLossless)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	reencoded, err := result.ToJSON(JSONLossless)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(data, reencoded) {
+		t.Errorf("lossless round trip changed json:\n%s\n%s", data, reencoded)
+	}
+
+	var expected, actual bytes.Buffer
+	writer := NewWriter(&expected)
+	writer.Write(kv)
+	writer = NewWriter(&actual)
+	writer.Write(result)
+	if expected.String() != actual.String() {
+		t.Errorf("lossless round trip changed tree:\n%s", actual.String())
+	}
+
+	if _, err = FromJSON([]byte(`{"key":"a","type":"bogus"}`), JSONLossless); err == nil {
+		t.Error("expected error for unknown type")
+	}
+}
@@ -2,6 +2,8 @@ package keyvalues
 
 import (
 	"errors"
+	"fmt"
+	"image/color"
 	"strconv"
 	"strings"
 )
@@ -13,8 +15,14 @@ const reservedKeyReplace = "replace"
 type KeyValue struct {
 	key       string
 	valueType ValueType
-	value     []interface{}
-	parent    *KeyValue
+	// value is a leaf's value, as it was written
+	value string
+	// children are a block's KeyValues, in order
+	children []*KeyValue
+	// index is built from children by AddChild once a block is large, and
+	// kept up to date by every change to children
+	index  *childIndex
+	parent *KeyValue
 }
 
 // key is the identifier for a stored value
@@ -36,24 +44,34 @@ func (node *KeyValue) Type() ValueType {
 // It will return the first found KeyValue in cases where the key is defined
 // multiple times
 func (node *KeyValue) Find(key string) (*KeyValue, error) {
-	children, err := node.FindAll(key)
-	if err != nil {
-		return nil, err
+	if index := node.indexed(); index != nil && isASCII(key) {
+		if children := index.find(key); len(children) > 0 {
+			return children[0], nil
+		}
+		return nil, errors.New("could not find key: " + key)
+	}
+	for _, child := range node.children {
+		if strings.EqualFold(child.key, key) {
+			return child, nil
+		}
 	}
 
-	return children[0], err
+	return nil, errors.New("could not find key: " + key)
 }
 
 // FindAll returns all children of a given type for a node.
 // This is different from properties, as a property is a string:<primitive>
 // This will return an array of all KeyValues that match a given
 // key, even though there should be only one.
+// Blocks with many children are looked up by an index.
 func (node *KeyValue) FindAll(key string) (children []*KeyValue, err error) {
-	searchKey := strings.ToLower(key)
-	for idx := range node.value {
-		n, _ := node.value[idx].(*KeyValue)
-		if strings.ToLower(n.key) == searchKey {
-			children = append(children, n)
+	if index := node.indexed(); index != nil && isASCII(key) {
+		children = append(children, index.find(key)...)
+	} else {
+		for _, child := range node.children {
+			if strings.EqualFold(child.key, key) {
+				children = append(children, child)
+			}
 		}
 	}
 	if len(children) == 0 {
@@ -76,19 +94,36 @@ func (node *KeyValue) Children() (children []*KeyValue, err error) {
 	if !node.HasChildren() {
 		return nil, errors.New("keyvalue has no children")
 	}
-	for idx := range node.value {
-		n, _ := node.value[idx].(*KeyValue)
-		children = append(children, n)
+	return append(children, node.children...), nil
+}
+
+// Value returns this key's value as it was written, regardless of
+// its detected type. Keys with children have no single value.
+func (node *KeyValue) Value() (string, error) {
+	if node.HasChildren() {
+		return "", errors.New("keyvalue has children")
 	}
-	return children, nil
+	return node.value, nil
+}
+
+// SetValue replaces this key's value, detecting its type the same way
+// the Reader does.
+func (node *KeyValue) SetValue(value string) error {
+	if node.HasChildren() {
+		return errors.New("cannot set value of keyvalue with children")
+	}
+	node.valueType = getType(value)
+	node.value = value
+	return nil
 }
 
-// AsString returns value as a string, assuming it is of string type
+// AsString returns value as a string, assuming it is of string or wide
+// string type
 func (node *KeyValue) AsString() (string, error) {
-	if node.valueType != ValueString {
+	if node.valueType != ValueString && node.valueType != ValueWString {
 		return "", errors.New("value is not of type string")
 	}
-	return (node.value[0]).(string), nil
+	return node.value, nil
 }
 
 // AsInt returns value as an int32, assuming it is of integer type
@@ -96,7 +131,7 @@ func (node *KeyValue) AsInt() (int32, error) {
 	if node.valueType != ValueInt {
 		return -1, errors.New("value is not of type integer")
 	}
-	val, err := strconv.ParseInt(node.value[0].(string), 10, 32)
+	val, err := strconv.ParseInt(node.value, 10, 32)
 	return int32(val), err
 }
 
@@ -105,10 +140,58 @@ func (node *KeyValue) AsFloat() (float32, error) {
 	if node.valueType != ValueFloat {
 		return -1, errors.New("value is not of type float")
 	}
-	val, err := strconv.ParseFloat(node.value[0].(string), 32)
+	val, err := strconv.ParseFloat(node.value, 32)
 	return float32(val), err
 }
 
+// AsPtr returns value as a uint32, assuming it is of pointer type
+// Pointers only appear in binary KeyValues, and are meaningless outside of
+// the process that wrote them.
+func (node *KeyValue) AsPtr() (uint32, error) {
+	if node.valueType != ValuePtr {
+		return 0, errors.New("value is not of type ptr")
+	}
+	val, err := strconv.ParseUint(node.value, 10, 32)
+	return uint32(val), err
+}
+
+// AsUint64 returns value as a uint64, assuming it is of uint64 type
+func (node *KeyValue) AsUint64() (uint64, error) {
+	if node.valueType != ValueUint64 {
+		return 0, errors.New("value is not of type uint64")
+	}
+	return strconv.ParseUint(node.value, 10, 64)
+}
+
+// AsInt64 returns value as an int64, assuming it is of int64 or integer type
+func (node *KeyValue) AsInt64() (int64, error) {
+	if node.valueType != ValueInt64 && node.valueType != ValueInt {
+		return 0, errors.New("value is not of type int64")
+	}
+	return strconv.ParseInt(node.value, 10, 64)
+}
+
+// AsColor returns value as a color, assuming it is of color type
+// Colors are written as "r g b a".
+func (node *KeyValue) AsColor() (color.RGBA, error) {
+	if node.valueType != ValueColor {
+		return color.RGBA{}, errors.New("value is not of type color")
+	}
+	fields := strings.Fields(node.value)
+	if len(fields) != 4 {
+		return color.RGBA{}, errors.New("color must have 4 components")
+	}
+	var rgba [4]uint8
+	for idx, field := range fields {
+		val, err := strconv.ParseUint(field, 10, 8)
+		if err != nil {
+			return color.RGBA{}, err
+		}
+		rgba[idx] = uint8(val)
+	}
+	return color.RGBA{R: rgba[0], G: rgba[1], B: rgba[2], A: rgba[3]}, nil
+}
+
 // AddChild adds a new KeyValue pair to an existing Key
 // Existing key's value must be an Array type
 func (node *KeyValue) AddChild(value *KeyValue) error {
@@ -116,7 +199,13 @@ func (node *KeyValue) AddChild(value *KeyValue) error {
 		return errors.New("parent does not accept child keys")
 	}
 	value.parent = node
-	node.value = append(node.value, value)
+	node.children = append(node.children, value)
+	switch {
+	case node.index != nil:
+		node.index.add(value)
+	case len(node.children) >= indexThreshold:
+		node.reindex()
+	}
 	return nil
 }
 
@@ -129,15 +218,27 @@ func (node *KeyValue) RemoveChild(key string) error {
 	if err != nil {
 		return errors.New("key does not exist")
 	}
-	for idx, c := range node.value {
+	for idx, c := range node.children {
 		if c == ret {
-			node.value = append(node.value[:idx], node.value[idx+1:]...)
+			node.children = append(node.children[:idx], node.children[idx+1:]...)
+			if node.index != nil {
+				node.index.remove(c)
+			}
 			return nil
 		}
 	}
 	return nil
 }
 
+// setKey renames node, rebuilding its parent's index, which has node under
+// its old key
+func (node *KeyValue) setKey(key string) {
+	node.key = key
+	if node.parent != nil {
+		node.parent.reindex()
+	}
+}
+
 // Parent returns this node's parent.
 // Parent can be nil
 func (node *KeyValue) Parent() *KeyValue {
@@ -147,6 +248,8 @@ func (node *KeyValue) Parent() *KeyValue {
 // Patch merges this KeyValue tree into another, adding KeyValues that don't exist in the parent.
 func (node *KeyValue) Patch(parent *KeyValue) (merged KeyValue, err error) {
 	merged = *parent
+	// merged gets its own index, as it may have different children
+	merged.reindex()
 	if node.Key() != merged.Key() {
 		// "patch" is a special key that can appear at the root of a keyvalue
 		// it does what it sounds like, its ony real purpose is to patch another tree
@@ -154,7 +257,7 @@ func (node *KeyValue) Patch(parent *KeyValue) (merged KeyValue, err error) {
 		if node.Key() != reservedKeyPatch {
 			return merged, errors.New("cannot merge mismatched root nodes")
 		}
-		node.key = merged.Key()
+		node.setKey(merged.Key())
 	}
 
 	err = recursiveMerge(node, &merged, false)
@@ -169,6 +272,7 @@ func (node *KeyValue) Patch(parent *KeyValue) (merged KeyValue, err error) {
 // replace the parent's value
 func (node *KeyValue) Replace(parent *KeyValue) (merged KeyValue, err error) {
 	merged = *parent
+	merged.reindex()
 	if node.Key() != merged.Key() {
 		// "replace" is a special key that can appear at the root of a keyvalue
 		// it does what it sounds like, its ony real purpose is to replace another tree's values
@@ -176,7 +280,7 @@ func (node *KeyValue) Replace(parent *KeyValue) (merged KeyValue, err error) {
 		if node.Key() != reservedKeyReplace {
 			return merged, errors.New("cannot merge mismatched root nodes")
 		}
-		node.key = merged.Key()
+		node.setKey(merged.Key())
 	}
 
 	err = recursiveMerge(node, &merged, true)
@@ -238,11 +342,41 @@ func recursiveMerge(a *KeyValue, b *KeyValue, shouldReplace bool) (err error) {
 }
 
 // NewKeyValuePair allows for manual creation of a single KeyValue pair.
+// Values that aren't strings are stored as they are printed by fmt.
 func NewKeyValuePair(key string, value interface{}, valueType ValueType) *KeyValue {
+	written, ok := value.(string)
+	if !ok {
+		written = fmt.Sprint(value)
+	}
 	return &KeyValue{
 		key:       key,
 		valueType: valueType,
-		value:     []interface{}{value},
+		value:     written,
 		parent:    nil,
 	}
 }
+
+// NewKeyValue creates a single KeyValue pair from its written value.
+// The value's type is detected the same way the Reader does.
+func NewKeyValue(key string, value string) *KeyValue {
+	return &KeyValue{
+		key:       key,
+		valueType: getType(value),
+		value:     value,
+		parent:    nil,
+	}
+}
+
+// NewKeyValueArray creates a KeyValue that holds other KeyValues as
+// its value.
+func NewKeyValueArray(key string, children ...*KeyValue) *KeyValue {
+	node := &KeyValue{
+		key:       key,
+		valueType: ValueArray,
+		parent:    nil,
+	}
+	for _, child := range children {
+		node.AddChild(child)
+	}
+	return node
+}
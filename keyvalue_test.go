@@ -9,7 +9,7 @@ func TestKeyValue_Key(t *testing.T) {
 	kv := KeyValue{
 		key:       "foo",
 		valueType: ValueString,
-		value:     nil,
+		value:     "",
 	}
 
 	if kv.Key() != key {
@@ -22,7 +22,7 @@ func TestKeyValue_Type(t *testing.T) {
 	kv := KeyValue{
 		key:       "foo",
 		valueType: ValueString,
-		value:     nil,
+		value:     "",
 	}
 
 	if kv.Type() != valueType {
@@ -34,7 +34,7 @@ func TestKeyValue_HasChildren(t *testing.T) {
 	kv := KeyValue{
 		key:       "foo",
 		valueType: ValueArray,
-		value:     nil,
+		value:     "",
 	}
 
 	if kv.HasChildren() != true {
@@ -51,17 +51,18 @@ func TestKeyValue_Find(t *testing.T) {
 	kv := KeyValue{
 		key:       "foo",
 		valueType: ValueArray,
-		value: append(make([]interface{}, 0),
+		children: []*KeyValue{
 			&KeyValue{
 				key:       "bar",
 				valueType: ValueString,
-				value:     append(make([]interface{}, 0), "hello"),
+				value:     "hello",
 			},
 			&KeyValue{
 				key:       "baz",
 				valueType: ValueInt,
-				value:     append(make([]interface{}, 0), 123),
-			}),
+				value:     "123",
+			},
+		},
 	}
 
 	if val, err := kv.Find("bar"); err != nil || val == nil {
@@ -78,22 +79,23 @@ func TestKeyValue_FindAll(t *testing.T) {
 	kv := KeyValue{
 		key:       "foo",
 		valueType: ValueArray,
-		value: append(make([]interface{}, 0),
+		children: []*KeyValue{
 			&KeyValue{
 				key:       "bar",
 				valueType: ValueString,
-				value:     append(make([]interface{}, 0), "hello"),
+				value:     "hello",
 			},
 			&KeyValue{
 				key:       "baz",
 				valueType: ValueInt,
-				value:     append(make([]interface{}, 0), 123),
+				value:     "123",
 			},
 			&KeyValue{
 				key:       "bar",
 				valueType: ValueFloat,
-				value:     append(make([]interface{}, 0), 345631.12312),
-			}),
+				value:     "345631.12312",
+			},
+		},
 	}
 
 	if vals, err := kv.FindAll("bar"); err != nil || vals == nil {
@@ -120,7 +122,7 @@ func TestKeyValue_AsString(t *testing.T) {
 	kv := KeyValue{
 		key:       "foo",
 		valueType: ValueString,
-		value:     append(make([]interface{}, 0), value),
+		value:     value,
 	}
 	if val, err := kv.AsString(); err != nil || val != value {
 		if err != nil {
@@ -137,7 +139,7 @@ func TestKeyValue_AsInt(t *testing.T) {
 	kv := KeyValue{
 		key:       "foo",
 		valueType: ValueInt,
-		value:     append(make([]interface{}, 0), value),
+		value:     value,
 	}
 	if val, err := kv.AsInt(); err != nil || val != expected {
 		if err != nil {
@@ -154,7 +156,7 @@ func TestKeyValue_AsFloat(t *testing.T) {
 	kv := KeyValue{
 		key:       "foo",
 		valueType: ValueFloat,
-		value:     append(make([]interface{}, 0), expected),
+		value:     expected,
 	}
 	if val, err := kv.AsFloat(); err != nil || val != value {
 		if err != nil {
@@ -169,47 +171,37 @@ func TestKeyValue_MergeInto(t *testing.T) {
 	a := &KeyValue{
 		key:       "replace",
 		valueType: ValueArray,
-		value: []interface{}{
+		children: []*KeyValue{
 			&KeyValue{
 				key:       "bar",
 				valueType: ValueString,
-				value: []interface{}{
-					"bar",
-				},
+				value:     "bar",
 			},
 			&KeyValue{
 				key:       "baz",
 				valueType: ValueString,
-				value: []interface{}{
-					"baz",
-				},
+				value:     "baz",
 			},
 			&KeyValue{
 				key:       "bat",
 				valueType: ValueString,
-				value: []interface{}{
-					"bat",
-				},
+				value:     "bat",
 			},
 		},
 	}
 	b := &KeyValue{
 		key:       "foo",
 		valueType: ValueArray,
-		value: []interface{}{
+		children: []*KeyValue{
 			&KeyValue{
 				key:       "bar",
 				valueType: ValueString,
-				value: []interface{}{
-					"cart",
-				},
+				value:     "cart",
 			},
 			&KeyValue{
 				key:       "egg",
 				valueType: ValueString,
-				value: []interface{}{
-					"bat",
-				},
+				value:     "bat",
 			},
 		},
 	}
@@ -252,47 +244,37 @@ func TestKeyValue_RemoveChild(t *testing.T) {
 	a := &KeyValue{
 		key:       "replace",
 		valueType: ValueArray,
-		value: []interface{}{
+		children: []*KeyValue{
 			&KeyValue{
 				key:       "bar",
 				valueType: ValueString,
-				value: []interface{}{
-					"bar",
-				},
+				value:     "bar",
 			},
 			&KeyValue{
 				key:       "baz",
 				valueType: ValueString,
-				value: []interface{}{
-					"baz",
-				},
+				value:     "baz",
 			},
 			&KeyValue{
 				key:       "bat",
 				valueType: ValueString,
-				value: []interface{}{
-					"bat",
-				},
+				value:     "bat",
 			},
 		},
 	}
 	b := &KeyValue{
 		key:       "foo",
 		valueType: ValueArray,
-		value: []interface{}{
+		children: []*KeyValue{
 			&KeyValue{
 				key:       "bar",
 				valueType: ValueString,
-				value: []interface{}{
-					"cart",
-				},
+				value:     "cart",
 			},
 			&KeyValue{
 				key:       "egg",
 				valueType: ValueString,
-				value: []interface{}{
-					"bat",
-				},
+				value:     "bat",
 			},
 		},
 	}
@@ -322,3 +304,34 @@ func TestKeyValue_RemoveChild(t *testing.T) {
 	}
 
 }
+
+func TestKeyValue_Value(t *testing.T) {
+	kv := NewKeyValue("foo", "123")
+	if kv.Type() != ValueInt {
+		t.Error("failed to detect value type")
+	}
+	if val, err := kv.Value(); err != nil || val != "123" {
+		t.Error("returned value does not match expected")
+	}
+
+	if err := kv.SetValue("bar"); err != nil {
+		t.Error(err)
+	}
+	if kv.Type() != ValueString {
+		t.Error("failed to detect value type")
+	}
+	if val, err := kv.Value(); err != nil || val != "bar" {
+		t.Error("returned value does not match expected")
+	}
+
+	parent := NewKeyValueArray("baz", kv)
+	if _, err := parent.Value(); err == nil {
+		t.Error("expected error, but received none")
+	}
+	if err := parent.SetValue("bar"); err == nil {
+		t.Error("expected error, but received none")
+	}
+	if kv.Parent() != parent {
+		t.Error("child does not reference its parent")
+	}
+}
@@ -0,0 +1,98 @@
+package kv3
+
+import (
+	"errors"
+	"math"
+	"strconv"
+
+	"github.com/galaco/KeyValues"
+)
+
+// ToKeyValue converts node into a KeyValue with key.
+// KeyValues have no arrays, so array items become children keyed by their
+// index. Booleans become 1 or 0, null becomes an empty string, and flags are
+// dropped. Binary blobs and nil members or items can't be converted.
+func (node *Node) ToKeyValue(key string) (*keyvalues.KeyValue, error) {
+	if node == nil {
+		return nil, errors.New("kv3 value " + key + " is nil")
+	}
+	switch node.Type {
+	case TypeObject:
+		kv := keyvalues.NewKeyValueArray(key)
+		for _, member := range node.Members {
+			child, err := member.Value.ToKeyValue(member.Key)
+			if err != nil {
+				return nil, err
+			}
+			kv.AddChild(child)
+		}
+		return kv, nil
+	case TypeArray:
+		kv := keyvalues.NewKeyValueArray(key)
+		for idx, item := range node.Items {
+			child, err := item.ToKeyValue(strconv.Itoa(idx))
+			if err != nil {
+				return nil, err
+			}
+			kv.AddChild(child)
+		}
+		return kv, nil
+	case TypeNull:
+		return keyvalues.NewKeyValuePair(key, "", keyvalues.ValueString), nil
+	case TypeBool:
+		if node.Bool {
+			return keyvalues.NewKeyValuePair(key, "1", keyvalues.ValueInt), nil
+		}
+		return keyvalues.NewKeyValuePair(key, "0", keyvalues.ValueInt), nil
+	case TypeInt:
+		if node.Int < math.MinInt32 || node.Int > math.MaxInt32 {
+			return keyvalues.NewKeyValuePair(key, strconv.FormatInt(node.Int, 10), keyvalues.ValueInt64), nil
+		}
+		return keyvalues.NewKeyValuePair(key, strconv.FormatInt(node.Int, 10), keyvalues.ValueInt), nil
+	case TypeUint:
+		return keyvalues.NewKeyValuePair(key, strconv.FormatUint(node.Uint, 10), keyvalues.ValueUint64), nil
+	case TypeFloat:
+		return keyvalues.NewKeyValuePair(key, formatFloat(node.Float), keyvalues.ValueFloat), nil
+	case TypeString:
+		return keyvalues.NewKeyValuePair(key, node.String, keyvalues.ValueString), nil
+	case TypeBlob:
+		return nil, errors.New("binary blob " + key + " cannot be converted to keyvalues")
+	}
+	return nil, errors.New("unknown kv3 type: " + strconv.Itoa(int(node.Type)))
+}
+
+// FromKeyValue converts the value of kv into a Node.
+// KeyValues with children become objects, keeping duplicate keys. Values
+// become the KV3 type matching their ValueType, and colors become arrays of
+// four integers.
+func FromKeyValue(kv *keyvalues.KeyValue) *Node {
+	if kv.HasChildren() {
+		node := NewObject()
+		children, _ := kv.Children()
+		for _, child := range children {
+			node.Members = append(node.Members, Member{Key: child.Key(), Value: FromKeyValue(child)})
+		}
+		return node
+	}
+
+	value, _ := kv.Value()
+	switch kv.Type() {
+	case keyvalues.ValueInt, keyvalues.ValueInt64:
+		if i, err := strconv.ParseInt(value, 10, 64); err == nil {
+			return NewInt(i)
+		}
+	case keyvalues.ValuePtr, keyvalues.ValueUint64:
+		if u, err := strconv.ParseUint(value, 10, 64); err == nil {
+			return NewUint(u)
+		}
+	case keyvalues.ValueFloat:
+		if f, err := strconv.ParseFloat(value, 64); err == nil {
+			return NewFloat(f)
+		}
+	case keyvalues.ValueColor:
+		if rgba, err := kv.AsColor(); err == nil {
+			return NewArray(NewInt(int64(rgba.R)), NewInt(int64(rgba.G)), NewInt(int64(rgba.B)), NewInt(int64(rgba.A)))
+		}
+	}
+	return NewString(value)
+}
@@ -0,0 +1,82 @@
+package kv3
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/galaco/KeyValues"
+)
+
+func TestNode_ToKeyValue(t *testing.T) {
+	reader := NewReader(strings.NewReader(sampleKV3))
+	doc, err := reader.Read()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = doc.Root.ToKeyValue("root"); err == nil {
+		t.Error("expected error converting binary blob")
+	}
+	doc.Root.Remove("m_Data")
+
+	kv, err := doc.Root.ToKeyValue("root")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if node, _ := kv.Find("m_bEnabled"); node.Type() != keyvalues.ValueInt {
+		t.Errorf("unexpected bool type: %s", node.Type())
+	}
+	if node, _ := kv.Find("m_Material"); node.Type() != keyvalues.ValueString {
+		t.Errorf("unexpected resource type: %s", node.Type())
+	}
+	origin, _ := kv.Find("m_vOrigin")
+	if z, _ := origin.Find("2"); z == nil || z.Type() != keyvalues.ValueFloat {
+		t.Error("unexpected array conversion")
+	}
+	children, _ := kv.Find("m_Children")
+	first, _ := children.Find("0")
+	if name, _ := first.Find("m_Name"); name == nil {
+		t.Error("unexpected object in array conversion")
+	} else if value, _ := name.AsString(); value != "first" {
+		t.Errorf("unexpected child name: %s", value)
+	}
+}
+
+func TestFromKeyValue(t *testing.T) {
+	kv := keyvalues.NewKeyValueArray("root",
+		keyvalues.NewKeyValue("count", "3"),
+		keyvalues.NewKeyValue("scale", "0.5"),
+		keyvalues.NewKeyValue("name", "foo"),
+		keyvalues.NewKeyValuePair("color", "255 128 0 255", keyvalues.ValueColor),
+		keyvalues.NewKeyValueArray("child", keyvalues.NewKeyValue("name", "bar")))
+
+	node := FromKeyValue(kv)
+	if node.Type != TypeObject || len(node.Members) != 5 {
+		t.Fatalf("unexpected node: %+v", node)
+	}
+	if count, _ := node.Find("count"); count.Type != TypeInt || count.Int != 3 {
+		t.Errorf("unexpected int: %+v", count)
+	}
+	if scale, _ := node.Find("scale"); scale.Type != TypeFloat || scale.Float != 0.5 {
+		t.Errorf("unexpected float: %+v", scale)
+	}
+	if color, _ := node.Find("color"); len(color.Items) != 4 || color.Items[1].Int != 128 {
+		t.Errorf("unexpected color: %+v", color)
+	}
+	child, _ := node.Find("child")
+	if name, _ := child.Find("name"); name.String != "bar" {
+		t.Errorf("unexpected child: %+v", child)
+	}
+}
+
+func TestNode_ToKeyValueNil(t *testing.T) {
+	object := NewObject()
+	object.Set("missing", nil)
+	for name, node := range map[string]*Node{
+		"member": object,
+		"item":   NewArray(NewInt(1), nil),
+	} {
+		if _, err := node.ToKeyValue("root"); err == nil {
+			t.Errorf("expected error for nil %s", name)
+		}
+	}
+}
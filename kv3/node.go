@@ -0,0 +1,194 @@
+package kv3
+
+import (
+	"errors"
+)
+
+// Type is the type of a KeyValues3 value
+type Type int
+
+const (
+	TypeNull Type = iota
+	TypeBool
+	TypeInt
+	TypeUint
+	TypeFloat
+	TypeString
+	TypeBlob
+	TypeArray
+	TypeObject
+)
+
+// Flag prefixes a KeyValues3 value with how it should be interpreted,
+// as in resource:"materials/dev/dev_measuregeneric01.vmat"
+type Flag string
+
+const FlagNone = Flag("")
+const FlagResource = Flag("resource")
+const FlagResourceName = Flag("resource_name")
+const FlagPanorama = Flag("panorama")
+const FlagSoundEvent = Flag("soundevent")
+const FlagSubclass = Flag("subclass")
+const FlagEntityName = Flag("entity_name")
+
+// Format names an encoding or format in a KeyValues3 header, along with the
+// GUID of its version
+type Format struct {
+	Name    string
+	Version string
+}
+
+// EncodingText is the encoding of KeyValues3 text files
+var EncodingText = Format{Name: "text", Version: "e21c7f3c-8a33-41c5-9977-a76d3a32aa0d"}
+
+// FormatGeneric is the format of KeyValues3 files without a specific schema
+var FormatGeneric = Format{Name: "generic", Version: "7412167c-06e9-4698-aff2-e63eb59037e7"}
+
+// Document is a KeyValues3 file: its header, and its root value, which is
+// normally an object
+type Document struct {
+	Encoding Format
+	Format   Format
+	Root     *Node
+}
+
+// NewDocument returns a text encoded, generic format Document containing root
+func NewDocument(root *Node) *Document {
+	return &Document{
+		Encoding: EncodingText,
+		Format:   FormatGeneric,
+		Root:     root,
+	}
+}
+
+// Node is a single KeyValues3 value.
+// Only the field matching Type is used; arrays use Items and objects use
+// Members.
+type Node struct {
+	Type    Type
+	Flag    Flag
+	Bool    bool
+	Int     int64
+	Uint    uint64
+	Float   float64
+	String  string
+	Blob    []byte
+	Items   []*Node
+	Members []Member
+}
+
+// Member is a single key of an object.
+// Members are kept in the order they were read or added.
+type Member struct {
+	Key   string
+	Value *Node
+}
+
+// NewNull returns a null value
+func NewNull() *Node {
+	return &Node{Type: TypeNull}
+}
+
+// NewBool returns a boolean value
+func NewBool(value bool) *Node {
+	return &Node{Type: TypeBool, Bool: value}
+}
+
+// NewInt returns a signed integer value
+func NewInt(value int64) *Node {
+	return &Node{Type: TypeInt, Int: value}
+}
+
+// NewUint returns an unsigned integer value
+func NewUint(value uint64) *Node {
+	return &Node{Type: TypeUint, Uint: value}
+}
+
+// NewFloat returns a floating point value
+func NewFloat(value float64) *Node {
+	return &Node{Type: TypeFloat, Float: value}
+}
+
+// NewString returns a string value
+func NewString(value string) *Node {
+	return &Node{Type: TypeString, String: value}
+}
+
+// NewFlaggedString returns a string value with a flag, such as a resource path
+func NewFlaggedString(flag Flag, value string) *Node {
+	return &Node{Type: TypeString, Flag: flag, String: value}
+}
+
+// NewBlob returns a binary blob value
+func NewBlob(value []byte) *Node {
+	return &Node{Type: TypeBlob, Blob: value}
+}
+
+// NewArray returns an array of items
+func NewArray(items ...*Node) *Node {
+	return &Node{Type: TypeArray, Items: items}
+}
+
+// NewObject returns an empty object
+func NewObject() *Node {
+	return &Node{Type: TypeObject}
+}
+
+// IsScalar returns whether the node is neither an array nor an object
+func (node *Node) IsScalar() bool {
+	return node.Type != TypeArray && node.Type != TypeObject
+}
+
+// Find returns the value of the first member of an object with key.
+// Keys are case sensitive in KeyValues3.
+func (node *Node) Find(key string) (*Node, error) {
+	if node.Type != TypeObject {
+		return nil, errors.New("node is not an object")
+	}
+	for _, member := range node.Members {
+		if member.Key == key {
+			return member.Value, nil
+		}
+	}
+	return nil, errors.New("could not find key: " + key)
+}
+
+// Set sets the value of key in an object, replacing the first member with the
+// same key, or adding a new member if there is none
+func (node *Node) Set(key string, value *Node) error {
+	if node.Type != TypeObject {
+		return errors.New("node is not an object")
+	}
+	for idx := range node.Members {
+		if node.Members[idx].Key == key {
+			node.Members[idx].Value = value
+			return nil
+		}
+	}
+	node.Members = append(node.Members, Member{Key: key, Value: value})
+	return nil
+}
+
+// Remove removes every member of an object with key
+func (node *Node) Remove(key string) error {
+	if node.Type != TypeObject {
+		return errors.New("node is not an object")
+	}
+	members := node.Members[:0]
+	for _, member := range node.Members {
+		if member.Key != key {
+			members = append(members, member)
+		}
+	}
+	node.Members = members
+	return nil
+}
+
+// Append adds items to the end of an array
+func (node *Node) Append(items ...*Node) error {
+	if node.Type != TypeArray {
+		return errors.New("node is not an array")
+	}
+	node.Items = append(node.Items, items...)
+	return nil
+}
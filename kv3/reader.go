@@ -0,0 +1,381 @@
+package kv3
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"io"
+	"io/ioutil"
+	"strconv"
+	"strings"
+)
+
+const tokenHeaderStart = "<!--"
+const tokenHeaderEnd = "-->"
+const tokenHeaderName = "kv3"
+const tokenHeaderVersion = "version"
+const tokenMultiLineString = `"""`
+const tokenLineComment = "//"
+const tokenBlockCommentStart = "/*"
+const tokenBlockCommentEnd = "*/"
+const tokenBlobStart = "#["
+const tokenNull = "null"
+const tokenTrue = "true"
+const tokenFalse = "false"
+
+// Reader is used for parsing a KeyValues3 text stream
+type Reader struct {
+	file io.Reader
+}
+
+// NewReader Return a new KeyValues3 Reader
+func NewReader(file io.Reader) Reader {
+	reader := Reader{}
+	reader.file = file
+	return reader
+}
+
+// Read parses the stream into a Document.
+// The stream must start with a KeyValues3 header, followed by a single value.
+func (reader *Reader) Read() (*Document, error) {
+	data, err := ioutil.ReadAll(reader.file)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{data: data}
+
+	doc := &Document{}
+	if err = p.readHeader(doc); err != nil {
+		return nil, err
+	}
+	if doc.Root, err = p.readValue(); err != nil {
+		return nil, err
+	}
+	if err = p.skipSpace(); err != nil {
+		return nil, err
+	}
+	if !p.eof() {
+		return nil, p.error("unexpected data after root value")
+	}
+	return doc, nil
+}
+
+// parser holds the position of a Reader in its data
+type parser struct {
+	data []byte
+	pos  int
+}
+
+// readHeader parses a header such as
+// <!-- kv3 encoding:text:version{...} format:generic:version{...} -->
+func (p *parser) readHeader(doc *Document) error {
+	if err := p.skipSpace(); err != nil {
+		return err
+	}
+	if !p.consume(tokenHeaderStart) {
+		return p.error("missing kv3 header")
+	}
+	end := bytes.Index(p.data[p.pos:], []byte(tokenHeaderEnd))
+	if end < 0 {
+		return p.error("unterminated kv3 header")
+	}
+	fields := strings.Fields(string(p.data[p.pos : p.pos+end]))
+	if len(fields) == 0 || fields[0] != tokenHeaderName {
+		return p.error("missing kv3 header")
+	}
+	for _, field := range fields[1:] {
+		parts := strings.SplitN(field, ":", 3)
+		if len(parts) != 3 || !strings.HasPrefix(parts[2], tokenHeaderVersion+"{") || !strings.HasSuffix(parts[2], "}") {
+			return p.error("invalid kv3 header field: " + field)
+		}
+		format := Format{
+			Name:    parts[1],
+			Version: strings.TrimSuffix(strings.TrimPrefix(parts[2], tokenHeaderVersion+"{"), "}"),
+		}
+		switch parts[0] {
+		case "encoding":
+			doc.Encoding = format
+		case "format":
+			doc.Format = format
+		}
+	}
+	p.pos += end + len(tokenHeaderEnd)
+	return nil
+}
+
+// readValue parses a single value of any type, after any whitespace
+func (p *parser) readValue() (*Node, error) {
+	if err := p.skipSpace(); err != nil {
+		return nil, err
+	}
+	if p.eof() {
+		return nil, p.error("unexpected end of file")
+	}
+
+	switch {
+	case p.peek() == '{':
+		return p.readObject()
+	case p.peek() == '[':
+		return p.readArray()
+	case p.has(tokenBlobStart):
+		return p.readBlob()
+	case p.peek() == '"':
+		value, err := p.readString()
+		if err != nil {
+			return nil, err
+		}
+		return NewString(value), nil
+	}
+
+	start := p.pos
+	token := p.readToken()
+	if len(token) == 0 {
+		return nil, p.error("unexpected character '" + string(p.peek()) + "'")
+	}
+	// a flag, such as resource:"path"
+	if !p.eof() && p.peek() == ':' {
+		p.pos++
+		node, err := p.readValue()
+		if err != nil {
+			return nil, err
+		}
+		node.Flag = Flag(token)
+		return node, nil
+	}
+
+	switch token {
+	case tokenNull:
+		return NewNull(), nil
+	case tokenTrue:
+		return NewBool(true), nil
+	case tokenFalse:
+		return NewBool(false), nil
+	}
+	if i, err := strconv.ParseInt(token, 10, 64); err == nil {
+		return NewInt(i), nil
+	}
+	if u, err := strconv.ParseUint(token, 10, 64); err == nil {
+		return NewUint(u), nil
+	}
+	if f, err := strconv.ParseFloat(token, 64); err == nil {
+		return NewFloat(f), nil
+	}
+	p.pos = start
+	return nil, p.error("invalid value: " + token)
+}
+
+func (p *parser) readObject() (*Node, error) {
+	node := NewObject()
+	p.pos++
+	for {
+		if err := p.skipSpace(); err != nil {
+			return nil, err
+		}
+		if p.eof() {
+			return nil, p.error("unterminated object")
+		}
+		if p.peek() == '}' {
+			p.pos++
+			return node, nil
+		}
+
+		key, err := p.readKey()
+		if err != nil {
+			return nil, err
+		}
+		if err = p.skipSpace(); err != nil {
+			return nil, err
+		}
+		if !p.consume("=") {
+			return nil, p.error("expected '=' after key " + key)
+		}
+		value, err := p.readValue()
+		if err != nil {
+			return nil, err
+		}
+		node.Members = append(node.Members, Member{Key: key, Value: value})
+
+		if err = p.skipSpace(); err != nil {
+			return nil, err
+		}
+		p.consume(",")
+	}
+}
+
+func (p *parser) readArray() (*Node, error) {
+	node := NewArray()
+	p.pos++
+	for {
+		if err := p.skipSpace(); err != nil {
+			return nil, err
+		}
+		if p.eof() {
+			return nil, p.error("unterminated array")
+		}
+		if p.peek() == ']' {
+			p.pos++
+			return node, nil
+		}
+
+		item, err := p.readValue()
+		if err != nil {
+			return nil, err
+		}
+		node.Items = append(node.Items, item)
+
+		if err = p.skipSpace(); err != nil {
+			return nil, err
+		}
+		if !p.consume(",") && !p.has("]") {
+			return nil, p.error("expected ',' or ']' in array")
+		}
+	}
+}
+
+// readBlob parses a binary blob of hex bytes, such as #[ 00 01 FF ]
+func (p *parser) readBlob() (*Node, error) {
+	p.pos += len(tokenBlobStart)
+	end := bytes.IndexByte(p.data[p.pos:], ']')
+	if end < 0 {
+		return nil, p.error("unterminated binary blob")
+	}
+	digits := strings.Join(strings.Fields(string(p.data[p.pos:p.pos+end])), "")
+	blob, err := hex.DecodeString(digits)
+	if err != nil {
+		return nil, p.error("invalid binary blob")
+	}
+	p.pos += end + 1
+	return NewBlob(blob), nil
+}
+
+// readKey parses a key, which is either quoted or a bare identifier
+func (p *parser) readKey() (string, error) {
+	if p.peek() == '"' {
+		return p.readString()
+	}
+	key := p.readToken()
+	if len(key) == 0 {
+		return "", p.error("unexpected character '" + string(p.peek()) + "'")
+	}
+	return key, nil
+}
+
+// readString parses a quoted or multi-line string
+func (p *parser) readString() (string, error) {
+	if p.consume(tokenMultiLineString) {
+		// the line breaks after the opening and before the closing quotes
+		// aren't part of the string
+		if !p.consume("\r\n") {
+			p.consume("\n")
+		}
+		end := bytes.Index(p.data[p.pos:], []byte(tokenMultiLineString))
+		if end < 0 {
+			return "", p.error("unterminated multi-line string")
+		}
+		// only the line break of the closing quotes' line is trimmed, so
+		// that a string ending in \r keeps it
+		value := strings.TrimSuffix(string(p.data[p.pos:p.pos+end]), "\n")
+		p.pos += end + len(tokenMultiLineString)
+		return value, nil
+	}
+
+	start := p.pos
+	p.pos++
+	var builder strings.Builder
+	for !p.eof() {
+		char := p.data[p.pos]
+		p.pos++
+		switch char {
+		case '"':
+			return builder.String(), nil
+		case '\\':
+			if p.eof() {
+				break
+			}
+			escaped := p.data[p.pos]
+			p.pos++
+			switch escaped {
+			case 'n':
+				builder.WriteByte('\n')
+			case 't':
+				builder.WriteByte('\t')
+			case 'r':
+				builder.WriteByte('\r')
+			default:
+				builder.WriteByte(escaped)
+			}
+		default:
+			builder.WriteByte(char)
+		}
+	}
+	p.pos = start
+	return "", p.error("unterminated string")
+}
+
+// readToken reads a bare word: an identifier, keyword or number
+func (p *parser) readToken() string {
+	start := p.pos
+	for !p.eof() && isTokenCharacter(p.peek()) {
+		p.pos++
+	}
+	return string(p.data[start:p.pos])
+}
+
+// skipSpace skips whitespace and comments
+func (p *parser) skipSpace() error {
+	for !p.eof() {
+		switch {
+		case p.peek() == ' ' || p.peek() == '\t' || p.peek() == '\r' || p.peek() == '\n':
+			p.pos++
+		case p.has(tokenLineComment):
+			end := bytes.IndexByte(p.data[p.pos:], '\n')
+			if end < 0 {
+				p.pos = len(p.data)
+			} else {
+				p.pos += end + 1
+			}
+		case p.has(tokenBlockCommentStart):
+			end := bytes.Index(p.data[p.pos+len(tokenBlockCommentStart):], []byte(tokenBlockCommentEnd))
+			if end < 0 {
+				return p.error("unterminated comment")
+			}
+			p.pos += len(tokenBlockCommentStart) + end + len(tokenBlockCommentEnd)
+		default:
+			return nil
+		}
+	}
+	return nil
+}
+
+func (p *parser) eof() bool {
+	return p.pos >= len(p.data)
+}
+
+func (p *parser) peek() byte {
+	return p.data[p.pos]
+}
+
+func (p *parser) has(token string) bool {
+	return bytes.HasPrefix(p.data[p.pos:], []byte(token))
+}
+
+func (p *parser) consume(token string) bool {
+	if !p.has(token) {
+		return false
+	}
+	p.pos += len(token)
+	return true
+}
+
+// error returns an error with the line of the current position
+func (p *parser) error(message string) error {
+	line := bytes.Count(p.data[:p.pos], []byte("\n")) + 1
+	return errors.New(message + " on line " + strconv.Itoa(line))
+}
+
+func isTokenCharacter(char byte) bool {
+	return char >= 'a' && char <= 'z' ||
+		char >= 'A' && char <= 'Z' ||
+		char >= '0' && char <= '9' ||
+		char == '_' || char == '.' || char == '-' || char == '+'
+}
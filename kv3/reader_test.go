@@ -0,0 +1,131 @@
+package kv3
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+const sampleKV3 = `<!-- kv3 encoding:text:version{e21c7f3c-8a33-41c5-9977-a76d3a32aa0d} format:generic:version{7412167c-06e9-4698-aff2-e63eb59037e7} -->
+{
+	// a line comment
+	m_nVersion = 3
+	m_bEnabled = true
+	m_flScale = 1.5
+	m_nBig = 18446744073709551615
+	m_Nothing = null
+	"quoted key" = "escaped \"value\"\n"
+	m_Material = resource:"materials/dev/dev_measuregeneric01.vmat"
+	m_Sound = soundevent:"Weapon.Fire"
+	/* a block
+	   comment */
+	m_vOrigin = [ 0.0, -64.0, 128.0 ]
+	m_Data = #[ 00 0A ff ]
+	m_Children =
+	[
+		{
+			m_Name = "first"
+		},
+		{
+			m_Name = "second"
+		},
+	]
+	m_Description = """
+first line
+second line
+"""
+}
+`
+
+func TestReader_Read(t *testing.T) {
+	reader := NewReader(strings.NewReader(sampleKV3))
+	doc, err := reader.Read()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if doc.Encoding != EncodingText || doc.Format != FormatGeneric {
+		t.Errorf("unexpected header: %v %v", doc.Encoding, doc.Format)
+	}
+
+	root := doc.Root
+	if root.Type != TypeObject || len(root.Members) != 12 {
+		t.Fatalf("unexpected root: %+v", root)
+	}
+	if node, _ := root.Find("m_nVersion"); node.Type != TypeInt || node.Int != 3 {
+		t.Errorf("unexpected int: %+v", node)
+	}
+	if node, _ := root.Find("m_bEnabled"); node.Type != TypeBool || !node.Bool {
+		t.Errorf("unexpected bool: %+v", node)
+	}
+	if node, _ := root.Find("m_flScale"); node.Type != TypeFloat || node.Float != 1.5 {
+		t.Errorf("unexpected float: %+v", node)
+	}
+	if node, _ := root.Find("m_nBig"); node.Type != TypeUint || node.Uint != 18446744073709551615 {
+		t.Errorf("unexpected uint: %+v", node)
+	}
+	if node, _ := root.Find("m_Nothing"); node.Type != TypeNull {
+		t.Errorf("unexpected null: %+v", node)
+	}
+	if node, _ := root.Find("quoted key"); node.String != "escaped \"value\"\n" {
+		t.Errorf("unexpected string: %q", node.String)
+	}
+	if node, _ := root.Find("m_Material"); node.Flag != FlagResource || node.String != "materials/dev/dev_measuregeneric01.vmat" {
+		t.Errorf("unexpected resource: %+v", node)
+	}
+	if node, _ := root.Find("m_Sound"); node.Flag != FlagSoundEvent {
+		t.Errorf("unexpected sound event: %+v", node)
+	}
+	if node, _ := root.Find("m_vOrigin"); len(node.Items) != 3 || node.Items[1].Float != -64 {
+		t.Errorf("unexpected array: %+v", node)
+	}
+	if node, _ := root.Find("m_Data"); !bytes.Equal(node.Blob, []byte{0x00, 0x0a, 0xff}) {
+		t.Errorf("unexpected blob: %v", node.Blob)
+	}
+	children, _ := root.Find("m_Children")
+	if len(children.Items) != 2 {
+		t.Fatalf("unexpected children: %+v", children)
+	}
+	if name, _ := children.Items[1].Find("m_Name"); name.String != "second" {
+		t.Errorf("unexpected child name: %s", name.String)
+	}
+	if node, _ := root.Find("m_Description"); node.String != "first line\nsecond line" {
+		t.Errorf("unexpected multi-line string: %q", node.String)
+	}
+}
+
+func TestReader_Read_MultiLineCarriageReturn(t *testing.T) {
+	reader := NewReader(strings.NewReader("<!-- kv3 -->\n{\n\ta = \"\"\"\nline\r\n\"\"\"\n\tb = \"\"\"\r\nline\r\n\"\"\"\n}\n"))
+	doc, err := reader.Read()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for key, expected := range map[string]string{
+		"a": "line\r",
+		"b": "line\r",
+	} {
+		node, err := doc.Root.Find(key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if node.String != expected {
+			t.Errorf("unexpected multi-line string for %s: %q", key, node.String)
+		}
+	}
+}
+
+func TestReader_ReadErrors(t *testing.T) {
+	samples := map[string]string{
+		"missing header":      "{ a = 1 }",
+		"missing equals":      "<!-- kv3 -->\n{ a 1 }",
+		"unterminated":        "<!-- kv3 -->\n{ a = [ 1, 2 }",
+		"invalid value":       "<!-- kv3 -->\n{ a = 1x }",
+		"unterminated string": "<!-- kv3 -->\n{ a = \"b }",
+		"trailing data":       "<!-- kv3 -->\n{ } }",
+	}
+	for name, sample := range samples {
+		reader := NewReader(strings.NewReader(sample))
+		if _, err := reader.Read(); err == nil {
+			t.Errorf("expected error for %s", name)
+		}
+	}
+}
@@ -0,0 +1,208 @@
+package kv3
+
+import (
+	"bufio"
+	"encoding/hex"
+	"errors"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+)
+
+const tokenNewLine = "\n"
+const tokenIndent = "\t"
+
+// Writer is used for writing a Document in KeyValues3 text format
+type Writer struct {
+	file io.Writer
+}
+
+// NewWriter Return a new KeyValues3 Writer
+func NewWriter(file io.Writer) Writer {
+	writer := Writer{}
+	writer.file = file
+	return writer
+}
+
+// Write serializes a Document to the underlying stream.
+// Objects are written over multiple lines with tab indentation, as are arrays
+// containing objects, arrays or multi-line strings; other arrays are written
+// on a single line. Strings containing line breaks are written as multi-line
+// strings.
+func (writer *Writer) Write(doc *Document) error {
+	if doc.Root == nil {
+		return errors.New("document has no root value")
+	}
+	encoding, format := doc.Encoding, doc.Format
+	if len(encoding.Name) == 0 {
+		encoding = EncodingText
+	}
+	if len(format.Name) == 0 {
+		format = FormatGeneric
+	}
+
+	bufWriter := bufio.NewWriter(writer.file)
+	bufWriter.WriteString(tokenHeaderStart + " " + tokenHeaderName +
+		" encoding:" + encoding.Name + ":" + tokenHeaderVersion + "{" + encoding.Version + "}" +
+		" format:" + format.Name + ":" + tokenHeaderVersion + "{" + format.Version + "}" +
+		" " + tokenHeaderEnd + tokenNewLine)
+	if err := writeValue(bufWriter, doc.Root, 0); err != nil {
+		return err
+	}
+	bufWriter.WriteString(tokenNewLine)
+	return bufWriter.Flush()
+}
+
+// writeValue writes node at the current position of writer, indenting any
+// lines after the first to depth.
+// Errors are left for the final flush, as bufio.Writer keeps them.
+func writeValue(writer *bufio.Writer, node *Node, depth int) error {
+	if node == nil {
+		return errors.New("kv3 value is nil")
+	}
+	if len(node.Flag) > 0 {
+		writer.WriteString(string(node.Flag) + ":")
+	}
+
+	switch node.Type {
+	case TypeNull:
+		writer.WriteString(tokenNull)
+	case TypeBool:
+		writer.WriteString(strconv.FormatBool(node.Bool))
+	case TypeInt:
+		writer.WriteString(strconv.FormatInt(node.Int, 10))
+	case TypeUint:
+		writer.WriteString(strconv.FormatUint(node.Uint, 10))
+	case TypeFloat:
+		writer.WriteString(formatFloat(node.Float))
+	case TypeString:
+		writer.WriteString(quoteString(node.String))
+	case TypeBlob:
+		writer.WriteString(tokenBlobStart)
+		for _, b := range node.Blob {
+			writer.WriteString(" " + strings.ToUpper(hex.EncodeToString([]byte{b})))
+		}
+		writer.WriteString(" ]")
+	case TypeArray:
+		return writeArray(writer, node, depth)
+	case TypeObject:
+		return writeObject(writer, node, depth)
+	default:
+		return errors.New("unknown kv3 type: " + strconv.Itoa(int(node.Type)))
+	}
+	return nil
+}
+
+func writeObject(writer *bufio.Writer, node *Node, depth int) error {
+	writer.WriteString("{" + tokenNewLine)
+	for _, member := range node.Members {
+		if member.Value == nil {
+			return errors.New("kv3 member " + member.Key + " has a nil value")
+		}
+		writer.WriteString(indent(depth+1) + quoteKey(member.Key) + " =")
+		if member.Value.IsScalar() || isInlineArray(member.Value) {
+			writer.WriteString(" ")
+		} else {
+			// objects and arrays open on their own line
+			writer.WriteString(tokenNewLine + indent(depth+1))
+		}
+		if err := writeValue(writer, member.Value, depth+1); err != nil {
+			return err
+		}
+		writer.WriteString(tokenNewLine)
+	}
+	writer.WriteString(indent(depth) + "}")
+	return nil
+}
+
+func writeArray(writer *bufio.Writer, node *Node, depth int) error {
+	if isInlineArray(node) {
+		if len(node.Items) == 0 {
+			writer.WriteString("[]")
+			return nil
+		}
+		writer.WriteString("[ ")
+		for idx, item := range node.Items {
+			if idx > 0 {
+				writer.WriteString(", ")
+			}
+			if err := writeValue(writer, item, depth); err != nil {
+				return err
+			}
+		}
+		writer.WriteString(" ]")
+		return nil
+	}
+
+	writer.WriteString("[" + tokenNewLine)
+	for _, item := range node.Items {
+		writer.WriteString(indent(depth + 1))
+		if err := writeValue(writer, item, depth+1); err != nil {
+			return err
+		}
+		writer.WriteString("," + tokenNewLine)
+	}
+	writer.WriteString(indent(depth) + "]")
+	return nil
+}
+
+// isInlineArray returns whether an array can be written on a single line
+func isInlineArray(node *Node) bool {
+	if node.Type != TypeArray {
+		return false
+	}
+	for _, item := range node.Items {
+		if item == nil || !item.IsScalar() || item.Type == TypeString && strings.Contains(item.String, tokenNewLine) {
+			return false
+		}
+	}
+	return true
+}
+
+func indent(depth int) string {
+	return strings.Repeat(tokenIndent, depth)
+}
+
+// quoteKey returns key bare if it is an identifier, or quoted otherwise
+func quoteKey(key string) string {
+	if len(key) == 0 || key[0] >= '0' && key[0] <= '9' || key[0] == '-' || key[0] == '+' || key[0] == '.' {
+		return quote(key)
+	}
+	for idx := 0; idx < len(key); idx++ {
+		if !isTokenCharacter(key[idx]) || key[idx] == '-' || key[idx] == '+' {
+			return quote(key)
+		}
+	}
+	switch key {
+	case tokenNull, tokenTrue, tokenFalse:
+		return quote(key)
+	}
+	return key
+}
+
+// quoteString returns value as a multi-line string if it contains line
+// breaks, or a quoted string otherwise
+func quoteString(value string) string {
+	if strings.Contains(value, tokenNewLine) && !strings.Contains(value, tokenMultiLineString) {
+		return tokenMultiLineString + tokenNewLine + value + tokenNewLine + tokenMultiLineString
+	}
+	return quote(value)
+}
+
+func quote(value string) string {
+	replacer := strings.NewReplacer("\\", "\\\\", "\"", "\\\"", "\n", "\\n", "\t", "\\t", "\r", "\\r")
+	return "\"" + replacer.Replace(value) + "\""
+}
+
+// formatFloat formats f so that it is read back as a float
+func formatFloat(f float64) string {
+	if math.IsInf(f, 0) || math.IsNaN(f) {
+		return strings.ToLower(strconv.FormatFloat(f, 'f', -1, 64))
+	}
+	value := strconv.FormatFloat(f, 'f', -1, 64)
+	if !strings.Contains(value, ".") {
+		value += ".0"
+	}
+	return value
+}
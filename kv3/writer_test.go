@@ -0,0 +1,107 @@
+package kv3
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestWriter_Write(t *testing.T) {
+	root := NewObject()
+	root.Set("m_nVersion", NewInt(3))
+	root.Set("m_flScale", NewFloat(2))
+	root.Set("m_Material", NewFlaggedString(FlagResource, "materials/dev/dev_measuregeneric01.vmat"))
+	root.Set("m_vOrigin", NewArray(NewFloat(0), NewFloat(-64), NewFloat(128)))
+	child := NewObject()
+	child.Set("m_Name", NewString("first"))
+	root.Set("m_Children", NewArray(child))
+	root.Set("1 key", NewString("line\nbreak"))
+
+	var buf bytes.Buffer
+	writer := NewWriter(&buf)
+	if err := writer.Write(NewDocument(root)); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := `<!-- kv3 encoding:text:version{e21c7f3c-8a33-41c5-9977-a76d3a32aa0d} format:generic:version{7412167c-06e9-4698-aff2-e63eb59037e7} -->
+{
+	m_nVersion = 3
+	m_flScale = 2.0
+	m_Material = resource:"materials/dev/dev_measuregeneric01.vmat"
+	m_vOrigin = [ 0.0, -64.0, 128.0 ]
+	m_Children =
+	[
+		{
+			m_Name = "first"
+		},
+	]
+	"1 key" = """
+line
+break
+"""
+}
+`
+	if buf.String() != expected {
+		t.Errorf("unexpected output:\n%s", buf.String())
+	}
+}
+
+func TestWriter_RoundTrip(t *testing.T) {
+	reader := NewReader(strings.NewReader(sampleKV3))
+	doc, err := reader.Read()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	writer := NewWriter(&buf)
+	if err = writer.Write(doc); err != nil {
+		t.Fatal(err)
+	}
+
+	reader = NewReader(&buf)
+	reread, err := reader.Read()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(doc, reread) {
+		t.Error("document changed after writing and reading back")
+	}
+}
+
+func TestWriter_RoundTripCarriageReturn(t *testing.T) {
+	root := NewObject()
+	root.Set("text", NewString("first\r\nsecond\r"))
+
+	var buf bytes.Buffer
+	writer := NewWriter(&buf)
+	if err := writer.Write(NewDocument(root)); err != nil {
+		t.Fatal(err)
+	}
+	reader := NewReader(&buf)
+	doc, err := reader.Read()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if node, _ := doc.Root.Find("text"); node.String != "first\r\nsecond\r" {
+		t.Errorf("unexpected multi-line string: %q", node.String)
+	}
+}
+
+func TestWriter_WriteNil(t *testing.T) {
+	object := NewObject()
+	object.Set("missing", nil)
+	for name, root := range map[string]*Node{
+		"member":       object,
+		"item":         NewArray(NewInt(1), nil),
+		"nested item":  NewArray(NewArray(nil)),
+		"nested array": NewArray(NewObject(), nil),
+	} {
+		var buf bytes.Buffer
+		writer := NewWriter(&buf)
+		if err := writer.Write(NewDocument(root)); err == nil {
+			t.Errorf("expected error for nil %s", name)
+		}
+	}
+}
@@ -0,0 +1,157 @@
+package keyvalues
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"strings"
+)
+
+// maxInternedKeys bounds the keys a lexer interns, so that streams with
+// unique keys don't grow without limit. Most formats use few distinct keys.
+const maxInternedKeys = 4096
+
+var commentBytes = []byte(tokenComment)
+
+// lexer reads a KeyValues stream a line at a time into a reused buffer, so
+// lines are only copied into strings once they are known to be keys or
+// values. The keys it reads are interned, so repeated keys share a string.
+type lexer struct {
+	file *bufio.Reader
+	line []byte
+	keys map[string]string
+}
+
+func newLexer(file io.Reader) lexer {
+	return lexer{
+		file: bufio.NewReader(file),
+		keys: map[string]string{},
+	}
+}
+
+// next returns the next line with comments and padding removed, and tabs
+// replaced by spaces. The line is only valid until the next call.
+// The last line is returned whether or not it ends with a line break.
+func (l *lexer) next() ([]byte, error) {
+	raw, err := l.file.ReadSlice('\n')
+	l.line = append(l.line[:0], raw...)
+	for err == bufio.ErrBufferFull {
+		raw, err = l.file.ReadSlice('\n')
+		l.line = append(l.line, raw...)
+	}
+	if err == io.EOF && len(l.line) > 0 {
+		err = nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	line := l.line
+	if idx := commentIndex(line); idx >= 0 {
+		line = line[:idx]
+	}
+	line = trimDiscard(line)
+	for idx := range line {
+		if line[idx] == tokenTab[0] {
+			line[idx] = tokenSeparator[0]
+		}
+	}
+	return line, nil
+}
+
+// commentIndex returns the index of the first comment in line that isn't
+// inside quotes, such as the // of a url, or -1 if there is none
+func commentIndex(line []byte) int {
+	quoted := false
+	for idx := 0; idx < len(line); idx++ {
+		switch {
+		case line[idx] == tokenEscape[0]:
+			quoted = !quoted
+		case !quoted && bytes.HasPrefix(line[idx:], commentBytes):
+			return idx
+		}
+	}
+	return -1
+}
+
+// intern returns key as a string, shared with every other key that is
+// written the same way
+func (l *lexer) intern(key []byte) string {
+	if s, ok := l.keys[string(key)]; ok {
+		return s
+	}
+	s := string(key)
+	if len(l.keys) < maxInternedKeys {
+		l.keys[s] = s
+	}
+	return s
+}
+
+// splitFields counts the space separated fields of a line as a csv.Reader
+// would, and returns the first with its quotes removed. ok is false for
+// lines that need the csv.Reader to be read the same way, such as escaped
+// quotes or quotes within a field.
+func splitFields(line []byte) (count int, first []byte, ok bool) {
+	idx := 0
+	for {
+		var field []byte
+		if idx < len(line) && line[idx] == tokenEscape[0] {
+			end := bytes.IndexByte(line[idx+1:], tokenEscape[0])
+			if end < 0 {
+				return 0, nil, false
+			}
+			end += idx + 1
+			if end+1 < len(line) && line[end+1] != tokenSeparator[0] {
+				return 0, nil, false
+			}
+			field = line[idx+1 : end]
+			idx = end + 1
+		} else {
+			end := bytes.IndexByte(line[idx:], tokenSeparator[0])
+			if end < 0 {
+				end = len(line)
+			} else {
+				end += idx
+			}
+			field = line[idx:end]
+			if bytes.IndexByte(field, tokenEscape[0]) >= 0 {
+				return 0, nil, false
+			}
+			idx = end
+		}
+
+		count++
+		if count == 1 {
+			first = field
+		}
+		if idx == len(line) {
+			return count, first, true
+		}
+		// skip the separator
+		idx++
+	}
+}
+
+// trimDiscard removes the characters of tokenDiscardCutset from both ends
+// of line
+func trimDiscard(line []byte) []byte {
+	for len(line) > 0 && strings.IndexByte(tokenDiscardCutset, line[0]) >= 0 {
+		line = line[1:]
+	}
+	for len(line) > 0 && strings.IndexByte(tokenDiscardCutset, line[len(line)-1]) >= 0 {
+		line = line[:len(line)-1]
+	}
+	return line
+}
+
+// trimBytes is trim, without converting value to a string
+func trimBytes(value []byte) []byte {
+	value = bytes.TrimSpace(value)
+	for len(value) > 0 && value[0] == tokenEscape[0] {
+		value = value[1:]
+	}
+	for len(value) > 0 && value[len(value)-1] == tokenEscape[0] {
+		value = value[:len(value)-1]
+	}
+	return value
+}
@@ -0,0 +1,100 @@
+package keyvalues
+
+import (
+	"encoding/csv"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestLexer_Next(t *testing.T) {
+	l := newLexer(strings.NewReader("\t\"a\"\t\"b\" // comment\n\r\n  }\nlast"))
+	for _, expected := range []string{`"a" "b"`, "", "}", "last"} {
+		line, err := l.next()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(line) != expected {
+			t.Errorf("expected %q, got %q", expected, line)
+		}
+	}
+	if _, err := l.next(); err != io.EOF {
+		t.Errorf("expected io.EOF, received: %v", err)
+	}
+}
+
+func TestLexer_NextQuotedComment(t *testing.T) {
+	l := newLexer(strings.NewReader("\"url\" \"http://example.com\" // comment\n"))
+	line, err := l.next()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := `"url" "http://example.com"`; string(line) != expected {
+		t.Errorf("expected %q, got %q", expected, line)
+	}
+}
+
+func TestLexer_NextLongLine(t *testing.T) {
+	long := strings.Repeat("k", 10000)
+	l := newLexer(strings.NewReader(long + "\n"))
+	line, err := l.next()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(line) != long {
+		t.Errorf("expected a line of %d bytes, got %d", len(long), len(line))
+	}
+}
+
+func TestLexer_Intern(t *testing.T) {
+	l := newLexer(strings.NewReader(""))
+	a := l.intern([]byte("classname"))
+	b := l.intern([]byte("classname"))
+	if a != "classname" || b != "classname" {
+		t.Errorf("unexpected keys %q and %q", a, b)
+	}
+	if len(l.keys) != 1 {
+		t.Errorf("expected 1 interned key, got %d", len(l.keys))
+	}
+}
+
+func TestSplitFields(t *testing.T) {
+	for _, line := range []string{
+		`key`,
+		`"key"`,
+		`"key" "value"`,
+		`key value`,
+		`"key" "two words"`,
+		`key  value`,
+		`key `,
+		`""`,
+		`"a""b"`,
+		`"a"b`,
+		`a"b`,
+		`"unterminated`,
+	} {
+		r := csv.NewReader(strings.NewReader(line))
+		r.Comma = ' '
+		prop, _ := r.Read()
+
+		count, first, ok := splitFields([]byte(line))
+		if !ok {
+			continue
+		}
+		if count != len(prop) {
+			t.Errorf("%s: expected %d fields, got %d", line, len(prop), count)
+			continue
+		}
+		if string(first) != prop[0] {
+			t.Errorf("%s: expected first field %q, got %q", line, prop[0], first)
+		}
+	}
+}
+
+func TestTrimBytes(t *testing.T) {
+	for _, value := range []string{`"a"`, ` "a b" `, `""a""`, "\t\"a\"\v", `a`, ``} {
+		if actual := string(trimBytes([]byte(value))); actual != trim(value) {
+			t.Errorf("%q: expected %q, got %q", value, trim(value), actual)
+		}
+	}
+}
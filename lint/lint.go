@@ -0,0 +1,232 @@
+// Package lint reports likely problems in KeyValues files: duplicated and
+// case conflicting keys, unbalanced braces, unknown conditionals, empty
+// blocks, inconsistently quoted numbers and missing #base files.
+package lint
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Check identifies a kind of problem
+type Check string
+
+// CheckSyntax reports unterminated strings and conditionals, and keys without a value
+const CheckSyntax = Check("syntax")
+
+// CheckDuplicateKey reports a value key that appears more than once in a
+// block. Block keys, and the outputs in a VMF connections block, are not
+// checked, as they repeat by design.
+const CheckDuplicateKey = Check("duplicate-key")
+
+// CheckKeyCase reports value keys in a block that differ only by case, which Find treats as the same key
+const CheckKeyCase = Check("key-case")
+
+// CheckBraces reports unbalanced braces
+const CheckBraces = Check("braces")
+
+// CheckConditional reports conditionals that the engine doesn't know
+const CheckConditional = Check("conditional")
+
+// CheckEmptyBlock reports blocks without children
+const CheckEmptyBlock = Check("empty-block")
+
+// CheckNumericQuoting reports numeric values quoted differently to the first numeric value in the file
+const CheckNumericQuoting = Check("numeric-quoting")
+
+// CheckBase reports #base and #include files that don't exist
+const CheckBase = Check("base")
+
+// AllChecks lists every check, in the order they are described
+var AllChecks = []Check{
+	CheckSyntax,
+	CheckDuplicateKey,
+	CheckKeyCase,
+	CheckBraces,
+	CheckConditional,
+	CheckEmptyBlock,
+	CheckNumericQuoting,
+	CheckBase,
+}
+
+// repeatedValueBlocks are blocks whose values repeat keys by design, such as
+// the outputs of a VMF entity
+var repeatedValueBlocks = map[string]bool{
+	"connections": true,
+}
+
+// knownConditionals are the platform conditionals the engine evaluates
+var knownConditionals = map[string]bool{
+	"$X360":        true,
+	"$XBOX":        true,
+	"$PS3":         true,
+	"$GAMECONSOLE": true,
+	"$WIN32":       true,
+	"$WINDOWS":     true,
+	"$OSX":         true,
+	"$LINUX":       true,
+	"$POSIX":       true,
+	"$DECK":        true,
+}
+
+// Problem is a single problem found in a file
+type Problem struct {
+	File    string `json:"file"`
+	Line    int    `json:"line"`
+	Column  int    `json:"column"`
+	Check   Check  `json:"check"`
+	Message string `json:"message"`
+}
+
+// String formats the problem as file:line:column: check: message
+func (p Problem) String() string {
+	return p.File + ":" + strconv.Itoa(p.Line) + ":" + strconv.Itoa(p.Column) + ": " + string(p.Check) + ": " + p.Message
+}
+
+// Lint checks src, the contents of filename, and returns the problems found
+// by the given checks, ordered by position.
+// #base files are resolved relative to the directory of filename.
+func Lint(filename string, src []byte, checks []Check) []Problem {
+	enabled := map[Check]bool{}
+	for _, check := range checks {
+		enabled[check] = true
+	}
+
+	p := &parser{scanner: newScanner(src)}
+	root := p.parseBlock(nil)
+
+	l := &linter{filename: filename}
+	l.problems = p.problems
+	l.checkBlock(root, nil)
+
+	var problems []Problem
+	for _, problem := range l.problems {
+		if !enabled[problem.Check] {
+			continue
+		}
+		problem.File = filename
+		problems = append(problems, problem)
+	}
+	sort.SliceStable(problems, func(i, j int) bool {
+		if problems[i].Line != problems[j].Line {
+			return problems[i].Line < problems[j].Line
+		}
+		return problems[i].Column < problems[j].Column
+	})
+	return problems
+}
+
+// linter runs the checks over a parsed file
+type linter struct {
+	filename string
+	problems []Problem
+	// numeric is the first numeric value, that others are compared to
+	numeric *token
+}
+
+func (l *linter) report(tok token, check Check, message string) {
+	l.problems = append(l.problems, Problem{Line: tok.line, Column: tok.column, Check: check, Message: message})
+}
+
+// checkBlock checks the entries of parent, which is nil at the top level
+func (l *linter) checkBlock(entries []*entry, parent *entry) {
+	topLevel := parent == nil
+	repeats := !topLevel && repeatedValueBlocks[strings.ToLower(parent.key.text)]
+	seen := map[string]*entry{}
+	for _, e := range entries {
+		for _, conditional := range e.conditionals {
+			l.checkConditional(conditional)
+		}
+
+		// a file may include any number of others
+		if topLevel && e.value != nil && (strings.EqualFold(e.key.text, "#base") || strings.EqualFold(e.key.text, "#include")) {
+			l.checkBase(*e.value)
+			continue
+		}
+
+		// entries for different platforms are expected to repeat a key, and
+		// blocks such as solid and side repeat by design
+		if !e.block && !repeats && len(e.conditionals) == 0 && e.key.text != "" {
+			lower := strings.ToLower(e.key.text)
+			if previous, ok := seen[lower]; ok {
+				if previous.key.text == e.key.text {
+					l.report(e.key, CheckDuplicateKey, "duplicate key \""+e.key.text+"\", first defined on line "+strconv.Itoa(previous.key.line))
+				} else {
+					l.report(e.key, CheckKeyCase, "key \""+e.key.text+"\" differs only by case from \""+previous.key.text+"\" on line "+strconv.Itoa(previous.key.line))
+				}
+			} else {
+				seen[lower] = e
+			}
+		}
+
+		if e.block {
+			if len(e.children) == 0 {
+				l.report(e.key, CheckEmptyBlock, "empty block \""+e.key.text+"\"")
+			}
+			l.checkBlock(e.children, e)
+			continue
+		}
+		if e.value == nil {
+			continue
+		}
+		l.checkNumeric(*e.value)
+	}
+}
+
+func (l *linter) checkConditional(tok token) {
+	for _, term := range strings.FieldsFunc(tok.text, func(r rune) bool {
+		return r == '|' || r == '&' || r == ' ' || r == '\t'
+	}) {
+		name := strings.TrimPrefix(term, "!")
+		if !knownConditionals[strings.ToUpper(name)] {
+			l.report(tok, CheckConditional, "unknown conditional \""+name+"\"")
+		}
+	}
+}
+
+func (l *linter) checkNumeric(value token) {
+	if !isNumeric(value.text) {
+		return
+	}
+	if l.numeric == nil {
+		l.numeric = &value
+		return
+	}
+	if value.quoted != l.numeric.quoted {
+		style := "unquoted"
+		if l.numeric.quoted {
+			style = "quoted"
+		}
+		l.report(value, CheckNumericQuoting, "numeric value "+value.text+" is quoted differently to the "+style+" value on line "+strconv.Itoa(l.numeric.line))
+	}
+}
+
+func (l *linter) checkBase(value token) {
+	name := filepath.FromSlash(strings.ReplaceAll(value.text, "\\", "/"))
+	if !filepath.IsAbs(name) {
+		name = filepath.Join(filepath.Dir(l.filename), name)
+	}
+	if _, err := os.Stat(name); err != nil {
+		l.report(value, CheckBase, "cannot find \""+value.text+"\"")
+	}
+}
+
+// isNumeric returns whether text is a decimal integer or float
+func isNumeric(text string) bool {
+	text = strings.TrimPrefix(text, "-")
+	digits, dot := 0, false
+	for _, c := range text {
+		switch {
+		case c >= '0' && c <= '9':
+			digits++
+		case c == '.' && !dot:
+			dot = true
+		default:
+			return false
+		}
+	}
+	return digits > 0
+}
@@ -0,0 +1,171 @@
+package lint
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+)
+
+const sample = `"LightmappedGeneric"
+{
+	"$basetexture" "foo"
+	"$BaseTexture" "bar"
+	"$alpha" "0.5"
+	$envmapcontrast 1
+	"$detail" "x" [$PS4]
+	"$detail" "y" [!$X360 && $WIN32]
+	"proxies"
+	{
+	}
+	"$alpha" "1" // again
+}
+}
+`
+
+func TestLint(t *testing.T) {
+	problems := Lint("sample.vmt", []byte(sample), AllChecks)
+
+	expected := []Problem{
+		{File: "sample.vmt", Line: 4, Column: 2, Check: CheckKeyCase},
+		{File: "sample.vmt", Line: 6, Column: 18, Check: CheckNumericQuoting},
+		{File: "sample.vmt", Line: 7, Column: 16, Check: CheckConditional},
+		{File: "sample.vmt", Line: 9, Column: 2, Check: CheckEmptyBlock},
+		{File: "sample.vmt", Line: 12, Column: 2, Check: CheckDuplicateKey},
+		{File: "sample.vmt", Line: 14, Column: 1, Check: CheckBraces},
+	}
+	if len(problems) != len(expected) {
+		t.Fatalf("expected %d problems, got %d: %v", len(expected), len(problems), problems)
+	}
+	for idx, problem := range problems {
+		problem.Message = ""
+		if problem != expected[idx] {
+			t.Errorf("expected %v, got %v", expected[idx], problem)
+		}
+	}
+}
+
+func TestLint_Toggle(t *testing.T) {
+	problems := Lint("sample.vmt", []byte(sample), []Check{CheckEmptyBlock, CheckBraces})
+	if len(problems) != 2 {
+		t.Fatalf("expected 2 problems, got %v", problems)
+	}
+	if problems[0].Check != CheckEmptyBlock || problems[1].Check != CheckBraces {
+		t.Errorf("unexpected problems: %v", problems)
+	}
+	if problems := Lint("sample.vmt", []byte(sample), nil); len(problems) != 0 {
+		t.Errorf("expected no problems without checks, got %v", problems)
+	}
+}
+
+func TestLint_Syntax(t *testing.T) {
+	problems := Lint("a.vdf", []byte("\"root\"\n{\n\t\"key\"\n}\n\"open\"\n{\n\t\"a\" \"b\n"), AllChecks)
+	if len(problems) != 3 {
+		t.Fatalf("expected 3 problems, got %v", problems)
+	}
+	if problems[0].Check != CheckSyntax || problems[0].Line != 3 {
+		t.Errorf("expected key without value on line 3, got %v", problems[0])
+	}
+	if problems[1].Check != CheckBraces || problems[1].Line != 6 {
+		t.Errorf("expected unclosed brace on line 6, got %v", problems[1])
+	}
+	if problems[2].Check != CheckSyntax || problems[2].Line != 7 || problems[2].Column != 6 {
+		t.Errorf("expected unterminated string at 7:6, got %v", problems[2])
+	}
+}
+
+func TestLint_Base(t *testing.T) {
+	dir := t.TempDir()
+	if err := ioutil.WriteFile(filepath.Join(dir, "base.vmt"), []byte("\"a\"\n{\n}\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	src := "#base \"base.vmt\"\n#base \"missing.vmt\"\n\"b\"\n{\n\t\"c\" \"d\"\n}\n"
+
+	problems := Lint(filepath.Join(dir, "file.vmt"), []byte(src), AllChecks)
+	if len(problems) != 1 {
+		t.Fatalf("expected 1 problem, got %v", problems)
+	}
+	if problems[0].Check != CheckBase || problems[0].Line != 2 {
+		t.Errorf("unexpected problem: %v", problems[0])
+	}
+}
+
+func TestProblem_String(t *testing.T) {
+	problem := Problem{File: "a.vmt", Line: 3, Column: 5, Check: CheckEmptyBlock, Message: "empty block \"proxies\""}
+	if problem.String() != "a.vmt:3:5: empty-block: empty block \"proxies\"" {
+		t.Errorf("unexpected string: %s", problem.String())
+	}
+}
+
+const sampleVmf = `versioninfo
+{
+	"editorversion" "400"
+	"formatversion" "100"
+}
+visgroups
+{
+	visgroup
+	{
+		"name" "a"
+		"visgroupid" "1"
+	}
+	visgroup
+	{
+		"name" "b"
+		"visgroupid" "2"
+	}
+}
+world
+{
+	"id" "1"
+	"classname" "worldspawn"
+	solid
+	{
+		"id" "2"
+		side
+		{
+			"id" "1"
+			"material" "TOOLS/TOOLSNODRAW"
+		}
+		side
+		{
+			"id" "2"
+			"material" "TOOLS/TOOLSNODRAW"
+		}
+	}
+	solid
+	{
+		"id" "3"
+		side
+		{
+			"id" "3"
+			"material" "DEV/DEV_MEASUREGENERIC01"
+		}
+	}
+}
+entity
+{
+	"id" "4"
+	"classname" "logic_relay"
+	connections
+	{
+		"OnTrigger" "door,Open,,0,-1"
+		"OnTrigger" "light,TurnOn,,0,-1"
+	}
+}
+entity
+{
+	"id" "5"
+	"classname" "func_door"
+	"targetname" "door"
+	connections
+	{
+		"OnOpen" "relay,Trigger,,0,-1"
+	}
+}
+`
+
+func TestLint_Vmf(t *testing.T) {
+	if problems := Lint("sample.vmf", []byte(sampleVmf), AllChecks); len(problems) != 0 {
+		t.Errorf("expected no problems, got %v", problems)
+	}
+}
@@ -0,0 +1,113 @@
+package lint
+
+// entry is a key with either a value or a block of children
+type entry struct {
+	key          token
+	value        *token
+	block        bool
+	children     []*entry
+	conditionals []token
+}
+
+// parser builds a tree of entries from tokens, recording structural problems
+// rather than stopping at them
+type parser struct {
+	scanner *scanner
+	peeked  *token
+	done    bool
+	// failed is set when the scanner stops at an unterminated token
+	failed   bool
+	problems []Problem
+}
+
+func (p *parser) report(tok token, check Check, message string) {
+	p.problems = append(p.problems, Problem{Line: tok.line, Column: tok.column, Check: check, Message: message})
+}
+
+func (p *parser) peek() (token, bool) {
+	if p.peeked != nil {
+		return *p.peeked, true
+	}
+	if p.done {
+		return token{}, false
+	}
+	tok, ok, problem := p.scanner.next()
+	if problem != nil {
+		p.problems = append(p.problems, *problem)
+		p.failed = true
+	}
+	if !ok {
+		p.done = true
+		return token{}, false
+	}
+	p.peeked = &tok
+	return tok, true
+}
+
+func (p *parser) take() (token, bool) {
+	tok, ok := p.peek()
+	p.peeked = nil
+	return tok, ok
+}
+
+// parseBlock parses entries until the closing brace of the block opened by
+// open, or the end of the file if open is nil
+func (p *parser) parseBlock(open *token) []*entry {
+	var entries []*entry
+	for {
+		tok, ok := p.take()
+		if !ok {
+			if open != nil {
+				p.report(*open, CheckBraces, "unclosed brace")
+			}
+			return entries
+		}
+
+		switch tok.kind {
+		case tokenClose:
+			if open != nil {
+				return entries
+			}
+			p.report(tok, CheckBraces, "unexpected closing brace")
+		case tokenOpen:
+			p.report(tok, CheckSyntax, "block without a key")
+			entries = append(entries, &entry{key: token{line: tok.line, column: tok.column}, block: true, children: p.parseBlock(&tok)})
+		case tokenConditional:
+			if len(entries) == 0 {
+				p.report(tok, CheckSyntax, "conditional without a key")
+				continue
+			}
+			last := entries[len(entries)-1]
+			last.conditionals = append(last.conditionals, tok)
+		default:
+			entries = append(entries, p.parseEntry(tok))
+		}
+	}
+}
+
+// parseEntry parses the value or block that follows key
+func (p *parser) parseEntry(key token) *entry {
+	e := &entry{key: key}
+	for {
+		tok, ok := p.peek()
+		if !ok || tok.kind == tokenClose {
+			if !p.failed {
+				p.report(key, CheckSyntax, "key \""+key.text+"\" has no value")
+			}
+			return e
+		}
+		p.take()
+
+		switch tok.kind {
+		case tokenConditional:
+			e.conditionals = append(e.conditionals, tok)
+		case tokenOpen:
+			e.block = true
+			e.children = p.parseBlock(&tok)
+			return e
+		default:
+			e.value = &tok
+			return e
+		}
+	}
+}
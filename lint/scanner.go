@@ -0,0 +1,122 @@
+package lint
+
+import (
+	"strings"
+)
+
+type tokenKind int
+
+const tokenString = tokenKind(0)
+const tokenOpen = tokenKind(1)
+const tokenClose = tokenKind(2)
+const tokenConditional = tokenKind(3)
+
+// token is a single token of a KeyValues file, with the position it starts at
+type token struct {
+	kind   tokenKind
+	text   string
+	quoted bool
+	line   int
+	column int
+}
+
+// scanner splits KeyValues source into tokens. Unlike keyvalues.Reader it
+// keeps quoting, conditionals and positions, and doesn't depend on line
+// layout, so that problems can be reported where they are. Like the Reader,
+// it treats a backslash as text, so a quoted string ends at the next quote.
+type scanner struct {
+	src    string
+	offset int
+	line   int
+	column int
+}
+
+func newScanner(src []byte) *scanner {
+	return &scanner{
+		src:    strings.ReplaceAll(string(src), "\r\n", "\n"),
+		line:   1,
+		column: 1,
+	}
+}
+
+// next returns the next token, or false at the end of the source.
+// An unterminated quoted string or conditional is returned as a Problem.
+func (s *scanner) next() (token, bool, *Problem) {
+	s.skipSpaceAndComments()
+	if s.offset >= len(s.src) {
+		return token{}, false, nil
+	}
+	tok := token{line: s.line, column: s.column}
+
+	switch c := s.src[s.offset]; c {
+	case '{':
+		tok.kind, tok.text = tokenOpen, "{"
+		s.advance(1)
+	case '}':
+		tok.kind, tok.text = tokenClose, "}"
+		s.advance(1)
+	case '"':
+		tok.quoted = true
+		s.advance(1)
+		var text strings.Builder
+		for {
+			if s.offset >= len(s.src) {
+				return tok, false, &Problem{Line: tok.line, Column: tok.column, Check: CheckSyntax, Message: "unterminated quoted string"}
+			}
+			c := s.src[s.offset]
+			if c == '"' {
+				s.advance(1)
+				break
+			}
+			text.WriteByte(c)
+			s.advance(1)
+		}
+		tok.text = text.String()
+	case '[':
+		end := strings.IndexAny(s.src[s.offset:], "]\n")
+		if end < 0 || s.src[s.offset+end] != ']' {
+			return tok, false, &Problem{Line: tok.line, Column: tok.column, Check: CheckSyntax, Message: "unterminated conditional"}
+		}
+		tok.kind, tok.text = tokenConditional, s.src[s.offset+1:s.offset+end]
+		s.advance(end + 1)
+	default:
+		end := s.offset
+		for end < len(s.src) && !strings.ContainsRune(" \t\r\n\"{}[", rune(s.src[end])) &&
+			!strings.HasPrefix(s.src[end:], "//") {
+			end++
+		}
+		tok.text = s.src[s.offset:end]
+		s.advance(end - s.offset)
+	}
+	return tok, true, nil
+}
+
+func (s *scanner) skipSpaceAndComments() {
+	for s.offset < len(s.src) {
+		switch {
+		case strings.ContainsRune(" \t\r\n", rune(s.src[s.offset])):
+			s.advance(1)
+		case strings.HasPrefix(s.src[s.offset:], "//"):
+			end := strings.IndexByte(s.src[s.offset:], '\n')
+			if end < 0 {
+				end = len(s.src) - s.offset
+			}
+			s.advance(end)
+		default:
+			return
+		}
+	}
+}
+
+// advance moves past n bytes, keeping track of the line and column
+func (s *scanner) advance(n int) {
+	for _, c := range []byte(s.src[s.offset : s.offset+n]) {
+		if c == '\n' {
+			s.line++
+			s.column = 1
+		} else {
+			s.column++
+		}
+	}
+	s.offset += n
+}
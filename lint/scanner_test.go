@@ -0,0 +1,47 @@
+package lint
+
+import (
+	"testing"
+)
+
+func TestScanner(t *testing.T) {
+	s := newScanner([]byte("\"key\" value // comment\r\n{ \"a\\b\" [$WIN32] }"))
+
+	expected := []token{
+		{kind: tokenString, text: "key", quoted: true, line: 1, column: 1},
+		{kind: tokenString, text: "value", line: 1, column: 7},
+		{kind: tokenOpen, text: "{", line: 2, column: 1},
+		{kind: tokenString, text: "a\\b", quoted: true, line: 2, column: 3},
+		{kind: tokenConditional, text: "$WIN32", line: 2, column: 9},
+		{kind: tokenClose, text: "}", line: 2, column: 18},
+	}
+	for _, e := range expected {
+		tok, ok, problem := s.next()
+		if !ok || problem != nil {
+			t.Fatalf("expected %v, got end of input", e)
+		}
+		if tok != e {
+			t.Errorf("expected %v, got %v", e, tok)
+		}
+	}
+	if _, ok, _ := s.next(); ok {
+		t.Error("expected end of input")
+	}
+}
+
+func TestScanner_Backslash(t *testing.T) {
+	s := newScanner([]byte("\"C:\\sdk\\\" \"value\""))
+
+	for _, expected := range []string{"C:\\sdk\\", "value"} {
+		tok, ok, problem := s.next()
+		if !ok || problem != nil {
+			t.Fatalf("expected %q, got end of input", expected)
+		}
+		if tok.text != expected {
+			t.Errorf("expected %q, got %q", expected, tok.text)
+		}
+	}
+	if _, ok, _ := s.next(); ok {
+		t.Error("expected end of input")
+	}
+}
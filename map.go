@@ -0,0 +1,114 @@
+package keyvalues
+
+import "sort"
+
+// MapItem is a single key of an OrderedMap
+type MapItem struct {
+	Key   string
+	Value interface{}
+}
+
+// OrderedMap is a block of KeyValues as a list of keys and values, in the
+// order they appear in the block
+type OrderedMap []MapItem
+
+// Get returns the value of key, and whether the map has it
+func (m OrderedMap) Get(key string) (interface{}, bool) {
+	for _, item := range m {
+		if item.Key == key {
+			return item.Value, true
+		}
+	}
+	return nil, false
+}
+
+// ToMap converts the tree into a map containing this KeyValue, or the
+// children of a `$root` node.
+// Blocks become map[string]interface{}, and the values of a key that appears
+// more than once are collected into a []interface{}. Leaves become int32,
+// float32, int64, uint64 or uint32 for the integer, float, int64, uint64 and
+// ptr types, and string otherwise.
+func (node *KeyValue) ToMap() map[string]interface{} {
+	return unorderedMap(toFriendlyRoot(node, mapLeaf)).(map[string]interface{})
+}
+
+// ToOrderedMap is ToMap, but with blocks as OrderedMaps that keep their key
+// order
+func (node *KeyValue) ToOrderedMap() OrderedMap {
+	return toFriendlyRoot(node, mapLeaf)
+}
+
+// FromMap converts a map into a KeyValue tree.
+// Maps become KeyValues with children, with keys in sorted order; slices
+// become repeated keys. Values may be strings, booleans, nil, or any integer
+// or float type ToMap returns. As with Reader, a map with more than one key
+// is contained in a `$root` node.
+func FromMap(m map[string]interface{}) (*KeyValue, error) {
+	return fromFriendlyRoot(sortedMap(m))
+}
+
+// FromOrderedMap is FromMap, keeping the order of OrderedMaps. Maps and
+// OrderedMaps may be mixed at any depth.
+func FromOrderedMap(m OrderedMap) (*KeyValue, error) {
+	return fromFriendlyRoot(m)
+}
+
+func mapLeaf(node *KeyValue) interface{} {
+	switch node.Type() {
+	case ValueInt:
+		if i, err := node.AsInt(); err == nil {
+			return i
+		}
+	case ValueFloat:
+		if f, err := node.AsFloat(); err == nil {
+			return f
+		}
+	case ValueInt64:
+		if i, err := node.AsInt64(); err == nil {
+			return i
+		}
+	case ValueUint64:
+		if u, err := node.AsUint64(); err == nil {
+			return u
+		}
+	case ValuePtr:
+		if p, err := node.AsPtr(); err == nil {
+			return p
+		}
+	}
+	value, _ := node.Value()
+	return value
+}
+
+// unorderedMap replaces the OrderedMaps of a friendly value with maps
+func unorderedMap(value interface{}) interface{} {
+	switch v := value.(type) {
+	case OrderedMap:
+		m := make(map[string]interface{}, len(v))
+		for _, item := range v {
+			m[item.Key] = unorderedMap(item.Value)
+		}
+		return m
+	case []interface{}:
+		items := make([]interface{}, len(v))
+		for idx, item := range v {
+			items[idx] = unorderedMap(item)
+		}
+		return items
+	}
+	return value
+}
+
+// sortedMap converts a map into an OrderedMap with its keys sorted
+func sortedMap(m map[string]interface{}) OrderedMap {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	ordered := make(OrderedMap, 0, len(keys))
+	for _, key := range keys {
+		ordered = append(ordered, MapItem{Key: key, Value: m[key]})
+	}
+	return ordered
+}
@@ -0,0 +1,91 @@
+package keyvalues
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestKeyValue_ToMap(t *testing.T) {
+	kv := readJSONSample(t)
+	m := kv.ToMap()
+
+	root, ok := m["root"].(map[string]interface{})
+	if !ok {
+		t.Fatalf("unexpected root: %v", m)
+	}
+	if root["name"] != "test" || root["count"] != int32(3) || root["scale"] != float32(0.5) {
+		t.Errorf("unexpected leaves: %v", root)
+	}
+	if !reflect.DeepEqual(root["solid"], []interface{}{int32(1), int32(2)}) {
+		t.Errorf("unexpected repeated key: %v", root["solid"])
+	}
+	if root["steamid"] != uint64(76561197960287930) {
+		t.Errorf("unexpected uint64: %v", root["steamid"])
+	}
+}
+
+func TestKeyValue_ToOrderedMap(t *testing.T) {
+	kv := readJSONSample(t)
+	m := kv.ToOrderedMap()
+
+	value, ok := m.Get("root")
+	if !ok {
+		t.Fatal("missing root")
+	}
+	root := value.(OrderedMap)
+	var keys []string
+	for _, item := range root {
+		keys = append(keys, item.Key)
+	}
+	if strings.Join(keys, ",") != "name,count,scale,solid,child,steamid" {
+		t.Errorf("unexpected key order: %v", keys)
+	}
+}
+
+func TestFromMap(t *testing.T) {
+	kv, err := FromMap(map[string]interface{}{
+		"root": map[string]interface{}{
+			"name":  "test",
+			"count": 3,
+			"scale": float32(0.5),
+			"solid": []interface{}{int32(1), int32(2)},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	writer := NewWriter(&buf)
+	if err = writer.Write(kv); err != nil {
+		t.Fatal(err)
+	}
+	expected := "\"root\"\n{\n\t\"count\" \"3\"\n\t\"name\" \"test\"\n\t\"scale\" \"0.5\"\n\t\"solid\" \"1\"\n\t\"solid\" \"2\"\n}\n"
+	if buf.String() != expected {
+		t.Errorf("unexpected tree:\n%s", buf.String())
+	}
+
+	if _, err = FromMap(map[string]interface{}{"a": struct{}{}}); err == nil {
+		t.Error("expected error for unsupported value")
+	}
+}
+
+func TestFromOrderedMap(t *testing.T) {
+	kv := readJSONSample(t)
+	result, err := FromOrderedMap(kv.ToOrderedMap())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Key() != "root" {
+		t.Fatalf("unexpected root key: %s", result.Key())
+	}
+	children, _ := result.Children()
+	if len(children) != 7 || children[0].Key() != "name" || children[5].Key() != "child" {
+		t.Errorf("unexpected children: %d", len(children))
+	}
+	if scale, _ := result.Find("scale"); scale.Type() != ValueFloat {
+		t.Errorf("unexpected scale type: %s", scale.Type())
+	}
+}
@@ -0,0 +1,211 @@
+package keyvalues
+
+import (
+	"bytes"
+	"encoding/csv"
+	"io"
+)
+
+// EventType identifies what an Event describes
+type EventType int
+
+// EventEnterBlock is a key that opens a block
+const EventEnterBlock = EventType(0)
+
+// EventValue is a key with a value
+const EventValue = EventType(1)
+
+// EventExitBlock closes the most recently opened block
+const EventExitBlock = EventType(2)
+
+// Event is a single step through a KeyValues stream
+type Event struct {
+	Type EventType
+	// Key is the key of the value or block. ExitBlock events have the key of
+	// the block they close.
+	Key string
+	// Value is only set for EventValue
+	Value string
+	// Depth is the number of blocks that the key is inside
+	Depth int
+}
+
+// Parser reads a KeyValues stream as a sequence of events, without building
+// a tree, so that files of any size can be scanned in constant memory.
+// It reads the stream line by line in the same way as Reader, which is built
+// on top of it.
+type Parser struct {
+	lexer lexer
+	// keys holds the key of every open block
+	keys []string
+	// pendingKey is a key without a value, that opens a block if the next
+	// line is a brace
+	pendingKey    string
+	hasPendingKey bool
+	// queue holds the events read but not yet returned, from head onwards
+	queue []Event
+	head  int
+	done  bool
+}
+
+// NewParser returns a new Parser
+func NewParser(file io.Reader) Parser {
+	parser := Parser{}
+	parser.lexer = newLexer(file)
+	return parser
+}
+
+// Next returns the next event, or io.EOF once the stream is finished.
+// Blocks still open at the end of the stream are closed first.
+func (parser *Parser) Next() (Event, error) {
+	for parser.head == len(parser.queue) {
+		parser.queue = parser.queue[:0]
+		parser.head = 0
+		if parser.done {
+			return Event{}, io.EOF
+		}
+		if err := parser.readLine(); err != nil {
+			return Event{}, err
+		}
+	}
+	event := parser.queue[parser.head]
+	parser.head++
+	return event, nil
+}
+
+// Parse calls callback with every event in the stream, in order.
+// It stops at the first error that callback returns, and returns it.
+func (parser *Parser) Parse(callback func(event Event) error) error {
+	for {
+		event, err := parser.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if err = callback(event); err != nil {
+			return err
+		}
+	}
+}
+
+// readLine reads a line, and queues the events it finishes
+func (parser *Parser) readLine() error {
+	line, err := parser.lexer.next()
+	if err == io.EOF {
+		parser.finish()
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	if len(line) == 0 {
+		return nil
+	}
+
+	// New scope, unless the brace is quoted
+	if open := bytes.LastIndexByte(line, tokenEnterScope[0]); open >= 0 && bytes.LastIndexByte(line, tokenEscape[0]) < open {
+		// Scope is opened by the key on the line before
+		// There may be situations where there is no key, so the block has none
+		key := ""
+		if parser.hasPendingKey {
+			key = parser.pendingKey
+			parser.hasPendingKey = false
+		}
+		parser.enterBlock(key)
+		return nil
+	}
+	parser.flushPendingKey()
+
+	// Exit scope
+	if bytes.IndexByte(line, tokenExitScope[0]) >= 0 {
+		if len(parser.keys) == 0 {
+			// closing the root scope ends the stream
+			parser.done = true
+			return nil
+		}
+		parser.exitBlock()
+		return nil
+	}
+
+	// Split the line as CSV to remove the quotes and split in the correct place
+	// Without that, it will split on the first space, not the first unquoted space
+	count, first, ok := splitFields(line)
+	if !ok {
+		// lines splitFields doesn't handle are left to a real csv.Reader
+		r := csv.NewReader(bytes.NewReader(line))
+		r.Comma = rune(tokenSeparator[0])
+		prop, _ := r.Read()
+		count = len(prop)
+		if count == 1 {
+			first = []byte(prop[0])
+		}
+	}
+
+	// Only the key is defined here
+	// This *SHOULD* mean key has children
+	if count == 1 {
+		parser.pendingKey = parser.lexer.intern(trimBytes(first))
+		parser.hasPendingKey = true
+		return nil
+	}
+
+	// Lines with carriage returns may hold more than one keyvalue
+	if bytes.IndexByte(line, '\r') >= 0 {
+		for _, kv := range parseKV(string(line)) {
+			parser.queueValue(kv.key, kv.value)
+		}
+		return nil
+	}
+
+	// The key ends at the first space, even within quotes, as parseKV does
+	key := line
+	if idx := bytes.IndexByte(line, tokenSeparator[0]); idx >= 0 {
+		key = line[:idx]
+	}
+	value := trimBytes(trimBytes(line[len(key):]))
+	parser.queueValue(parser.lexer.intern(trimBytes(key)), string(value))
+	return nil
+}
+
+func (parser *Parser) queueValue(key string, value string) {
+	parser.queue = append(parser.queue, Event{
+		Type:  EventValue,
+		Key:   key,
+		Value: value,
+		Depth: len(parser.keys),
+	})
+}
+
+// flushPendingKey turns a key without a value that wasn't followed by a
+// brace into an empty block
+func (parser *Parser) flushPendingKey() {
+	if !parser.hasPendingKey {
+		return
+	}
+	parser.enterBlock(parser.pendingKey)
+	parser.exitBlock()
+	parser.hasPendingKey = false
+}
+
+func (parser *Parser) enterBlock(key string) {
+	parser.queue = append(parser.queue, Event{Type: EventEnterBlock, Key: key, Depth: len(parser.keys)})
+	parser.keys = append(parser.keys, key)
+}
+
+func (parser *Parser) exitBlock() {
+	key := parser.keys[len(parser.keys)-1]
+	parser.keys = parser.keys[:len(parser.keys)-1]
+	parser.queue = append(parser.queue, Event{Type: EventExitBlock, Key: key, Depth: len(parser.keys)})
+}
+
+// finish closes the pending key and any open blocks at the end of the stream
+func (parser *Parser) finish() {
+	parser.flushPendingKey()
+	for len(parser.keys) > 0 {
+		parser.exitBlock()
+	}
+	parser.done = true
+}
@@ -0,0 +1,74 @@
+package keyvalues
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestParser_Next(t *testing.T) {
+	parser := NewParser(strings.NewReader("\"world\"\n{\n\t\"classname\" \"worldspawn\" // comment\n\t\"solid\"\n\t{\n\t\t\"id\" \"1\"\n\t}\n\t\"empty\"\n}\n\"entity\"\n{\n\t\"classname\"\t\"light\"\n"))
+
+	expected := []Event{
+		{Type: EventEnterBlock, Key: "world", Depth: 0},
+		{Type: EventValue, Key: "classname", Value: "worldspawn", Depth: 1},
+		{Type: EventEnterBlock, Key: "solid", Depth: 1},
+		{Type: EventValue, Key: "id", Value: "1", Depth: 2},
+		{Type: EventExitBlock, Key: "solid", Depth: 1},
+		{Type: EventEnterBlock, Key: "empty", Depth: 1},
+		{Type: EventExitBlock, Key: "empty", Depth: 1},
+		{Type: EventExitBlock, Key: "world", Depth: 0},
+		{Type: EventEnterBlock, Key: "entity", Depth: 0},
+		{Type: EventValue, Key: "classname", Value: "light", Depth: 1},
+		{Type: EventExitBlock, Key: "entity", Depth: 0},
+	}
+	for _, e := range expected {
+		event, err := parser.Next()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if event != e {
+			t.Errorf("expected %+v, got %+v", e, event)
+		}
+	}
+	if _, err := parser.Next(); err == nil {
+		t.Error("expected end of stream")
+	}
+}
+
+func TestParser_Parse(t *testing.T) {
+	parser := NewParser(strings.NewReader("\"entity\"\n{\n\"classname\" \"light\"\n}\n\"entity\"\n{\n\"classname\" \"info_player_start\"\n}\n\"entity\"\n{\n\"classname\" \"prop_static\"\n}\n"))
+
+	var classnames []string
+	stop := errors.New("stop")
+	err := parser.Parse(func(event Event) error {
+		if event.Type == EventValue && event.Depth == 1 && event.Key == "classname" {
+			classnames = append(classnames, event.Value)
+		}
+		if len(classnames) == 2 {
+			return stop
+		}
+		return nil
+	})
+	if err != stop {
+		t.Errorf("expected callback error, got %v", err)
+	}
+	if len(classnames) != 2 || classnames[0] != "light" || classnames[1] != "info_player_start" {
+		t.Errorf("unexpected classnames: %v", classnames)
+	}
+}
+
+func TestParser_RootClose(t *testing.T) {
+	parser := NewParser(strings.NewReader("\"a\" \"1\"\n}\n\"b\" \"2\"\n"))
+
+	var events []Event
+	if err := parser.Parse(func(event Event) error {
+		events = append(events, event)
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 1 || events[0].Key != "a" {
+		t.Errorf("expected the stream to end at the closing brace, got %+v", events)
+	}
+}
@@ -0,0 +1,93 @@
+package keyvalues
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestReader_Read(t *testing.T) {
+	reader := NewReader(strings.NewReader("\"GameInfo\"\n{\n\tgame\t\"Counter-Strike Source\"\n\tplatform\t\t|all_source_engine_paths|platform\n\t\"skyname\" \"skyname\"\n}\n"))
+	kv, err := reader.Read()
+	if err != nil {
+		t.Error(err)
+	}
+	if kv.Key() != "GameInfo" {
+		t.Errorf("unexpected root key: %s", kv.Key())
+	}
+
+	for key, expected := range map[string]string{
+		"game":     "Counter-Strike Source",
+		"platform": "|all_source_engine_paths|platform",
+		"skyname":  "skyname",
+	} {
+		node, err := kv.Find(key)
+		if err != nil {
+			t.Error(err)
+			continue
+		}
+		if actual, _ := node.Value(); actual != expected {
+			t.Errorf("unexpected value for %s. expected %s, received: %s", key, expected, actual)
+		}
+	}
+}
+
+func TestReader_Read_NoTrailingLineBreak(t *testing.T) {
+	reader := NewReader(strings.NewReader("\"a\"\n{\n\t\"b\" \"c\"\n}\n\"d\" \"e\""))
+	kv, err := reader.Read()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if kv.Key() != RootNodeKey {
+		t.Fatalf("expected %s node, got %s", RootNodeKey, kv.Key())
+	}
+	node, err := kv.Find("d")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if value, _ := node.Value(); value != "e" {
+		t.Errorf("unexpected value: %s", value)
+	}
+}
+
+func TestReader_Read_QuotedComment(t *testing.T) {
+	reader := NewReader(strings.NewReader("\"a\"\n{\n\t\"url\" \"http://example.com\" // comment\n\t\"b\" \"c\" // \"quoted\" comment\n}\n"))
+	kv, err := reader.Read()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for key, expected := range map[string]string{
+		"url": "http://example.com",
+		"b":   "c",
+	} {
+		node, err := kv.Find(key)
+		if err != nil {
+			t.Error(err)
+			continue
+		}
+		if actual, _ := node.Value(); actual != expected {
+			t.Errorf("unexpected value for %s. expected %s, received: %s", key, expected, actual)
+		}
+	}
+}
+
+func TestReader_Read_ValueStartsWithKey(t *testing.T) {
+	reader := NewReader(strings.NewReader("\"a\"\n{\n\t\"model\" \"models/model.mdl\"\n\tname name_2\n\t\"same\" \"same\"\n}\n"))
+	kv, err := reader.Read()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for key, expected := range map[string]string{
+		"model": "models/model.mdl",
+		"name":  "name_2",
+		"same":  "same",
+	} {
+		node, err := kv.Find(key)
+		if err != nil {
+			t.Error(err)
+			continue
+		}
+		if actual, _ := node.Value(); actual != expected {
+			t.Errorf("unexpected value for %s. expected %s, received: %s", key, expected, actual)
+		}
+	}
+}
@@ -0,0 +1,103 @@
+package schema
+
+import (
+	"strings"
+
+	"github.com/galaco/KeyValues"
+)
+
+// Infer returns a schema that every sample is valid against, built from the
+// keys seen in each block. Keys are declared in the order they are first
+// seen, and are required if every sample of their block has them, and
+// repeated if any does more than once. A key's type is the one all of its
+// values share, float if they are a mix of integers and floats, and string
+// otherwise. A key that is a block in one sample and a value in another
+// allows both, without declaring the block's children.
+func Infer(samples ...*keyvalues.KeyValue) *Schema {
+	blocks := make([][]*keyvalues.KeyValue, len(samples))
+	for idx, sample := range samples {
+		blocks[idx] = []*keyvalues.KeyValue{sample}
+		if sample.Key() == keyvalues.RootNodeKey && sample.HasChildren() {
+			blocks[idx], _ = sample.Children()
+		}
+	}
+	return inferBlock(blocks)
+}
+
+// inferBlock infers the schema of a block from the children of each sample
+// of it
+func inferBlock(blocks [][]*keyvalues.KeyValue) *Schema {
+	schema := &Schema{}
+	declared := map[string]*Key{}
+	// nodes holds every node seen for each key
+	nodes := map[string][]*keyvalues.KeyValue{}
+	// present counts the samples that have each key
+	present := map[string]int{}
+
+	for _, children := range blocks {
+		counts := map[string]int{}
+		for _, child := range children {
+			lower := strings.ToLower(child.Key())
+			if _, ok := declared[lower]; !ok {
+				declared[lower] = &Key{Name: child.Key()}
+				schema.Keys = append(schema.Keys, declared[lower])
+			}
+			nodes[lower] = append(nodes[lower], child)
+			counts[lower]++
+		}
+		for lower, count := range counts {
+			present[lower]++
+			if count > 1 {
+				declared[lower].MaxCount = Unlimited
+			}
+		}
+	}
+
+	for lower, key := range declared {
+		key.Required = present[lower] == len(blocks)
+		inferType(key, nodes[lower])
+	}
+	return schema
+}
+
+// inferType sets the type of key from the nodes seen for it
+func inferType(key *Key, nodes []*keyvalues.KeyValue) {
+	var blocks [][]*keyvalues.KeyValue
+	var types []keyvalues.ValueType
+	for _, node := range nodes {
+		if node.HasChildren() {
+			children, _ := node.Children()
+			blocks = append(blocks, children)
+			continue
+		}
+		types = append(types, node.Type())
+	}
+
+	switch {
+	case len(types) == 0:
+		key.Children = inferBlock(blocks)
+	case len(blocks) > 0:
+		key.Types = []keyvalues.ValueType{keyvalues.ValueString, keyvalues.ValueArray}
+	default:
+		key.Types = []keyvalues.ValueType{commonType(types)}
+	}
+}
+
+// commonType returns the type that allows every value of types
+func commonType(types []keyvalues.ValueType) keyvalues.ValueType {
+	common := types[0]
+	for _, valueType := range types[1:] {
+		switch {
+		case valueType == common:
+		case isNumber(valueType) && isNumber(common):
+			common = keyvalues.ValueFloat
+		default:
+			return keyvalues.ValueString
+		}
+	}
+	return common
+}
+
+func isNumber(valueType keyvalues.ValueType) bool {
+	return valueType == keyvalues.ValueInt || valueType == keyvalues.ValueFloat
+}
@@ -0,0 +1,96 @@
+package schema
+
+import (
+	"testing"
+
+	"github.com/galaco/KeyValues"
+)
+
+func TestInfer(t *testing.T) {
+	a := readTree(t, `"WeaponData"
+{
+	"printname" "Pistol"
+	"damage" "5"
+	"spread" "1"
+	"bucket" "1"
+	"bucket" "2"
+	"SoundData"
+	{
+		"single_shot" "Weapon_Pistol.Single"
+	}
+}
+`)
+	b := readTree(t, `"WeaponData"
+{
+	"printname" "SMG"
+	"damage" "4"
+	"spread" "0.5"
+	"extra" "1"
+	"SoundData"
+	{
+		"single_shot" "Weapon_SMG1.Single"
+		"reload" "Weapon_SMG1.Reload"
+	}
+}
+`)
+	schema := Infer(a, b)
+
+	if len(schema.Keys) != 1 || schema.Keys[0].Name != "WeaponData" || !schema.Keys[0].Required {
+		t.Fatal("expected a required WeaponData block")
+	}
+	data := schema.Keys[0].Children
+	if data == nil {
+		t.Fatal("expected WeaponData to be a block")
+	}
+
+	expected := []struct {
+		name      string
+		required  bool
+		maxCount  int
+		valueType keyvalues.ValueType
+	}{
+		{"printname", true, 0, keyvalues.ValueString},
+		{"damage", true, 0, keyvalues.ValueInt},
+		{"spread", true, 0, keyvalues.ValueFloat},
+		{"bucket", false, Unlimited, keyvalues.ValueInt},
+		{"SoundData", true, 0, ""},
+		{"extra", false, 0, keyvalues.ValueInt},
+	}
+	if len(data.Keys) != len(expected) {
+		t.Fatalf("expected %d keys, got %d", len(expected), len(data.Keys))
+	}
+	for idx, key := range data.Keys {
+		e := expected[idx]
+		if key.Name != e.name || key.Required != e.required || key.MaxCount != e.maxCount {
+			t.Errorf("unexpected key %s: required %t, max count %d", key.Name, key.Required, key.MaxCount)
+		}
+		if e.valueType != "" && (len(key.Types) != 1 || key.Types[0] != e.valueType) {
+			t.Errorf("%s: expected type %s, got %v", key.Name, e.valueType, key.Types)
+		}
+	}
+
+	sound := data.Keys[4].Children
+	if sound == nil || len(sound.Keys) != 2 || !sound.Keys[0].Required || sound.Keys[1].Required {
+		t.Error("unexpected SoundData schema")
+	}
+
+	for _, sample := range []*keyvalues.KeyValue{a, b} {
+		if violations := schema.Validate(sample); len(violations) != 0 {
+			t.Errorf("expected samples to be valid, got %v", violations)
+		}
+	}
+}
+
+func TestInfer_Mixed(t *testing.T) {
+	schema := Infer(
+		keyvalues.NewKeyValueArray("root", keyvalues.NewKeyValue("a", "1"), keyvalues.NewKeyValue("b", "x")),
+		keyvalues.NewKeyValueArray("root", keyvalues.NewKeyValueArray("a"), keyvalues.NewKeyValue("b", "2")))
+
+	keys := schema.Keys[0].Children.Keys
+	if len(keys[0].Types) != 2 || keys[0].Children != nil {
+		t.Errorf("expected a to allow a value or a block, got %v", keys[0].Types)
+	}
+	if len(keys[1].Types) != 1 || keys[1].Types[0] != keyvalues.ValueString {
+		t.Errorf("expected b to be a string, got %v", keys[1].Types)
+	}
+}
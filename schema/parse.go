@@ -0,0 +1,181 @@
+package schema
+
+import (
+	"errors"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/galaco/KeyValues"
+)
+
+// keySchemaOptions holds the options of a block in a schema file
+const keySchemaOptions = "$schema"
+
+const optionRequired = "required"
+const optionRepeated = "repeated"
+const optionAllowUnknown = "allow_unknown"
+const optionCount = "count="
+const optionRange = "range="
+const optionEnum = "enum="
+
+// Parse reads a schema written as KeyValues, where each key declares a key
+// of the same name. A block declares a block, with its children declared
+// inside it. A value lists the options of the key, separated by spaces:
+//
+//	string, integer, float, ...	an allowed type, as named by ValueType
+//	required			the key must appear
+//	repeated			the key may appear any number of times
+//	count=MIN..MAX			bounds how many times the key appears
+//	range=MIN..MAX			bounds the value as a number
+//	enum=A|B|C			lists the allowed values
+//
+// Either bound of a count or range may be left out. A block's own options
+// are given by a $schema key inside it, which may also use allow_unknown.
+// A $schema key may also be given at the top level, where only allow_unknown
+// applies.
+func Parse(kv *keyvalues.KeyValue) (*Schema, error) {
+	tops := []*keyvalues.KeyValue{kv}
+	if kv.Key() == keyvalues.RootNodeKey && kv.HasChildren() {
+		tops, _ = kv.Children()
+	}
+	schema, _, err := parseBlock(tops)
+	return schema, err
+}
+
+// parseBlock parses the declarations of a block, returning the options of
+// the block itself that were given by its $schema key
+func parseBlock(children []*keyvalues.KeyValue) (*Schema, *Key, error) {
+	schema := &Schema{}
+	options := &Key{}
+	for _, child := range children {
+		if child.Key() == keySchemaOptions {
+			value, err := child.Value()
+			if err != nil {
+				return nil, nil, errors.New(keySchemaOptions + " must be a value")
+			}
+			if err = parseOptions(options, value, &schema.AllowUnknown); err != nil {
+				return nil, nil, err
+			}
+			continue
+		}
+		key, err := parseKey(child)
+		if err != nil {
+			return nil, nil, err
+		}
+		schema.Keys = append(schema.Keys, key)
+	}
+	return schema, options, nil
+}
+
+func parseKey(node *keyvalues.KeyValue) (*Key, error) {
+	if !node.HasChildren() {
+		key := &Key{Name: node.Key()}
+		value, _ := node.Value()
+		var allowUnknown bool
+		if err := parseOptions(key, value, &allowUnknown); err != nil {
+			return nil, err
+		}
+		if allowUnknown {
+			return nil, errors.New(node.Key() + ": " + optionAllowUnknown + " only applies to blocks")
+		}
+		return key, nil
+	}
+
+	children, _ := node.Children()
+	block, key, err := parseBlock(children)
+	if err != nil {
+		return nil, errors.New(node.Key() + "/" + err.Error())
+	}
+	if len(key.Types) > 0 || len(key.Enum) > 0 || key.Range != nil {
+		return nil, errors.New(node.Key() + ": blocks cannot have types, enums or ranges")
+	}
+	key.Name = node.Key()
+	key.Children = block
+	return key, nil
+}
+
+// parseOptions sets the options in value on key
+func parseOptions(key *Key, value string, allowUnknown *bool) error {
+	for _, option := range strings.Fields(value) {
+		var err error
+		switch {
+		case option == optionRequired:
+			key.Required = true
+		case option == optionRepeated:
+			key.MaxCount = Unlimited
+		case option == optionAllowUnknown:
+			*allowUnknown = true
+		case strings.HasPrefix(option, optionCount):
+			err = parseCount(key, strings.TrimPrefix(option, optionCount))
+		case strings.HasPrefix(option, optionRange):
+			key.Range, err = parseRange(strings.TrimPrefix(option, optionRange))
+		case strings.HasPrefix(option, optionEnum):
+			key.Enum = strings.Split(strings.TrimPrefix(option, optionEnum), "|")
+		case isValueType(option):
+			key.Types = append(key.Types, keyvalues.ValueType(option))
+		default:
+			err = errors.New("unknown option " + strconv.Quote(option))
+		}
+		if err != nil {
+			return errors.New(key.Name + ": " + err.Error())
+		}
+	}
+	return nil
+}
+
+func parseCount(key *Key, value string) error {
+	min, max, err := parseBounds(value)
+	if err != nil {
+		return err
+	}
+	if min != "" {
+		if key.MinCount, err = strconv.Atoi(min); err != nil {
+			return errors.New("invalid count " + strconv.Quote(value))
+		}
+	}
+	key.MaxCount = Unlimited
+	if max != "" {
+		if key.MaxCount, err = strconv.Atoi(max); err != nil || key.MaxCount < 1 {
+			return errors.New("invalid count " + strconv.Quote(value))
+		}
+	}
+	return nil
+}
+
+func parseRange(value string) (*Range, error) {
+	min, max, err := parseBounds(value)
+	if err != nil {
+		return nil, err
+	}
+	r := Between(math.Inf(-1), math.Inf(1))
+	if min != "" {
+		if r.Min, err = strconv.ParseFloat(min, 64); err != nil {
+			return nil, errors.New("invalid range " + strconv.Quote(value))
+		}
+	}
+	if max != "" {
+		if r.Max, err = strconv.ParseFloat(max, 64); err != nil {
+			return nil, errors.New("invalid range " + strconv.Quote(value))
+		}
+	}
+	return r, nil
+}
+
+// parseBounds splits MIN..MAX
+func parseBounds(value string) (min string, max string, err error) {
+	idx := strings.Index(value, "..")
+	if idx < 0 {
+		return "", "", errors.New("expected MIN..MAX, got " + strconv.Quote(value))
+	}
+	return value[:idx], value[idx+2:], nil
+}
+
+func isValueType(name string) bool {
+	switch keyvalues.ValueType(name) {
+	case keyvalues.ValueString, keyvalues.ValueInt, keyvalues.ValueFloat, keyvalues.ValuePtr,
+		keyvalues.ValueWString, keyvalues.ValueColor, keyvalues.ValueUint64, keyvalues.ValueInt64:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,81 @@
+package schema
+
+import (
+	"testing"
+
+	"github.com/galaco/KeyValues"
+)
+
+const weaponSchemaText = `"WeaponData"
+{
+	"$schema" "required"
+	"printname" "required"
+	"playermodel" "required"
+	"*model" ""
+	"damage" "integer range=0.."
+	"weight" "integer"
+	"anim_prefix" "enum=pistol|smg2"
+	"bucket" "range=0..5"
+	"clip_size" "float range=..10"
+	"SoundData"
+	{
+		"$schema" "allow_unknown"
+	}
+	"TextureData"
+	{
+		"$schema" "repeated"
+		"weapon" "required"
+	}
+}
+`
+
+func TestParse(t *testing.T) {
+	schema, err := Parse(readTree(t, weaponSchemaText))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	violations := schema.Validate(readTree(t, weaponScript))
+	expected := weaponSchema().Validate(readTree(t, weaponScript))
+	if len(violations) != len(expected) {
+		t.Fatalf("expected %d violations, got %d: %v", len(expected), len(violations), violations)
+	}
+	for idx, violation := range violations {
+		if violation != expected[idx] {
+			t.Errorf("expected %s, got %s", expected[idx].Error(), violation.Error())
+		}
+	}
+}
+
+func TestParse_Count(t *testing.T) {
+	schema, err := Parse(keyvalues.NewKeyValue("a", "count=2..3"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if schema.Keys[0].MinCount != 2 || schema.Keys[0].MaxCount != 3 {
+		t.Errorf("unexpected count %d..%d", schema.Keys[0].MinCount, schema.Keys[0].MaxCount)
+	}
+	if schema, err = Parse(keyvalues.NewKeyValue("a", "count=1..")); err != nil {
+		t.Fatal(err)
+	}
+	if schema.Keys[0].MinCount != 1 || schema.Keys[0].MaxCount != Unlimited {
+		t.Errorf("unexpected count %d..%d", schema.Keys[0].MinCount, schema.Keys[0].MaxCount)
+	}
+}
+
+func TestParse_Errors(t *testing.T) {
+	samples := []*keyvalues.KeyValue{
+		keyvalues.NewKeyValue("a", "optional"),
+		keyvalues.NewKeyValue("a", "count=x..2"),
+		keyvalues.NewKeyValue("a", "count=0..0"),
+		keyvalues.NewKeyValue("a", "range=5"),
+		keyvalues.NewKeyValue("a", "allow_unknown"),
+		keyvalues.NewKeyValueArray("a", keyvalues.NewKeyValue("$schema", "integer")),
+		keyvalues.NewKeyValueArray("a", keyvalues.NewKeyValueArray("$schema")),
+	}
+	for _, sample := range samples {
+		if _, err := Parse(sample); err == nil {
+			t.Errorf("expected error for %s", sample.Key())
+		}
+	}
+}
@@ -0,0 +1,149 @@
+// Package schema validates KeyValue trees against a declared structure:
+// which keys a block has, how often they may appear, and what their values
+// may be.
+//
+// A weapon script schema might look like:
+//
+//	weapon := &schema.Schema{
+//		Keys: []*schema.Key{
+//			{
+//				Name:     "WeaponData",
+//				Required: true,
+//				Children: &schema.Schema{
+//					Keys: []*schema.Key{
+//						{Name: "printname", Required: true},
+//						{Name: "damage", Types: []keyvalues.ValueType{keyvalues.ValueInt}, Range: schema.AtLeast(0)},
+//						{Name: "weight", Types: []keyvalues.ValueType{keyvalues.ValueInt}},
+//						{Name: "SoundData", Children: &schema.Schema{AllowUnknown: true}},
+//					},
+//				},
+//			},
+//		},
+//	}
+//	violations := weapon.Validate(tree)
+package schema
+
+import (
+	"math"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/galaco/KeyValues"
+)
+
+// Unlimited is a Key.MaxCount that allows any number of a key
+const Unlimited = -1
+
+// Schema describes the children of a block, or the top level keys of a file
+type Schema struct {
+	Keys []*Key
+	// AllowUnknown allows keys that match none of Keys
+	AllowUnknown bool
+}
+
+// Key describes a key that may appear in a block
+type Key struct {
+	// Name is matched ignoring case, as Find does. It may use * and ?
+	// wildcards; a key is validated against the first Key it matches.
+	Name string
+	// Required keys must appear at least once
+	Required bool
+	// MinCount and MaxCount bound how many times the key appears.
+	// A MaxCount of 0 allows the key once, and Unlimited any number of times.
+	MinCount int
+	MaxCount int
+	// Types lists the allowed types, or any type if empty. ValueArray
+	// allows a block. As the Reader types values by how they look,
+	// ValueString allows any value, and ValueFloat allows integers too.
+	Types []keyvalues.ValueType
+	// Enum lists the allowed values, if not empty
+	Enum []string
+	// Range bounds the value as a number, if set
+	Range *Range
+	// Children validates the contents of the key, which must be a block,
+	// if set
+	Children *Schema
+}
+
+// Range is an inclusive numeric range
+type Range struct {
+	Min float64
+	Max float64
+}
+
+// Between returns a Range from min to max
+func Between(min float64, max float64) *Range {
+	return &Range{Min: min, Max: max}
+}
+
+// AtLeast returns a Range with no upper bound
+func AtLeast(min float64) *Range {
+	return &Range{Min: min, Max: math.Inf(1)}
+}
+
+// AtMost returns a Range with no lower bound
+func AtMost(max float64) *Range {
+	return &Range{Min: math.Inf(-1), Max: max}
+}
+
+// Contains returns whether value is within the range
+func (r *Range) Contains(value float64) bool {
+	return value >= r.Min && value <= r.Max
+}
+
+// String formats the range as it is written in violations
+func (r *Range) String() string {
+	switch {
+	case math.IsInf(r.Max, 1):
+		return ">= " + formatNumber(r.Min)
+	case math.IsInf(r.Min, -1):
+		return "<= " + formatNumber(r.Max)
+	default:
+		return formatNumber(r.Min) + " to " + formatNumber(r.Max)
+	}
+}
+
+func (key *Key) matches(name string) bool {
+	if !strings.ContainsAny(key.Name, "*?[") {
+		return strings.EqualFold(key.Name, name)
+	}
+	ok, err := path.Match(strings.ToLower(key.Name), strings.ToLower(name))
+	return ok && err == nil
+}
+
+func (key *Key) minCount() int {
+	if key.Required && key.MinCount < 1 {
+		return 1
+	}
+	return key.MinCount
+}
+
+func (key *Key) maxCount() int {
+	if key.MaxCount == 0 {
+		return 1
+	}
+	return key.MaxCount
+}
+
+// allowsType returns whether node's type is one of the allowed Types
+func (key *Key) allowsType(node *keyvalues.KeyValue) bool {
+	if len(key.Types) == 0 {
+		return true
+	}
+	for _, allowed := range key.Types {
+		switch {
+		case allowed == node.Type():
+			return true
+		case allowed == keyvalues.ValueString && !node.HasChildren():
+			return true
+		case allowed == keyvalues.ValueFloat && node.Type() == keyvalues.ValueInt:
+			return true
+		}
+	}
+	return false
+}
+
+func formatNumber(value float64) string {
+	return strconv.FormatFloat(value, 'g', -1, 64)
+}
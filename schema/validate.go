@@ -0,0 +1,161 @@
+package schema
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/galaco/KeyValues"
+)
+
+// Violation is a single way that a tree doesn't match a schema.
+// Path is the /-separated keys from the top of the file to the offending
+// node, with the index of keys that are repeated in their block.
+type Violation struct {
+	Path    string
+	Message string
+}
+
+// Error formats the violation as path: message
+func (v Violation) Error() string {
+	return v.Path + ": " + v.Message
+}
+
+// Validate checks kv against the schema, and returns every violation in
+// the order they appear in the tree. A $root node, as the Reader returns for
+// files with more than one top level key, is validated as the top level keys
+// themselves; any other node as the only top level key.
+func (schema *Schema) Validate(kv *keyvalues.KeyValue) (violations []Violation) {
+	tops := []*keyvalues.KeyValue{kv}
+	if kv.Key() == keyvalues.RootNodeKey && kv.HasChildren() {
+		tops, _ = kv.Children()
+	}
+	schema.validateBlock("", tops, &violations)
+	return violations
+}
+
+func (schema *Schema) validateBlock(blockPath string, children []*keyvalues.KeyValue, violations *[]Violation) {
+	keyCounts := map[string]int{}
+	for _, child := range children {
+		keyCounts[strings.ToLower(child.Key())]++
+	}
+
+	declCounts := make([]int, len(schema.Keys))
+	keySeen := map[string]int{}
+	for _, child := range children {
+		lower := strings.ToLower(child.Key())
+		childPath := joinPath(blockPath, child.Key(), keySeen[lower], keyCounts[lower])
+		keySeen[lower]++
+
+		idx := schema.find(child.Key())
+		if idx < 0 {
+			if !schema.AllowUnknown {
+				*violations = append(*violations, Violation{Path: childPath, Message: "unknown key"})
+			}
+			continue
+		}
+		declCounts[idx]++
+		decl := schema.Keys[idx]
+		if max := decl.maxCount(); max != Unlimited && declCounts[idx] > max {
+			*violations = append(*violations, Violation{Path: childPath, Message: "key appears more than " + times(max)})
+		}
+		decl.validate(childPath, child, violations)
+	}
+
+	for idx, decl := range schema.Keys {
+		if min := decl.minCount(); declCounts[idx] < min {
+			path := blockPath
+			if path == "" {
+				path = "/"
+			}
+			message := "missing required key \"" + decl.Name + "\""
+			if min > 1 {
+				message = "expected \"" + decl.Name + "\" at least " + times(min) + ", found " + strconv.Itoa(declCounts[idx])
+			}
+			*violations = append(*violations, Violation{Path: path, Message: message})
+		}
+	}
+}
+
+// find returns the index of the first Key that matches name, or -1
+func (schema *Schema) find(name string) int {
+	for idx, decl := range schema.Keys {
+		if decl.matches(name) {
+			return idx
+		}
+	}
+	return -1
+}
+
+func (key *Key) validate(nodePath string, node *keyvalues.KeyValue, violations *[]Violation) {
+	report := func(message string) {
+		*violations = append(*violations, Violation{Path: nodePath, Message: message})
+	}
+
+	if !key.allowsType(node) {
+		names := make([]string, len(key.Types))
+		for idx, allowed := range key.Types {
+			names[idx] = typeName(allowed)
+		}
+		report("expected " + strings.Join(names, " or ") + ", found " + typeName(node.Type()))
+		return
+	}
+
+	if node.HasChildren() {
+		if key.Children != nil {
+			children, _ := node.Children()
+			key.Children.validateBlock(nodePath, children, violations)
+		}
+		return
+	}
+	if key.Children != nil {
+		report("expected a block, found " + typeName(node.Type()))
+		return
+	}
+
+	value, _ := node.Value()
+	if len(key.Enum) > 0 && !contains(key.Enum, value) {
+		report("value " + strconv.Quote(value) + " is not one of " + strings.Join(key.Enum, ", "))
+	}
+	if key.Range != nil {
+		number, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+		if err != nil {
+			report("value " + strconv.Quote(value) + " is not a number")
+		} else if !key.Range.Contains(number) {
+			report("value " + value + " is out of range " + key.Range.String())
+		}
+	}
+}
+
+// joinPath returns the path of a child, with its index if the key is repeated
+func joinPath(blockPath string, key string, idx int, count int) string {
+	if count > 1 {
+		key += "[" + strconv.Itoa(idx) + "]"
+	}
+	if blockPath == "" {
+		return key
+	}
+	return blockPath + "/" + key
+}
+
+func typeName(valueType keyvalues.ValueType) string {
+	if valueType == keyvalues.ValueArray {
+		return "block"
+	}
+	return string(valueType)
+}
+
+func times(count int) string {
+	if count == 1 {
+		return "once"
+	}
+	return strconv.Itoa(count) + " times"
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
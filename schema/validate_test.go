@@ -0,0 +1,154 @@
+package schema
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/galaco/KeyValues"
+)
+
+const weaponScript = `"WeaponData"
+{
+	"printname" "#HL2_Pistol"
+	"viewmodel" "models/weapons/v_pistol.mdl"
+	"damage" "-5"
+	"weight" "heavy"
+	"anim_prefix" "pistol"
+	"bucket" "1"
+	"bucket" "2"
+	"clip_size" "18"
+	"SoundData"
+	{
+		"single_shot" "Weapon_Pistol.Single"
+	}
+	"TextureData"
+	{
+		"weapon" "x"
+	}
+	"TextureData"
+	{
+		"crosshair" "y"
+	}
+}
+`
+
+func weaponSchema() *Schema {
+	return &Schema{
+		Keys: []*Key{
+			{
+				Name:     "WeaponData",
+				Required: true,
+				Children: &Schema{
+					Keys: []*Key{
+						{Name: "printname", Required: true},
+						{Name: "playermodel", Required: true},
+						{Name: "*model"},
+						{Name: "damage", Types: []keyvalues.ValueType{keyvalues.ValueInt}, Range: AtLeast(0)},
+						{Name: "weight", Types: []keyvalues.ValueType{keyvalues.ValueInt}},
+						{Name: "anim_prefix", Enum: []string{"pistol", "smg2"}},
+						{Name: "bucket", Range: Between(0, 5)},
+						{Name: "clip_size", Types: []keyvalues.ValueType{keyvalues.ValueFloat}, Range: AtMost(10)},
+						{Name: "SoundData", Children: &Schema{AllowUnknown: true}},
+						{Name: "TextureData", MaxCount: Unlimited, Children: &Schema{
+							Keys: []*Key{{Name: "weapon", Required: true}},
+						}},
+					},
+				},
+			},
+		},
+	}
+}
+
+func readTree(t *testing.T, src string) *keyvalues.KeyValue {
+	reader := keyvalues.NewReader(bytes.NewBufferString(src))
+	tree, err := reader.Read()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &tree
+}
+
+func TestSchema_Validate(t *testing.T) {
+	violations := weaponSchema().Validate(readTree(t, weaponScript))
+
+	expected := []string{
+		"WeaponData/damage: value -5 is out of range >= 0",
+		"WeaponData/weight: expected integer, found string",
+		"WeaponData/bucket[1]: key appears more than once",
+		"WeaponData/clip_size: value 18 is out of range <= 10",
+		"WeaponData/TextureData[1]/crosshair: unknown key",
+		"WeaponData/TextureData[1]: missing required key \"weapon\"",
+		"WeaponData: missing required key \"playermodel\"",
+	}
+	if len(violations) != len(expected) {
+		t.Fatalf("expected %d violations, got %d: %v", len(expected), len(violations), violations)
+	}
+	for idx, violation := range violations {
+		if violation.Error() != expected[idx] {
+			t.Errorf("expected %s, got %s", expected[idx], violation.Error())
+		}
+	}
+}
+
+func TestSchema_Validate_Root(t *testing.T) {
+	schema := &Schema{
+		Keys: []*Key{
+			{Name: "a", Required: true, Enum: []string{"1"}},
+			{Name: "b", MinCount: 2, MaxCount: Unlimited, Types: []keyvalues.ValueType{keyvalues.ValueArray}},
+		},
+	}
+	violations := schema.Validate(readTree(t, "\"a\" \"2\"\n\"b\"\n{\n}\n\"c\" \"3\"\n"))
+
+	expected := []string{
+		"a: value \"2\" is not one of 1",
+		"c: unknown key",
+		"/: expected \"b\" at least 2 times, found 1",
+	}
+	if len(violations) != len(expected) {
+		t.Fatalf("expected %d violations, got %d: %v", len(expected), len(violations), violations)
+	}
+	for idx, violation := range violations {
+		if violation.Error() != expected[idx] {
+			t.Errorf("expected %s, got %s", expected[idx], violation.Error())
+		}
+	}
+}
+
+func TestSchema_Validate_Types(t *testing.T) {
+	schema := &Schema{
+		Keys: []*Key{
+			{Name: "root", Children: &Schema{
+				Keys: []*Key{
+					{Name: "block", Children: &Schema{}},
+					{Name: "leaf", Types: []keyvalues.ValueType{keyvalues.ValueString}},
+					{Name: "number", Range: Between(0, 1)},
+				},
+			}},
+		},
+	}
+	root := keyvalues.NewKeyValueArray("root",
+		keyvalues.NewKeyValue("block", "value"),
+		keyvalues.NewKeyValueArray("leaf"),
+		keyvalues.NewKeyValue("number", "abc"))
+
+	violations := schema.Validate(root)
+	expected := []string{
+		"root/block: expected a block, found string",
+		"root/leaf: expected string, found block",
+		"root/number: value \"abc\" is not a number",
+	}
+	if len(violations) != len(expected) {
+		t.Fatalf("expected %d violations, got %d: %v", len(expected), len(violations), violations)
+	}
+	for idx, violation := range violations {
+		if violation.Error() != expected[idx] {
+			t.Errorf("expected %s, got %s", expected[idx], violation.Error())
+		}
+	}
+
+	if violations = weaponSchema().Validate(keyvalues.NewKeyValueArray("WeaponData",
+		keyvalues.NewKeyValue("printname", "x"),
+		keyvalues.NewKeyValue("playermodel", "y"))); len(violations) != 0 {
+		t.Errorf("expected no violations, got %v", violations)
+	}
+}
@@ -0,0 +1,217 @@
+package steam
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/galaco/KeyValues"
+)
+
+// Known appinfo.vdf format versions, identified by the file's magic number
+const (
+	AppInfoVersion27 = uint32(0x07564427)
+	AppInfoVersion28 = uint32(0x07564428)
+	AppInfoVersion29 = uint32(0x07564429)
+)
+
+// appInfoHeaderSize is the size of the fields between an app's size and its
+// KeyValues data, in every format version before binary data hashes were added
+const appInfoHeaderSize = 4 + 4 + 8 + 20 + 4
+
+// AppInfoReader iterates the apps of a Steam appinfo.vdf
+// Apps are read one at a time, and their KeyValues are only parsed when asked
+// for, so that the whole file never needs to be in memory.
+type AppInfoReader struct {
+	file     io.Reader
+	version  uint32
+	universe uint32
+	keyTable []string
+	done     bool
+}
+
+// AppInfo is a single app's entry in appinfo.vdf
+type AppInfo struct {
+	AppID        uint32
+	InfoState    uint32
+	LastUpdated  time.Time
+	PICSToken    uint64
+	SHA1         [20]byte
+	ChangeNumber uint32
+	// BinarySHA1 is the hash of the app's KeyValues data. It is only present
+	// from AppInfoVersion28.
+	BinarySHA1 [20]byte
+
+	data     []byte
+	keyTable []string
+}
+
+// NewAppInfoReader reads the header of an appinfo.vdf, and returns a reader
+// for its apps.
+// From AppInfoVersion29, keys are stored in a string table at the end of the
+// file, so file must also be an io.Seeker for those versions.
+func NewAppInfoReader(file io.Reader) (*AppInfoReader, error) {
+	reader := &AppInfoReader{}
+
+	var header [8]byte
+	if _, err := io.ReadFull(file, header[:]); err != nil {
+		return nil, err
+	}
+	reader.version = binary.LittleEndian.Uint32(header[:4])
+	reader.universe = binary.LittleEndian.Uint32(header[4:])
+
+	switch reader.version {
+	case AppInfoVersion27, AppInfoVersion28:
+	case AppInfoVersion29:
+		keyTable, err := readAppInfoKeyTable(file)
+		if err != nil {
+			return nil, err
+		}
+		reader.keyTable = keyTable
+	default:
+		return nil, errors.New("unknown appinfo version: 0x" + strconv.FormatUint(uint64(reader.version), 16))
+	}
+
+	reader.file = bufio.NewReader(file)
+	return reader, nil
+}
+
+// Version returns the format version of the file, identified by its magic number
+func (reader *AppInfoReader) Version() uint32 {
+	return reader.version
+}
+
+// Universe returns the Steam universe the file was written for
+func (reader *AppInfoReader) Universe() uint32 {
+	return reader.universe
+}
+
+// Next returns the next app in the file, or io.EOF after the last app
+func (reader *AppInfoReader) Next() (*AppInfo, error) {
+	if reader.done {
+		return nil, io.EOF
+	}
+
+	var appID uint32
+	if err := binary.Read(reader.file, binary.LittleEndian, &appID); err != nil {
+		return nil, unexpectedEOF(err)
+	}
+	// an app id of 0 marks the end of the apps
+	if appID == 0 {
+		reader.done = true
+		return nil, io.EOF
+	}
+
+	var size uint32
+	if err := binary.Read(reader.file, binary.LittleEndian, &size); err != nil {
+		return nil, unexpectedEOF(err)
+	}
+	// the size isn't trusted to allocate with, so that a corrupt file fails
+	// at its end rather than asking for more memory than it holds
+	var buf bytes.Buffer
+	if _, err := io.CopyN(&buf, reader.file, int64(size)); err != nil {
+		return nil, unexpectedEOF(err)
+	}
+	entry := buf.Bytes()
+
+	headerSize := appInfoHeaderSize
+	if reader.version >= AppInfoVersion28 {
+		headerSize += 20
+	}
+	if len(entry) < headerSize {
+		return nil, errors.New("appinfo entry too small for app " + strconv.Itoa(int(appID)))
+	}
+
+	app := &AppInfo{
+		AppID:        appID,
+		InfoState:    binary.LittleEndian.Uint32(entry[0:4]),
+		LastUpdated:  time.Unix(int64(binary.LittleEndian.Uint32(entry[4:8])), 0).UTC(),
+		PICSToken:    binary.LittleEndian.Uint64(entry[8:16]),
+		ChangeNumber: binary.LittleEndian.Uint32(entry[36:40]),
+		data:         entry[headerSize:],
+		keyTable:     reader.keyTable,
+	}
+	copy(app.SHA1[:], entry[16:36])
+	if reader.version >= AppInfoVersion28 {
+		copy(app.BinarySHA1[:], entry[40:60])
+	}
+
+	return app, nil
+}
+
+// KeyValue parses the app's data into a KeyValue tree
+func (app *AppInfo) KeyValue() (*keyvalues.KeyValue, error) {
+	var reader keyvalues.BinaryReader
+	if app.keyTable != nil {
+		reader = keyvalues.NewBinaryReaderWithKeyTable(bytes.NewReader(app.data), app.keyTable)
+	} else {
+		reader = keyvalues.NewBinaryReader(bytes.NewReader(app.data))
+	}
+	kv, err := reader.Read()
+	if err != nil {
+		return nil, err
+	}
+	return &kv, nil
+}
+
+// readAppInfoKeyTable reads the string table that follows the apps, and
+// returns to the first app
+func readAppInfoKeyTable(file io.Reader) ([]string, error) {
+	seeker, ok := file.(io.Seeker)
+	if !ok {
+		return nil, errors.New("appinfo version 29 requires a seekable stream")
+	}
+
+	var offset int64
+	if err := binary.Read(file, binary.LittleEndian, &offset); err != nil {
+		return nil, unexpectedEOF(err)
+	}
+	start, err := seeker.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return nil, err
+	}
+	end, err := seeker.Seek(0, io.SeekEnd)
+	if err != nil {
+		return nil, err
+	}
+	if offset < start || offset > end-4 {
+		return nil, errors.New("appinfo key table offset is outside the file")
+	}
+	if _, err = seeker.Seek(offset, io.SeekStart); err != nil {
+		return nil, err
+	}
+
+	stream := bufio.NewReader(file)
+	var count uint32
+	if err = binary.Read(stream, binary.LittleEndian, &count); err != nil {
+		return nil, unexpectedEOF(err)
+	}
+	// every key takes at least its terminating byte
+	if int64(count) > end-offset-4 {
+		return nil, errors.New("appinfo key table has more keys than the file holds")
+	}
+	keyTable := make([]string, 0, count)
+	for idx := uint32(0); idx < count; idx++ {
+		key, err := stream.ReadString(0)
+		if err != nil {
+			return nil, unexpectedEOF(err)
+		}
+		keyTable = append(keyTable, key[:len(key)-1])
+	}
+
+	if _, err = seeker.Seek(start, io.SeekStart); err != nil {
+		return nil, err
+	}
+	return keyTable, nil
+}
+
+func unexpectedEOF(err error) error {
+	if err == io.EOF {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
@@ -0,0 +1,161 @@
+package steam
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"testing"
+
+	"github.com/galaco/KeyValues"
+)
+
+// appInfoEntry builds a single app entry for a version 28 or later file
+func appInfoEntry(appID uint32, changeNumber uint32, kv []byte) []byte {
+	var entry bytes.Buffer
+	binary.Write(&entry, binary.LittleEndian, appID)
+	binary.Write(&entry, binary.LittleEndian, uint32(appInfoHeaderSize+20+len(kv)))
+	binary.Write(&entry, binary.LittleEndian, uint32(2))          // info state
+	binary.Write(&entry, binary.LittleEndian, uint32(1600000000)) // last updated
+	binary.Write(&entry, binary.LittleEndian, uint64(0))          // pics token
+	entry.Write(make([]byte, 20))                                 // sha1
+	binary.Write(&entry, binary.LittleEndian, changeNumber)
+	entry.Write(make([]byte, 20)) // binary sha1
+	entry.Write(kv)
+	return entry.Bytes()
+}
+
+func TestAppInfoReader_Version28(t *testing.T) {
+	var kv bytes.Buffer
+	writer := keyvalues.NewBinaryWriter(&kv)
+	writer.Write(keyvalues.NewKeyValueArray("appinfo",
+		keyvalues.NewKeyValue("appid", "240"),
+		keyvalues.NewKeyValueArray("common",
+			keyvalues.NewKeyValue("name", "Counter-Strike: Source"))))
+
+	var file bytes.Buffer
+	binary.Write(&file, binary.LittleEndian, AppInfoVersion28)
+	binary.Write(&file, binary.LittleEndian, uint32(1))
+	file.Write(appInfoEntry(240, 12345, kv.Bytes()))
+	file.Write(appInfoEntry(440, 67890, kv.Bytes()))
+	binary.Write(&file, binary.LittleEndian, uint32(0))
+
+	reader, err := NewAppInfoReader(&file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reader.Version() != AppInfoVersion28 || reader.Universe() != 1 {
+		t.Error("unexpected header")
+	}
+
+	app, err := reader.Next()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if app.AppID != 240 || app.ChangeNumber != 12345 || app.LastUpdated.Unix() != 1600000000 {
+		t.Errorf("unexpected app: %+v", app)
+	}
+	root, err := app.KeyValue()
+	if err != nil {
+		t.Fatal(err)
+	}
+	common, _ := root.Find("common")
+	name, _ := common.Find("name")
+	if val, _ := name.AsString(); val != "Counter-Strike: Source" {
+		t.Errorf("unexpected app name: %s", val)
+	}
+
+	if app, err = reader.Next(); err != nil || app.AppID != 440 {
+		t.Error("second app not read")
+	}
+	if _, err = reader.Next(); err != io.EOF {
+		t.Errorf("expected io.EOF, received: %v", err)
+	}
+}
+
+func TestAppInfoReader_Version29(t *testing.T) {
+	// keys are indices into the string table: 0 appinfo, 1 appid, 2 common, 3 name
+	kv := []byte("\x00\x00\x00\x00\x00" +
+		"\x02\x01\x00\x00\x00\xf0\x00\x00\x00" +
+		"\x00\x02\x00\x00\x00" +
+		"\x01\x03\x00\x00\x00Counter-Strike: Source\x00" +
+		"\x08\x08\x08")
+
+	var apps bytes.Buffer
+	apps.Write(appInfoEntry(240, 1, kv))
+	binary.Write(&apps, binary.LittleEndian, uint32(0))
+
+	var file bytes.Buffer
+	binary.Write(&file, binary.LittleEndian, AppInfoVersion29)
+	binary.Write(&file, binary.LittleEndian, uint32(1))
+	binary.Write(&file, binary.LittleEndian, int64(16+apps.Len()))
+	file.Write(apps.Bytes())
+	binary.Write(&file, binary.LittleEndian, uint32(4))
+	file.WriteString("appinfo\x00appid\x00common\x00name\x00")
+
+	reader, err := NewAppInfoReader(bytes.NewReader(file.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	app, err := reader.Next()
+	if err != nil {
+		t.Fatal(err)
+	}
+	root, err := app.KeyValue()
+	if err != nil {
+		t.Fatal(err)
+	}
+	appID, _ := root.Find("appid")
+	if val, _ := appID.AsInt(); val != 240 {
+		t.Errorf("unexpected appid: %d", val)
+	}
+	if _, err = reader.Next(); err != io.EOF {
+		t.Errorf("expected io.EOF, received: %v", err)
+	}
+}
+
+func TestNewAppInfoReader_UnknownVersion(t *testing.T) {
+	if _, err := NewAppInfoReader(bytes.NewReader(make([]byte, 8))); err == nil {
+		t.Error("expected error, but received none")
+	}
+}
+
+func TestAppInfoReader_TruncatedEntry(t *testing.T) {
+	var file bytes.Buffer
+	binary.Write(&file, binary.LittleEndian, AppInfoVersion28)
+	binary.Write(&file, binary.LittleEndian, uint32(1))
+	binary.Write(&file, binary.LittleEndian, uint32(240))
+	// an entry that claims to be 4GB long
+	binary.Write(&file, binary.LittleEndian, uint32(0xffffffff))
+	file.Write(make([]byte, 64))
+
+	reader, err := NewAppInfoReader(&file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = reader.Next(); err != io.ErrUnexpectedEOF {
+		t.Errorf("expected io.ErrUnexpectedEOF, received: %v", err)
+	}
+}
+
+func TestNewAppInfoReader_CorruptKeyTable(t *testing.T) {
+	header := func(offset int64, count uint32) []byte {
+		var file bytes.Buffer
+		binary.Write(&file, binary.LittleEndian, AppInfoVersion29)
+		binary.Write(&file, binary.LittleEndian, uint32(1))
+		binary.Write(&file, binary.LittleEndian, offset)
+		binary.Write(&file, binary.LittleEndian, uint32(0))
+		binary.Write(&file, binary.LittleEndian, count)
+		file.WriteString("appinfo\x00")
+		return file.Bytes()
+	}
+
+	if _, err := NewAppInfoReader(bytes.NewReader(header(20, 0xffffffff))); err == nil {
+		t.Error("expected error for a key count larger than the file, but received none")
+	}
+	if _, err := NewAppInfoReader(bytes.NewReader(header(1<<40, 1))); err == nil {
+		t.Error("expected error for a key table offset outside the file, but received none")
+	}
+	if _, err := NewAppInfoReader(bytes.NewReader(header(20, 1))); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,328 @@
+package steam
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/galaco/KeyValues"
+)
+
+const keyLibraryFolders = "libraryfolders"
+const keyAppState = "appstate"
+const dirSteamApps = "steamapps"
+const dirCommon = "common"
+const fileLibraryFolders = "libraryfolders.vdf"
+const appManifestPrefix = "appmanifest_"
+const appManifestExtension = ".acf"
+
+// LibraryFolder is a single Steam library, that games can be installed into
+type LibraryFolder struct {
+	Path  string
+	Label string
+	// Apps maps the id of every app installed in this library to its size on
+	// disk. Legacy libraryfolders.vdf files don't list apps, so this is empty
+	// for them; use AppManifests instead.
+	Apps map[int]uint64
+}
+
+// AppState is an installed app's appmanifest_<appid>.acf
+type AppState struct {
+	AppID           int
+	Universe        int
+	Name            string
+	StateFlags      int
+	InstallDir      string
+	LastUpdated     time.Time
+	SizeOnDisk      uint64
+	BuildID         int
+	LastOwner       uint64
+	InstalledDepots []Depot
+	UserConfig      map[string]string
+	Unknown         []*keyvalues.KeyValue
+}
+
+// Depot is a single depot installed for an app
+type Depot struct {
+	ID       int
+	Manifest string
+	Size     uint64
+}
+
+// ReadLibraryFolders parses a libraryfolders.vdf.
+// Both the legacy format, where every library is a numbered key with a path
+// as its value, and the current format, where every library is a numbered
+// block with a path and its installed apps, are supported. The legacy
+// format doesn't list the Steam installation itself, so steamRoot is
+// returned as its first library; it is ignored for the current format.
+func ReadLibraryFolders(file io.Reader, steamRoot string) ([]LibraryFolder, error) {
+	reader := keyvalues.NewReader(file)
+	root, err := reader.Read()
+	if err != nil {
+		return nil, err
+	}
+	if !strings.EqualFold(root.Key(), keyLibraryFolders) {
+		return nil, errors.New("libraryfolders key not found")
+	}
+
+	var folders []LibraryFolder
+	children, _ := root.Children()
+	legacy := true
+	for _, child := range children {
+		// libraries are numbered; other keys are statistics
+		if _, err := strconv.Atoi(child.Key()); err != nil {
+			continue
+		}
+		if !child.HasChildren() {
+			value, _ := child.Value()
+			folders = append(folders, LibraryFolder{Path: unescape(value)})
+			continue
+		}
+		legacy = false
+		folder, err := readLibraryFolder(child)
+		if err != nil {
+			return nil, err
+		}
+		folders = append(folders, *folder)
+	}
+
+	if legacy && len(steamRoot) > 0 {
+		folders = append([]LibraryFolder{{Path: steamRoot}}, folders...)
+	}
+	return folders, nil
+}
+
+func readLibraryFolder(block *keyvalues.KeyValue) (*LibraryFolder, error) {
+	folder := &LibraryFolder{Apps: map[int]uint64{}}
+	children, _ := block.Children()
+	for _, child := range children {
+		switch strings.ToLower(child.Key()) {
+		case "path":
+			value, _ := child.Value()
+			folder.Path = unescape(value)
+		case "label":
+			value, _ := child.Value()
+			folder.Label = unescape(value)
+		case "apps":
+			apps, _ := child.Children()
+			for _, app := range apps {
+				appID, err := strconv.Atoi(app.Key())
+				if err != nil {
+					return nil, errors.New("invalid app id in library: " + app.Key())
+				}
+				value, _ := app.Value()
+				size, err := strconv.ParseUint(value, 10, 64)
+				if err != nil {
+					return nil, errors.New("invalid size for app " + app.Key() + ": " + value)
+				}
+				folder.Apps[appID] = size
+			}
+		}
+	}
+	if len(folder.Path) == 0 {
+		return nil, errors.New("library " + block.Key() + " has no path")
+	}
+	return folder, nil
+}
+
+// FindLibraryFolders reads the libraries of the Steam installation at steamRoot
+func FindLibraryFolders(steamRoot string) ([]LibraryFolder, error) {
+	candidates := []string{
+		filepath.Join(steamRoot, dirSteamApps, fileLibraryFolders),
+		filepath.Join(steamRoot, "config", fileLibraryFolders),
+	}
+	for _, candidate := range candidates {
+		file, err := os.Open(candidate)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		defer file.Close()
+		return ReadLibraryFolders(file, steamRoot)
+	}
+	// an installation with only the default library may not have the file
+	return []LibraryFolder{{Path: steamRoot}}, nil
+}
+
+// AppManifests reads every appmanifest_<appid>.acf in the library, ordered
+// by app id
+func (folder *LibraryFolder) AppManifests() ([]AppState, error) {
+	matches, err := filepath.Glob(filepath.Join(folder.Path, dirSteamApps, appManifestPrefix+"*"+appManifestExtension))
+	if err != nil {
+		return nil, err
+	}
+	var apps []AppState
+	for _, match := range matches {
+		app, err := readAppManifestFile(match)
+		if err != nil {
+			return nil, err
+		}
+		apps = append(apps, *app)
+	}
+	sort.Slice(apps, func(i, j int) bool {
+		return apps[i].AppID < apps[j].AppID
+	})
+	return apps, nil
+}
+
+// AppManifest reads the manifest of a single app in the library
+func (folder *LibraryFolder) AppManifest(appID int) (*AppState, error) {
+	return readAppManifestFile(filepath.Join(folder.Path, dirSteamApps, appManifestPrefix+strconv.Itoa(appID)+appManifestExtension))
+}
+
+// InstallPath returns the directory an app in this library is installed to
+func (folder *LibraryFolder) InstallPath(app *AppState) string {
+	return filepath.Join(folder.Path, dirSteamApps, dirCommon, app.InstallDir)
+}
+
+// FindApp searches every library of the Steam installation at steamRoot for
+// an installed app, and returns its manifest and the library it is in
+func FindApp(steamRoot string, appID int) (*AppState, *LibraryFolder, error) {
+	folders, err := FindLibraryFolders(steamRoot)
+	if err != nil {
+		return nil, nil, err
+	}
+	for idx := range folders {
+		folder := &folders[idx]
+		if folder.Apps != nil {
+			if _, ok := folder.Apps[appID]; !ok {
+				continue
+			}
+		}
+		app, err := folder.AppManifest(appID)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+		return app, folder, nil
+	}
+	return nil, nil, errors.New("app " + strconv.Itoa(appID) + " is not installed")
+}
+
+// ReadAppManifest parses an appmanifest_<appid>.acf
+func ReadAppManifest(file io.Reader) (*AppState, error) {
+	reader := keyvalues.NewReader(file)
+	root, err := reader.Read()
+	if err != nil {
+		return nil, err
+	}
+	if !strings.EqualFold(root.Key(), keyAppState) || !root.HasChildren() {
+		return nil, errors.New("appstate key not found")
+	}
+
+	app := &AppState{UserConfig: map[string]string{}}
+	children, _ := root.Children()
+	for _, child := range children {
+		if child.HasChildren() {
+			switch strings.ToLower(child.Key()) {
+			case "installeddepots":
+				if err = app.readDepots(child); err != nil {
+					return nil, err
+				}
+			case "userconfig":
+				config, _ := child.Children()
+				for _, entry := range config {
+					value, _ := entry.Value()
+					app.UserConfig[entry.Key()] = unescape(value)
+				}
+			default:
+				app.Unknown = append(app.Unknown, child)
+			}
+			continue
+		}
+
+		value, _ := child.Value()
+		switch strings.ToLower(child.Key()) {
+		case "appid":
+			app.AppID, err = strconv.Atoi(value)
+		case "universe":
+			app.Universe, err = strconv.Atoi(value)
+		case "name":
+			app.Name = unescape(value)
+		case "stateflags":
+			app.StateFlags, err = strconv.Atoi(value)
+		case "installdir":
+			app.InstallDir = unescape(value)
+		case "lastupdated":
+			var seconds int64
+			seconds, err = strconv.ParseInt(value, 10, 64)
+			app.LastUpdated = time.Unix(seconds, 0).UTC()
+		case "sizeondisk":
+			app.SizeOnDisk, err = strconv.ParseUint(value, 10, 64)
+		case "buildid":
+			app.BuildID, err = strconv.Atoi(value)
+		case "lastowner":
+			app.LastOwner, err = strconv.ParseUint(value, 10, 64)
+		default:
+			app.Unknown = append(app.Unknown, child)
+		}
+		if err != nil {
+			return nil, errors.New("invalid value for key " + child.Key() + ": " + value)
+		}
+	}
+	return app, nil
+}
+
+func (app *AppState) readDepots(block *keyvalues.KeyValue) error {
+	depots, _ := block.Children()
+	for _, depotNode := range depots {
+		id, err := strconv.Atoi(depotNode.Key())
+		if err != nil {
+			return errors.New("invalid depot id: " + depotNode.Key())
+		}
+		depot := Depot{ID: id}
+		if manifest, err := depotNode.Find("manifest"); err == nil {
+			depot.Manifest, _ = manifest.Value()
+		}
+		if size, err := depotNode.Find("size"); err == nil {
+			value, _ := size.Value()
+			if depot.Size, err = strconv.ParseUint(value, 10, 64); err != nil {
+				return errors.New("invalid size for depot " + depotNode.Key() + ": " + value)
+			}
+		}
+		app.InstalledDepots = append(app.InstalledDepots, depot)
+	}
+	return nil
+}
+
+func readAppManifestFile(path string) (*AppState, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return ReadAppManifest(file)
+}
+
+// unescape resolves the escape sequences Steam writes in its text files
+func unescape(value string) string {
+	if !strings.Contains(value, "\\") {
+		return value
+	}
+	var builder strings.Builder
+	for idx := 0; idx < len(value); idx++ {
+		if value[idx] != '\\' || idx == len(value)-1 {
+			builder.WriteByte(value[idx])
+			continue
+		}
+		idx++
+		switch value[idx] {
+		case 'n':
+			builder.WriteByte('\n')
+		case 't':
+			builder.WriteByte('\t')
+		default:
+			builder.WriteByte(value[idx])
+		}
+	}
+	return builder.String()
+}
@@ -0,0 +1,167 @@
+package steam
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+const sampleLegacyLibraryFolders = `"LibraryFolders"
+{
+	"TimeNextStatsReport"		"1561832478"
+	"ContentStatsID"		"-158337411110787451"
+	"1"		"D:\\Games\\Steam Library"
+}
+`
+
+const sampleLibraryFolders = `"libraryfolders"
+{
+	"contentstatsid"		"-158337411110787451"
+	"0"
+	{
+		"path"		"C:\\Program Files (x86)\\Steam"
+		"label"		""
+		"contentid"		"4261947365316946327"
+		"totalsize"		"0"
+		"apps"
+		{
+			"228980"		"427480064"
+		}
+	}
+	"1"
+	{
+		"path"		"D:\\Games\\Steam Library"
+		"label"		"games"
+		"apps"
+		{
+			"240"		"4553438372"
+			"243750"		"7410892581"
+		}
+	}
+}
+`
+
+const sampleAppManifest = `"AppState"
+{
+	"appid"		"243750"
+	"Universe"		"1"
+	"name"		"Source SDK Base 2013 Multiplayer"
+	"StateFlags"		"4"
+	"installdir"		"Source SDK Base 2013 Multiplayer"
+	"LastUpdated"		"1698154231"
+	"SizeOnDisk"		"7410892581"
+	"buildid"		"12345678"
+	"LastOwner"		"76561197960287930"
+	"AutoUpdateBehavior"		"0"
+	"InstalledDepots"
+	{
+		"243751"
+		{
+			"manifest"		"6843164329856237193"
+			"size"		"7410892581"
+		}
+	}
+	"UserConfig"
+	{
+		"language"		"english"
+	}
+}
+`
+
+func TestReadLibraryFolders_Legacy(t *testing.T) {
+	folders, err := ReadLibraryFolders(strings.NewReader(sampleLegacyLibraryFolders), "C:\\Steam")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(folders) != 2 {
+		t.Fatalf("unexpected number of libraries: %d", len(folders))
+	}
+	if folders[0].Path != "C:\\Steam" {
+		t.Errorf("unexpected steam root library: %s", folders[0].Path)
+	}
+	if folders[1].Path != "D:\\Games\\Steam Library" {
+		t.Errorf("unexpected library path: %s", folders[1].Path)
+	}
+	if folders[1].Apps != nil {
+		t.Error("legacy library should not list apps")
+	}
+}
+
+func TestReadLibraryFolders(t *testing.T) {
+	folders, err := ReadLibraryFolders(strings.NewReader(sampleLibraryFolders), "C:\\Steam")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(folders) != 2 {
+		t.Fatalf("unexpected number of libraries: %d", len(folders))
+	}
+	if folders[0].Path != "C:\\Program Files (x86)\\Steam" {
+		t.Errorf("unexpected library path: %s", folders[0].Path)
+	}
+	if folders[1].Label != "games" {
+		t.Errorf("unexpected library label: %s", folders[1].Label)
+	}
+	if len(folders[1].Apps) != 2 || folders[1].Apps[243750] != 7410892581 {
+		t.Errorf("unexpected library apps: %v", folders[1].Apps)
+	}
+}
+
+func TestReadAppManifest(t *testing.T) {
+	app, err := ReadAppManifest(strings.NewReader(sampleAppManifest))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if app.AppID != 243750 || app.Name != "Source SDK Base 2013 Multiplayer" || app.InstallDir != "Source SDK Base 2013 Multiplayer" {
+		t.Errorf("unexpected app: %+v", app)
+	}
+	if app.LastOwner != 76561197960287930 || app.SizeOnDisk != 7410892581 || app.BuildID != 12345678 {
+		t.Errorf("unexpected app: %+v", app)
+	}
+	if !app.LastUpdated.Equal(time.Unix(1698154231, 0)) {
+		t.Errorf("unexpected last updated: %s", app.LastUpdated)
+	}
+	if len(app.InstalledDepots) != 1 || app.InstalledDepots[0].ID != 243751 || app.InstalledDepots[0].Manifest != "6843164329856237193" {
+		t.Errorf("unexpected depots: %+v", app.InstalledDepots)
+	}
+	if app.UserConfig["language"] != "english" {
+		t.Errorf("unexpected user config: %v", app.UserConfig)
+	}
+	if len(app.Unknown) != 1 {
+		t.Errorf("unexpected number of unknown keys: %d", len(app.Unknown))
+	}
+}
+
+func TestFindApp(t *testing.T) {
+	steamRoot := t.TempDir()
+	library := t.TempDir()
+	libraryFolders := strings.Replace(sampleLegacyLibraryFolders, "D:\\\\Games\\\\Steam Library", strings.ReplaceAll(library, "\\", "\\\\"), 1)
+	writeFile(t, filepath.Join(steamRoot, "steamapps", "libraryfolders.vdf"), libraryFolders)
+	writeFile(t, filepath.Join(library, "steamapps", "appmanifest_243750.acf"), sampleAppManifest)
+
+	app, folder, err := FindApp(steamRoot, 243750)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if folder.Path != library || app.AppID != 243750 {
+		t.Errorf("unexpected app %d in %s", app.AppID, folder.Path)
+	}
+	expected := filepath.Join(library, "steamapps", "common", "Source SDK Base 2013 Multiplayer")
+	if folder.InstallPath(app) != expected {
+		t.Errorf("unexpected install path: %s", folder.InstallPath(app))
+	}
+
+	if _, _, err = FindApp(steamRoot, 240); err == nil {
+		t.Error("expected error for app that isn't installed")
+	}
+}
+
+func writeFile(t *testing.T, path string, data string) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
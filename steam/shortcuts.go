@@ -0,0 +1,284 @@
+package steam
+
+import (
+	"errors"
+	"hash/crc32"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/galaco/KeyValues"
+)
+
+const keyShortcuts = "shortcuts"
+const keyTags = "tags"
+
+// shortcutAppIDFlag is set on every app id Steam computes for a shortcut
+const shortcutAppIDFlag = uint32(0x80000000)
+
+// shortcutGameIDType is the low bits of a shortcut's 64 bit game id
+const shortcutGameIDType = uint64(0x02000000)
+
+// Shortcuts is the list of non-Steam games in a user's shortcuts.vdf
+type Shortcuts struct {
+	Shortcuts []Shortcut
+	rootKey   string
+}
+
+// Shortcut is a single non-Steam game
+// Shortcuts that were read from a file remember their original KeyValues,
+// so that writing them back keeps key order, key casing and keys that aren't
+// part of the model.
+type Shortcut struct {
+	// AppID is the app id Steam stores for the shortcut. Older files don't
+	// store one; see ComputedAppID.
+	AppID               int32
+	AppName             string
+	Exe                 string
+	StartDir            string
+	Icon                string
+	ShortcutPath        string
+	LaunchOptions       string
+	IsHidden            bool
+	AllowDesktopConfig  bool
+	AllowOverlay        bool
+	OpenVR              bool
+	Devkit              bool
+	DevkitGameID        string
+	DevkitOverrideAppID int32
+	LastPlayTime        int32
+	FlatpakAppID        string
+	Tags                []string
+
+	source *keyvalues.KeyValue
+}
+
+// shortcutField maps a single shortcut key to its Shortcut field
+type shortcutField struct {
+	key       string
+	valueType keyvalues.ValueType
+	get       func(shortcut *Shortcut) string
+	set       func(shortcut *Shortcut, value string) error
+}
+
+// shortcutFields are every known shortcut key, in the order Steam writes them
+var shortcutFields = []shortcutField{
+	intField("appid", func(s *Shortcut) *int32 { return &s.AppID }),
+	stringField("AppName", func(s *Shortcut) *string { return &s.AppName }),
+	stringField("Exe", func(s *Shortcut) *string { return &s.Exe }),
+	stringField("StartDir", func(s *Shortcut) *string { return &s.StartDir }),
+	stringField("icon", func(s *Shortcut) *string { return &s.Icon }),
+	stringField("ShortcutPath", func(s *Shortcut) *string { return &s.ShortcutPath }),
+	stringField("LaunchOptions", func(s *Shortcut) *string { return &s.LaunchOptions }),
+	boolField("IsHidden", func(s *Shortcut) *bool { return &s.IsHidden }),
+	boolField("AllowDesktopConfig", func(s *Shortcut) *bool { return &s.AllowDesktopConfig }),
+	boolField("AllowOverlay", func(s *Shortcut) *bool { return &s.AllowOverlay }),
+	boolField("OpenVR", func(s *Shortcut) *bool { return &s.OpenVR }),
+	boolField("Devkit", func(s *Shortcut) *bool { return &s.Devkit }),
+	stringField("DevkitGameID", func(s *Shortcut) *string { return &s.DevkitGameID }),
+	intField("DevkitOverrideAppID", func(s *Shortcut) *int32 { return &s.DevkitOverrideAppID }),
+	intField("LastPlayTime", func(s *Shortcut) *int32 { return &s.LastPlayTime }),
+	stringField("FlatpakAppID", func(s *Shortcut) *string { return &s.FlatpakAppID }),
+}
+
+// ReadShortcuts parses a binary shortcuts.vdf
+func ReadShortcuts(file io.Reader) (*Shortcuts, error) {
+	reader := keyvalues.NewBinaryReader(file)
+	root, err := reader.Read()
+	if err != nil {
+		return nil, err
+	}
+	if !strings.EqualFold(root.Key(), keyShortcuts) || !root.HasChildren() {
+		return nil, errors.New("shortcuts key not found")
+	}
+
+	shortcuts := &Shortcuts{rootKey: root.Key()}
+	entries, _ := root.Children()
+	for _, entry := range entries {
+		shortcut, err := readShortcut(entry)
+		if err != nil {
+			return nil, err
+		}
+		shortcuts.Shortcuts = append(shortcuts.Shortcuts, *shortcut)
+	}
+	return shortcuts, nil
+}
+
+func readShortcut(entry *keyvalues.KeyValue) (*Shortcut, error) {
+	if !entry.HasChildren() {
+		return nil, errors.New("shortcut " + entry.Key() + " has no children")
+	}
+	shortcut := &Shortcut{source: entry}
+	children, _ := entry.Children()
+	for _, child := range children {
+		if strings.EqualFold(child.Key(), keyTags) {
+			tags, _ := child.Children()
+			for _, tag := range tags {
+				value, _ := tag.Value()
+				shortcut.Tags = append(shortcut.Tags, value)
+			}
+			continue
+		}
+		for _, field := range shortcutFields {
+			if !strings.EqualFold(child.Key(), field.key) {
+				continue
+			}
+			value, err := child.Value()
+			if err != nil {
+				return nil, errors.New("shortcut key " + child.Key() + " has no value")
+			}
+			if err = field.set(shortcut, value); err != nil {
+				return nil, errors.New("invalid value for shortcut key " + child.Key() + ": " + value)
+			}
+			break
+		}
+	}
+	return shortcut, nil
+}
+
+// Write writes the shortcuts as a binary shortcuts.vdf
+// Shortcuts that were read and not modified are written back byte for byte.
+func (shortcuts *Shortcuts) Write(file io.Writer) error {
+	writer := keyvalues.NewBinaryWriter(file)
+	return writer.Write(shortcuts.ToKeyValue())
+}
+
+// ToKeyValue converts the shortcuts into a KeyValue tree
+func (shortcuts *Shortcuts) ToKeyValue() *keyvalues.KeyValue {
+	rootKey := shortcuts.rootKey
+	if len(rootKey) == 0 {
+		rootKey = keyShortcuts
+	}
+	root := keyvalues.NewKeyValueArray(rootKey)
+	for idx := range shortcuts.Shortcuts {
+		root.AddChild(shortcuts.Shortcuts[idx].toKeyValue(strconv.Itoa(idx)))
+	}
+	return root
+}
+
+// Add appends a new shortcut, setting its AppID to the one Steam would
+// compute if it has none
+func (shortcuts *Shortcuts) Add(shortcut Shortcut) {
+	if shortcut.AppID == 0 {
+		shortcut.AppID = int32(shortcut.ComputedAppID())
+	}
+	shortcut.source = nil
+	shortcuts.Shortcuts = append(shortcuts.Shortcuts, shortcut)
+}
+
+// ComputedAppID returns the app id Steam derives from a shortcut's Exe and
+// AppName, as used for its grid artwork and by older files that don't store
+// an app id
+func (shortcut *Shortcut) ComputedAppID() uint32 {
+	return crc32.ChecksumIEEE([]byte(shortcut.Exe+shortcut.AppName)) | shortcutAppIDFlag
+}
+
+// GameID returns the 64 bit game id Steam uses to launch the shortcut, as
+// in steam://rungameid/<id>
+func (shortcut *Shortcut) GameID() uint64 {
+	return uint64(shortcut.ComputedAppID())<<32 | shortcutGameIDType
+}
+
+// toKeyValue writes the shortcut into its original KeyValues if it has any,
+// otherwise into new KeyValues with every key in the order Steam writes them
+func (shortcut *Shortcut) toKeyValue(key string) *keyvalues.KeyValue {
+	if shortcut.source == nil {
+		node := keyvalues.NewKeyValueArray(key)
+		for _, field := range shortcutFields {
+			node.AddChild(keyvalues.NewKeyValuePair(field.key, field.get(shortcut), field.valueType))
+		}
+		node.AddChild(shortcut.tagsKeyValue())
+		return node
+	}
+
+	node := keyvalues.NewKeyValueArray(key)
+	children, _ := shortcut.source.Children()
+	written := map[string]bool{}
+	for _, child := range children {
+		written[strings.ToLower(child.Key())] = true
+		if strings.EqualFold(child.Key(), keyTags) {
+			tags := shortcut.tagsKeyValue()
+			node.AddChild(keyvalues.NewKeyValueArray(child.Key(), childrenOf(tags)...))
+			continue
+		}
+		node.AddChild(shortcut.update(child))
+	}
+	// keys the original didn't have are only added when they have a value
+	for _, field := range shortcutFields {
+		value := field.get(shortcut)
+		if written[strings.ToLower(field.key)] || len(value) == 0 || value == "0" {
+			continue
+		}
+		node.AddChild(keyvalues.NewKeyValuePair(field.key, value, field.valueType))
+	}
+	if !written[keyTags] && len(shortcut.Tags) > 0 {
+		node.AddChild(shortcut.tagsKeyValue())
+	}
+	return node
+}
+
+// update returns a copy of an original shortcut key with its current value
+func (shortcut *Shortcut) update(original *keyvalues.KeyValue) *keyvalues.KeyValue {
+	for _, field := range shortcutFields {
+		if strings.EqualFold(original.Key(), field.key) {
+			return keyvalues.NewKeyValuePair(original.Key(), field.get(shortcut), original.Type())
+		}
+	}
+	return original
+}
+
+func (shortcut *Shortcut) tagsKeyValue() *keyvalues.KeyValue {
+	tags := keyvalues.NewKeyValueArray(keyTags)
+	for idx, tag := range shortcut.Tags {
+		tags.AddChild(keyvalues.NewKeyValuePair(strconv.Itoa(idx), tag, keyvalues.ValueString))
+	}
+	return tags
+}
+
+func childrenOf(node *keyvalues.KeyValue) []*keyvalues.KeyValue {
+	children, _ := node.Children()
+	return children
+}
+
+func stringField(key string, field func(s *Shortcut) *string) shortcutField {
+	return shortcutField{
+		key:       key,
+		valueType: keyvalues.ValueString,
+		get:       func(s *Shortcut) string { return *field(s) },
+		set: func(s *Shortcut, value string) error {
+			*field(s) = value
+			return nil
+		},
+	}
+}
+
+func intField(key string, field func(s *Shortcut) *int32) shortcutField {
+	return shortcutField{
+		key:       key,
+		valueType: keyvalues.ValueInt,
+		get:       func(s *Shortcut) string { return strconv.Itoa(int(*field(s))) },
+		set: func(s *Shortcut, value string) error {
+			i, err := strconv.ParseInt(value, 10, 32)
+			*field(s) = int32(i)
+			return err
+		},
+	}
+}
+
+func boolField(key string, field func(s *Shortcut) *bool) shortcutField {
+	return shortcutField{
+		key:       key,
+		valueType: keyvalues.ValueInt,
+		get: func(s *Shortcut) string {
+			if *field(s) {
+				return "1"
+			}
+			return "0"
+		},
+		set: func(s *Shortcut, value string) error {
+			i, err := strconv.Atoi(value)
+			*field(s) = i != 0
+			return err
+		},
+	}
+}
@@ -0,0 +1,98 @@
+package steam
+
+import (
+	"bytes"
+	"testing"
+)
+
+// sampleShortcuts is a shortcuts.vdf as written by the Steam client, with a
+// key that isn't part of the model
+var sampleShortcuts = []byte("\x00shortcuts\x00" +
+	"\x000\x00" +
+	"\x02appid\x00\x9c\x5c\x3b\xd6" +
+	"\x01AppName\x00Hammer\x00" +
+	"\x01Exe\x00\"C:\\sdk\\bin\\hammer.exe\"\x00" +
+	"\x01StartDir\x00\"C:\\sdk\\bin\\\"\x00" +
+	"\x01icon\x00\x00" +
+	"\x01LaunchOptions\x00-nop4\x00" +
+	"\x02IsHidden\x00\x00\x00\x00\x00" +
+	"\x02AllowOverlay\x00\x01\x00\x00\x00" +
+	"\x01sortas\x00tools\x00" +
+	"\x02LastPlayTime\x00\x00\x00\x00\x00" +
+	"\x00tags\x00" +
+	"\x010\x00tools\x00" +
+	"\x08" +
+	"\x08" +
+	"\x08" +
+	"\x08")
+
+func TestReadShortcuts(t *testing.T) {
+	shortcuts, err := ReadShortcuts(bytes.NewReader(sampleShortcuts))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(shortcuts.Shortcuts) != 1 {
+		t.Fatalf("unexpected number of shortcuts: %d", len(shortcuts.Shortcuts))
+	}
+	shortcut := shortcuts.Shortcuts[0]
+	if shortcut.AppName != "Hammer" || shortcut.Exe != "\"C:\\sdk\\bin\\hammer.exe\"" || shortcut.LaunchOptions != "-nop4" {
+		t.Errorf("unexpected shortcut: %+v", shortcut)
+	}
+	if !shortcut.AllowOverlay || shortcut.IsHidden {
+		t.Error("unexpected shortcut flags")
+	}
+	if len(shortcut.Tags) != 1 || shortcut.Tags[0] != "tools" {
+		t.Errorf("unexpected tags: %v", shortcut.Tags)
+	}
+	if shortcut.AppID != -700752740 {
+		t.Errorf("unexpected app id: %d", shortcut.AppID)
+	}
+}
+
+func TestShortcuts_Write(t *testing.T) {
+	shortcuts, err := ReadShortcuts(bytes.NewReader(sampleShortcuts))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	if err = shortcuts.Write(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(buf.Bytes(), sampleShortcuts) {
+		t.Errorf("unmodified shortcuts were not written byte for byte.\nexpected: %q\nreceived: %q", sampleShortcuts, buf.Bytes())
+	}
+
+	shortcuts.Shortcuts[0].LaunchOptions = "-nop4 -dev"
+	shortcuts.Shortcuts[0].Tags = append(shortcuts.Shortcuts[0].Tags, "sdk")
+	shortcuts.Add(Shortcut{AppName: "Game", Exe: "\"C:\\game.exe\""})
+
+	buf.Reset()
+	if err = shortcuts.Write(&buf); err != nil {
+		t.Fatal(err)
+	}
+	modified, err := ReadShortcuts(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(modified.Shortcuts) != 2 {
+		t.Fatalf("unexpected number of shortcuts: %d", len(modified.Shortcuts))
+	}
+	if modified.Shortcuts[0].LaunchOptions != "-nop4 -dev" || len(modified.Shortcuts[0].Tags) != 2 {
+		t.Errorf("modifications were not written: %+v", modified.Shortcuts[0])
+	}
+	added := modified.Shortcuts[1]
+	if added.AppName != "Game" || uint32(added.AppID) != added.ComputedAppID() {
+		t.Errorf("unexpected added shortcut: %+v", added)
+	}
+}
+
+func TestShortcut_ComputedAppID(t *testing.T) {
+	shortcut := Shortcut{AppName: "Hammer", Exe: "\"C:\\sdk\\bin\\hammer.exe\""}
+	if shortcut.ComputedAppID()&shortcutAppIDFlag == 0 {
+		t.Error("computed app id is missing the shortcut flag")
+	}
+	if shortcut.GameID()>>32 != uint64(shortcut.ComputedAppID()) || shortcut.GameID()&0xffffffff != shortcutGameIDType {
+		t.Errorf("unexpected game id: %d", shortcut.GameID())
+	}
+}
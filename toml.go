@@ -0,0 +1,744 @@
+package keyvalues
+
+import (
+	"bytes"
+	"errors"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+const tomlHexDigits = "0123456789abcdef"
+
+// ToTOML converts the tree into a TOML document containing this KeyValue, or
+// the children of a `$root` node.
+// Blocks become tables, and the values of a key that appears more than once
+// are collapsed into an array, as in JSONFriendly; a key repeated only by
+// blocks becomes an array of tables. TOML requires a table's values to come
+// before its sub-tables, so within each block, values are written before
+// blocks; the order of values, and of blocks, is kept. TOML integers are
+// 64 bit signed, so uint64 values that don't fit are written as strings.
+func (node *KeyValue) ToTOML() ([]byte, error) {
+	var buf bytes.Buffer
+	if err := writeTOMLTable(&buf, nil, toFriendlyRoot(node, friendlyTypedLeaf)); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// FromTOML parses a TOML document into a KeyValue tree, the same way
+// FromJSON does in JSONFriendly mode.
+// TOML 1.0 is supported: tables, arrays of tables, inline tables, dotted
+// keys, and every string and number syntax. Offset date-times, local
+// date-times, local dates and local times are checked, and kept as strings.
+// Values that TOML doesn't allow, such as integers with leading zeros or
+// inline tables extended after they were written, return an error.
+func FromTOML(data []byte) (*KeyValue, error) {
+	p := &tomlParser{data: string(data)}
+	root, err := p.readDocument()
+	if err != nil {
+		return nil, err
+	}
+	return fromFriendlyRoot(root.toFriendly())
+}
+
+func writeTOMLTable(buf *bytes.Buffer, path []string, table OrderedMap) error {
+	for _, item := range table {
+		if isTOMLTable(item.Value) || isTOMLTableArray(item.Value) {
+			continue
+		}
+		value, err := formatTOMLValue(item.Value)
+		if err != nil {
+			return err
+		}
+		buf.WriteString(formatTOMLKey(item.Key) + " = " + value + "\n")
+	}
+
+	for _, item := range table {
+		itemPath := append(append([]string{}, path...), formatTOMLKey(item.Key))
+		switch {
+		case isTOMLTable(item.Value):
+			writeTOMLHeader(buf, "["+strings.Join(itemPath, ".")+"]")
+			if err := writeTOMLTable(buf, itemPath, item.Value.(OrderedMap)); err != nil {
+				return err
+			}
+		case isTOMLTableArray(item.Value):
+			for _, element := range item.Value.([]interface{}) {
+				writeTOMLHeader(buf, "[["+strings.Join(itemPath, ".")+"]]")
+				if err := writeTOMLTable(buf, itemPath, element.(OrderedMap)); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// writeTOMLHeader writes a table header, separated from anything before it
+// by a blank line
+func writeTOMLHeader(buf *bytes.Buffer, header string) {
+	if buf.Len() > 0 {
+		buf.WriteString("\n")
+	}
+	buf.WriteString(header + "\n")
+}
+
+func isTOMLTable(value interface{}) bool {
+	_, ok := value.(OrderedMap)
+	return ok
+}
+
+// isTOMLTableArray returns whether value is a repeated key where every value
+// is a block
+func isTOMLTableArray(value interface{}) bool {
+	items, ok := value.([]interface{})
+	if !ok || len(items) == 0 {
+		return false
+	}
+	for _, item := range items {
+		if !isTOMLTable(item) {
+			return false
+		}
+	}
+	return true
+}
+
+func formatTOMLValue(value interface{}) (string, error) {
+	switch v := value.(type) {
+	case string:
+		return formatTOMLString(v), nil
+	case bool:
+		return strconv.FormatBool(v), nil
+	case int64:
+		return strconv.FormatInt(v, 10), nil
+	case uint64:
+		if v > math.MaxInt64 {
+			return formatTOMLString(strconv.FormatUint(v, 10)), nil
+		}
+		return strconv.FormatUint(v, 10), nil
+	case float64:
+		switch {
+		case math.IsNaN(v):
+			return "nan", nil
+		case math.IsInf(v, 1):
+			return "inf", nil
+		case math.IsInf(v, -1):
+			return "-inf", nil
+		}
+		return formatFriendlyFloat(v, 64), nil
+	case []interface{}:
+		items := make([]string, len(v))
+		for idx, item := range v {
+			formatted, err := formatTOMLValue(item)
+			if err != nil {
+				return "", err
+			}
+			items[idx] = formatted
+		}
+		return "[" + strings.Join(items, ", ") + "]", nil
+	case OrderedMap:
+		if len(v) == 0 {
+			return "{}", nil
+		}
+		items := make([]string, len(v))
+		for idx, item := range v {
+			formatted, err := formatTOMLValue(item.Value)
+			if err != nil {
+				return "", err
+			}
+			items[idx] = formatTOMLKey(item.Key) + " = " + formatted
+		}
+		return "{ " + strings.Join(items, ", ") + " }", nil
+	}
+	return "", errors.New("unsupported toml value")
+}
+
+// formatTOMLKey returns key bare if it only has bare key characters, or
+// quoted otherwise
+func formatTOMLKey(key string) string {
+	if len(key) == 0 {
+		return formatTOMLString(key)
+	}
+	for idx := 0; idx < len(key); idx++ {
+		if !isTOMLBareKeyCharacter(key[idx]) {
+			return formatTOMLString(key)
+		}
+	}
+	return key
+}
+
+func formatTOMLString(value string) string {
+	var builder strings.Builder
+	builder.WriteByte('"')
+	for _, char := range value {
+		switch char {
+		case '"':
+			builder.WriteString("\\\"")
+		case '\\':
+			builder.WriteString("\\\\")
+		case '\b':
+			builder.WriteString("\\b")
+		case '\t':
+			builder.WriteString("\\t")
+		case '\n':
+			builder.WriteString("\\n")
+		case '\f':
+			builder.WriteString("\\f")
+		case '\r':
+			builder.WriteString("\\r")
+		default:
+			if char < ' ' || char == 0x7f {
+				builder.WriteString("\\u00" + string(tomlHexDigits[char>>4]) + string(tomlHexDigits[char&0xf]))
+				continue
+			}
+			builder.WriteRune(char)
+		}
+	}
+	builder.WriteByte('"')
+	return builder.String()
+}
+
+func isTOMLBareKeyCharacter(char byte) bool {
+	return char >= 'a' && char <= 'z' ||
+		char >= 'A' && char <= 'Z' ||
+		char >= '0' && char <= '9' ||
+		char == '_' || char == '-'
+}
+
+// tomlTable is a table being read, which can still be added to by later
+// headers and dotted keys
+type tomlTable struct {
+	keys []string
+	// values holds leaf values and arrays, *tomlTable for tables, and
+	// []*tomlTable for arrays of tables
+	values map[string]interface{}
+	// defined is set once a table has had a header, or was written inline,
+	// so that it can't be defined again
+	defined bool
+	// inline is set for inline tables, which can't have keys added later
+	inline bool
+}
+
+func newTOMLTable() *tomlTable {
+	return &tomlTable{values: map[string]interface{}{}}
+}
+
+func (table *tomlTable) set(key string, value interface{}) {
+	if _, ok := table.values[key]; !ok {
+		table.keys = append(table.keys, key)
+	}
+	table.values[key] = value
+}
+
+// toFriendly converts the table and everything in it into friendly form
+func (table *tomlTable) toFriendly() OrderedMap {
+	m := OrderedMap{}
+	for _, key := range table.keys {
+		m = append(m, MapItem{Key: key, Value: tomlToFriendly(table.values[key])})
+	}
+	return m
+}
+
+func tomlToFriendly(value interface{}) interface{} {
+	switch v := value.(type) {
+	case *tomlTable:
+		return v.toFriendly()
+	case []*tomlTable:
+		items := make([]interface{}, len(v))
+		for idx, table := range v {
+			items[idx] = table.toFriendly()
+		}
+		return items
+	case []interface{}:
+		items := make([]interface{}, len(v))
+		for idx, item := range v {
+			items[idx] = tomlToFriendly(item)
+		}
+		return items
+	}
+	return value
+}
+
+// tomlParser holds the position of FromTOML in its data
+type tomlParser struct {
+	data string
+	pos  int
+}
+
+func (p *tomlParser) readDocument() (*tomlTable, error) {
+	root := newTOMLTable()
+	current := root
+	for {
+		p.skipSpace(true)
+		if p.eof() {
+			return root, nil
+		}
+
+		switch {
+		case strings.HasPrefix(p.data[p.pos:], "[["):
+			p.pos += 2
+			path, err := p.readKeyPath()
+			if err != nil {
+				return nil, err
+			}
+			if !p.consume("]]") {
+				return nil, p.error("expected ']]'")
+			}
+			if current, err = p.appendTableArray(root, path); err != nil {
+				return nil, err
+			}
+		case p.peek() == '[':
+			p.pos++
+			path, err := p.readKeyPath()
+			if err != nil {
+				return nil, err
+			}
+			if !p.consume("]") {
+				return nil, p.error("expected ']'")
+			}
+			if current, err = p.defineTable(root, path); err != nil {
+				return nil, err
+			}
+		default:
+			if err := p.readKeyValue(current); err != nil {
+				return nil, err
+			}
+		}
+
+		p.skipSpace(false)
+		if !p.eof() && !p.consume("\n") && !p.consume("\r\n") {
+			return nil, p.error("expected a new line")
+		}
+	}
+}
+
+// readKeyValue reads a key, which may be dotted, and its value into table
+func (p *tomlParser) readKeyValue(table *tomlTable) error {
+	path, err := p.readKeyPath()
+	if err != nil {
+		return err
+	}
+	p.skipSpace(false)
+	if !p.consume("=") {
+		return p.error("expected '='")
+	}
+	p.skipSpace(false)
+	value, err := p.readValue()
+	if err != nil {
+		return err
+	}
+
+	parent, err := p.descend(table, path[:len(path)-1])
+	if err != nil {
+		return err
+	}
+	key := path[len(path)-1]
+	if _, ok := parent.values[key]; ok {
+		return p.error("duplicate key: " + key)
+	}
+	parent.set(key, value)
+	return nil
+}
+
+// descend returns the table at path below table, creating any that don't
+// exist. The last element of an array of tables is used.
+func (p *tomlParser) descend(table *tomlTable, path []string) (*tomlTable, error) {
+	current := table
+	for _, key := range path {
+		switch v := current.values[key].(type) {
+		case nil:
+			next := newTOMLTable()
+			current.set(key, next)
+			current = next
+		case *tomlTable:
+			if v.inline {
+				return nil, p.error("inline table cannot be extended: " + key)
+			}
+			current = v
+		case []*tomlTable:
+			current = v[len(v)-1]
+		default:
+			return nil, p.error("key is not a table: " + key)
+		}
+	}
+	return current, nil
+}
+
+func (p *tomlParser) defineTable(root *tomlTable, path []string) (*tomlTable, error) {
+	table, err := p.descend(root, path)
+	if err != nil {
+		return nil, err
+	}
+	if table.defined {
+		return nil, p.error("table defined more than once: " + strings.Join(path, "."))
+	}
+	table.defined = true
+	return table, nil
+}
+
+func (p *tomlParser) appendTableArray(root *tomlTable, path []string) (*tomlTable, error) {
+	parent, err := p.descend(root, path[:len(path)-1])
+	if err != nil {
+		return nil, err
+	}
+	key := path[len(path)-1]
+	table := newTOMLTable()
+	table.defined = true
+	switch v := parent.values[key].(type) {
+	case nil:
+		parent.set(key, []*tomlTable{table})
+	case []*tomlTable:
+		parent.set(key, append(v, table))
+	default:
+		return nil, p.error("key is not an array of tables: " + key)
+	}
+	return table, nil
+}
+
+// readKeyPath reads a key of one or more parts separated by dots
+func (p *tomlParser) readKeyPath() ([]string, error) {
+	var path []string
+	for {
+		p.skipSpace(false)
+		if p.eof() {
+			return nil, p.error("expected a key")
+		}
+		var key string
+		var err error
+		switch p.peek() {
+		case '"', '\'':
+			key, err = p.readString()
+			if err != nil {
+				return nil, err
+			}
+		default:
+			start := p.pos
+			for !p.eof() && isTOMLBareKeyCharacter(p.peek()) {
+				p.pos++
+			}
+			if start == p.pos {
+				return nil, p.error("expected a key")
+			}
+			key = p.data[start:p.pos]
+		}
+		path = append(path, key)
+
+		p.skipSpace(false)
+		if !p.consume(".") {
+			return path, nil
+		}
+	}
+}
+
+func (p *tomlParser) readValue() (interface{}, error) {
+	if p.eof() {
+		return nil, p.error("expected a value")
+	}
+	switch p.peek() {
+	case '"', '\'':
+		return p.readString()
+	case '[':
+		return p.readArray()
+	case '{':
+		return p.readInlineTable()
+	}
+
+	start := p.pos
+	for !p.eof() && strings.IndexByte("0123456789abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ+-._:", p.peek()) >= 0 {
+		p.pos++
+	}
+	// a space may separate the date and time of a date-time
+	if p.pos-start == 10 && strings.Count(p.data[start:p.pos], "-") == 2 && strings.HasPrefix(p.data[p.pos:], " ") &&
+		p.pos+1 < len(p.data) && p.data[p.pos+1] >= '0' && p.data[p.pos+1] <= '9' {
+		p.pos++
+		for !p.eof() && strings.IndexByte("0123456789+-.:Z", p.peek()) >= 0 {
+			p.pos++
+		}
+	}
+	token := p.data[start:p.pos]
+
+	switch token {
+	case "true":
+		return true, nil
+	case "false":
+		return false, nil
+	case "inf", "+inf":
+		return math.Inf(1), nil
+	case "-inf":
+		return math.Inf(-1), nil
+	case "nan", "+nan", "-nan":
+		return math.NaN(), nil
+	case "":
+		return nil, p.error("expected a value")
+	}
+	if strings.Contains(token, ":") || len(token) >= 10 && token[4] == '-' && token[7] == '-' {
+		if !isTOMLDateTime(token) {
+			return nil, p.error("invalid date or time: " + token)
+		}
+		return token, nil
+	}
+	if err := checkTOMLNumber(token); err != nil {
+		return nil, p.error(err.Error() + ": " + token)
+	}
+
+	number := strings.ReplaceAll(token, "_", "")
+	switch {
+	case strings.HasPrefix(number, "0x"), strings.HasPrefix(number, "0o"), strings.HasPrefix(number, "0b"):
+		i, err := strconv.ParseInt(number, 0, 64)
+		if err != nil {
+			return nil, p.error("invalid integer: " + token)
+		}
+		return i, nil
+	case strings.ContainsAny(number, ".eE"):
+		f, err := strconv.ParseFloat(number, 64)
+		if err != nil {
+			return nil, p.error("invalid float: " + token)
+		}
+		return f, nil
+	}
+	i, err := strconv.ParseInt(number, 10, 64)
+	if err != nil {
+		return nil, p.error("invalid value: " + token)
+	}
+	return i, nil
+}
+
+// tomlDateTimeLayouts are the layouts of an offset date-time, local
+// date-time, local date and local time. Fractional seconds are accepted
+// after the seconds of any of them.
+var tomlDateTimeLayouts = []string{
+	time.RFC3339,
+	"2006-01-02T15:04:05",
+	"2006-01-02",
+	"15:04:05",
+}
+
+// isTOMLDateTime returns whether token is a date, time or date-time
+func isTOMLDateTime(token string) bool {
+	// the date and time may be separated by a t or a space, and the offset
+	// may be a lower case z
+	if len(token) > 10 && (token[10] == 't' || token[10] == ' ') {
+		token = token[:10] + "T" + token[11:]
+	}
+	if strings.HasSuffix(token, "z") {
+		token = strings.TrimSuffix(token, "z") + "Z"
+	}
+	for _, layout := range tomlDateTimeLayouts {
+		if _, err := time.Parse(layout, token); err == nil {
+			return true
+		}
+	}
+	return false
+}
+
+// checkTOMLNumber returns an error for the parts of a number that strconv
+// accepts and TOML doesn't: underscores that aren't between two digits,
+// leading zeros, and decimal points without a digit on each side
+func checkTOMLNumber(token string) error {
+	digits := "0123456789"
+	if len(token) > 2 && token[0] == '0' && strings.IndexByte("xob", token[1]) >= 0 {
+		digits = "0123456789abcdefABCDEF"
+		token = token[2:]
+	}
+	for idx := 0; idx < len(token); idx++ {
+		switch token[idx] {
+		case '_':
+			if idx == 0 || idx == len(token)-1 ||
+				strings.IndexByte(digits, token[idx-1]) < 0 || strings.IndexByte(digits, token[idx+1]) < 0 {
+				return errors.New("underscores must be between digits")
+			}
+		case '.':
+			if idx == 0 || idx == len(token)-1 ||
+				strings.IndexByte("0123456789", token[idx-1]) < 0 || strings.IndexByte("0123456789", token[idx+1]) < 0 {
+				return errors.New("decimal points must be between digits")
+			}
+		}
+	}
+	if digits == "0123456789" {
+		unsigned := strings.TrimLeft(token, "+-")
+		if len(unsigned) > 1 && unsigned[0] == '0' && strings.IndexByte("0123456789_", unsigned[1]) >= 0 {
+			return errors.New("leading zeros are not allowed")
+		}
+	}
+	return nil
+}
+
+func (p *tomlParser) readArray() ([]interface{}, error) {
+	items := []interface{}{}
+	p.pos++
+	for {
+		p.skipSpace(true)
+		if p.eof() {
+			return nil, p.error("unterminated array")
+		}
+		if p.consume("]") {
+			return items, nil
+		}
+		item, err := p.readValue()
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+		p.skipSpace(true)
+		if !p.consume(",") && !strings.HasPrefix(p.data[p.pos:], "]") {
+			return nil, p.error("expected ',' or ']' in array")
+		}
+	}
+}
+
+func (p *tomlParser) readInlineTable() (*tomlTable, error) {
+	table := newTOMLTable()
+	table.defined = true
+	table.inline = true
+	p.pos++
+	p.skipSpace(false)
+	if p.consume("}") {
+		return table, nil
+	}
+	for {
+		if err := p.readKeyValue(table); err != nil {
+			return nil, err
+		}
+		p.skipSpace(false)
+		if p.consume("}") {
+			return table, nil
+		}
+		if !p.consume(",") {
+			return nil, p.error("expected ',' or '}' in inline table")
+		}
+	}
+}
+
+// readString reads a basic, literal or multi-line string
+func (p *tomlParser) readString() (string, error) {
+	quote := p.data[p.pos : p.pos+1]
+	multiLine := strings.HasPrefix(p.data[p.pos:], strings.Repeat(quote, 3))
+	terminator := quote
+	if multiLine {
+		terminator = strings.Repeat(quote, 3)
+		p.pos += 3
+		// a line break straight after the opening quotes isn't part of the string
+		if !p.consume("\n") {
+			p.consume("\r\n")
+		}
+	} else {
+		p.pos++
+	}
+
+	var builder strings.Builder
+	for {
+		if p.eof() {
+			return "", p.error("unterminated string")
+		}
+		if strings.HasPrefix(p.data[p.pos:], terminator) {
+			p.pos += len(terminator)
+			// up to two quotes may directly precede the closing quotes
+			for extra := 0; multiLine && extra < 2 && strings.HasPrefix(p.data[p.pos:], quote); extra++ {
+				builder.WriteString(quote)
+				p.pos++
+			}
+			return builder.String(), nil
+		}
+		char := p.data[p.pos]
+		if char == '\n' && !multiLine {
+			return "", p.error("unterminated string")
+		}
+		if char != '\\' || quote == "'" {
+			builder.WriteByte(char)
+			p.pos++
+			continue
+		}
+
+		p.pos++
+		if p.eof() {
+			return "", p.error("unterminated string")
+		}
+		escaped := p.data[p.pos]
+		p.pos++
+		switch escaped {
+		case 'b':
+			builder.WriteByte('\b')
+		case 't':
+			builder.WriteByte('\t')
+		case 'n':
+			builder.WriteByte('\n')
+		case 'f':
+			builder.WriteByte('\f')
+		case 'r':
+			builder.WriteByte('\r')
+		case '"':
+			builder.WriteByte('"')
+		case '\\':
+			builder.WriteByte('\\')
+		case 'u', 'U':
+			size := 4
+			if escaped == 'U' {
+				size = 8
+			}
+			if p.pos+size > len(p.data) {
+				return "", p.error("invalid unicode escape")
+			}
+			code, err := strconv.ParseUint(p.data[p.pos:p.pos+size], 16, 32)
+			if err != nil || !utf8.ValidRune(rune(code)) {
+				return "", p.error("invalid unicode escape")
+			}
+			builder.WriteRune(rune(code))
+			p.pos += size
+		case ' ', '\t', '\r', '\n':
+			// a backslash at the end of a line trims the following whitespace
+			if !multiLine {
+				return "", p.error("invalid escape")
+			}
+			for !p.eof() && strings.IndexByte(" \t\r\n", p.peek()) >= 0 {
+				p.pos++
+			}
+		default:
+			return "", p.error("invalid escape")
+		}
+	}
+}
+
+// skipSpace skips whitespace and comments, and line breaks if newLines is set
+func (p *tomlParser) skipSpace(newLines bool) {
+	for !p.eof() {
+		switch p.peek() {
+		case ' ', '\t':
+			p.pos++
+		case '\r', '\n':
+			if !newLines {
+				return
+			}
+			p.pos++
+		case '#':
+			for !p.eof() && p.peek() != '\n' {
+				p.pos++
+			}
+		default:
+			return
+		}
+	}
+}
+
+func (p *tomlParser) eof() bool {
+	return p.pos >= len(p.data)
+}
+
+func (p *tomlParser) peek() byte {
+	return p.data[p.pos]
+}
+
+func (p *tomlParser) consume(token string) bool {
+	if !strings.HasPrefix(p.data[p.pos:], token) {
+		return false
+	}
+	p.pos += len(token)
+	return true
+}
+
+// error returns an error with the line of the current position
+func (p *tomlParser) error(message string) error {
+	line := strings.Count(p.data[:p.pos], "\n") + 1
+	return errors.New(message + " on line " + strconv.Itoa(line))
+}
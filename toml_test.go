@@ -0,0 +1,207 @@
+package keyvalues
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestKeyValue_ToTOML(t *testing.T) {
+	kv := readJSONSample(t)
+	kv.AddChild(NewKeyValuePair("label", "say \"hi\"", ValueString))
+	kv.AddChild(NewKeyValueArray("side", NewKeyValue("id", "1"), NewKeyValue("material", "dev/dev_measurewall01a")))
+	kv.AddChild(NewKeyValueArray("side", NewKeyValue("id", "2")))
+	kv.AddChild(NewKeyValuePair("huge", "18446744073709551615", ValueUint64))
+
+	data, err := kv.ToTOML()
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := `[root]
+name = "test"
+count = 3
+scale = 0.5
+solid = [1, 2]
+steamid = 76561197960287930
+label = "say \"hi\""
+huge = "18446744073709551615"
+
+[root.child]
+id = 7
+
+[[root.side]]
+id = 1
+material = "dev/dev_measurewall01a"
+
+[[root.side]]
+id = 2
+`
+	if string(data) != expected {
+		t.Errorf("unexpected toml:\n%s", data)
+	}
+}
+
+func TestFromTOML(t *testing.T) {
+	data := []byte(`# a comment
+title = "test" # trailing comment
+
+[root]
+count = 1_000
+scale = 1.0
+literal = 'C:\sdk'
+multi = """
+first
+second"""
+solid = [
+	1,
+	2,
+]
+child.name = "dotted"
+inline = { a = 1, b = "two" }
+date = 1979-05-27T07:32:00Z
+
+[[root.side]]
+id = 1
+
+[[root.side]]
+id = 2
+
+[root.side.extra]
+flag = true
+`)
+	kv, err := FromTOML(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if kv.Key() != RootNodeKey {
+		t.Fatalf("expected $root node, got %s", kv.Key())
+	}
+	root, _ := kv.Find("root")
+	if count, _ := root.Find("count"); count == nil || count.Type() != ValueInt {
+		t.Error("unexpected count")
+	} else if value, _ := count.Value(); value != "1000" {
+		t.Errorf("unexpected count: %s", value)
+	}
+	if scale, _ := root.Find("scale"); scale.Type() != ValueFloat {
+		t.Errorf("unexpected scale type: %s", scale.Type())
+	}
+	if literal, _ := root.Find("literal"); literal == nil {
+		t.Error("missing literal string")
+	} else if value, _ := literal.Value(); value != "C:\\sdk" {
+		t.Errorf("unexpected literal string: %s", value)
+	}
+	if multi, _ := root.Find("multi"); multi == nil {
+		t.Error("missing multi-line string")
+	} else if value, _ := multi.Value(); value != "first\nsecond" {
+		t.Errorf("unexpected multi-line string: %q", value)
+	}
+	if solids, _ := root.FindAll("solid"); len(solids) != 2 {
+		t.Errorf("unexpected number of solids: %d", len(solids))
+	}
+	if child, _ := root.Find("child"); child == nil || !child.HasChildren() {
+		t.Error("dotted key didn't create a block")
+	}
+	if date, _ := root.Find("date"); date == nil {
+		t.Error("missing date")
+	} else if value, _ := date.Value(); value != "1979-05-27T07:32:00Z" {
+		t.Errorf("unexpected date: %s", value)
+	}
+	sides, _ := root.FindAll("side")
+	if len(sides) != 2 {
+		t.Fatalf("unexpected number of sides: %d", len(sides))
+	}
+	if extra, _ := sides[1].Find("extra"); extra == nil {
+		t.Error("sub-table wasn't added to the last table in the array")
+	}
+
+	errorSamples := []string{
+		"a = 1\na = 2\n",
+		"[a]\n[a]\n",
+		"a = \"unterminated\n",
+		"a = 1 b = 2\n",
+	}
+	for _, sample := range errorSamples {
+		if _, err = FromTOML([]byte(sample)); err == nil {
+			t.Errorf("expected error for %q", sample)
+		}
+	}
+}
+
+func TestFromTOML_DateTime(t *testing.T) {
+	samples := []string{
+		"1979-05-27T07:32:00Z",
+		"1979-05-27t07:32:00z",
+		"1979-05-27 07:32:00-07:00",
+		"1979-05-27T00:32:00.999999-07:00",
+		"1979-05-27T07:32:00",
+		"1979-05-27",
+		"07:32:00",
+		"00:32:00.999999",
+	}
+	for _, sample := range samples {
+		kv, err := FromTOML([]byte("a = " + sample + "\nb = 1\n"))
+		if err != nil {
+			t.Errorf("unexpected error for %s: %s", sample, err)
+			continue
+		}
+		if a, _ := kv.Find("a"); a == nil {
+			t.Errorf("missing date for %s", sample)
+		} else if value, _ := a.Value(); value != sample {
+			t.Errorf("unexpected date for %s: %s", sample, value)
+		}
+	}
+}
+
+func TestFromTOML_Invalid(t *testing.T) {
+	samples := map[string]string{
+		"a = 1979-13-27\n":                 "invalid date or time: 1979-13-27 on line 1",
+		"a = 07:32\n":                      "invalid date or time: 07:32 on line 1",
+		"a = foo:bar\n":                    "invalid date or time: foo:bar on line 1",
+		"a = 007\n":                        "leading zeros are not allowed: 007 on line 1",
+		"a = -01.5\n":                      "leading zeros are not allowed: -01.5 on line 1",
+		"a = 1__000\n":                     "underscores must be between digits: 1__000 on line 1",
+		"a = _1\n":                         "underscores must be between digits: _1 on line 1",
+		"a = 1_\n":                         "underscores must be between digits: 1_ on line 1",
+		"a = 0x_ff\n":                      "underscores must be between digits: 0x_ff on line 1",
+		"a = .5\n":                         "decimal points must be between digits: .5 on line 1",
+		"a = 1.\n":                         "decimal points must be between digits: 1. on line 1",
+		"a = 1.e5\n":                       "decimal points must be between digits: 1.e5 on line 1",
+		"a = { b = 1 }\n[a.c]\n":           "inline table cannot be extended: a on line 2",
+		"a = { b = 1 }\na.c = 2\n":         "inline table cannot be extended: a on line 2",
+		"a = { b = { c = 1 }, b.d = 2 }\n": "inline table cannot be extended: b on line 1",
+	}
+	for sample, expected := range samples {
+		_, err := FromTOML([]byte(sample))
+		if err == nil {
+			t.Errorf("expected error for %q", sample)
+		} else if err.Error() != expected {
+			t.Errorf("unexpected error for %q: %s", sample, err)
+		}
+	}
+
+	kv, err := FromTOML([]byte("a = 0\nb = -0.5\nc = 0xdead_beef\nd = 1_000.000_1e1_0\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d, _ := kv.Find("d"); d == nil || d.Type() != ValueFloat {
+		t.Error("unexpected d")
+	}
+}
+
+func TestKeyValue_ToTOML_RoundTrip(t *testing.T) {
+	kv := readJSONSample(t)
+	data, err := kv.ToTOML()
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, err := FromTOML(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	reencoded, err := result.ToTOML()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(data, reencoded) {
+		t.Errorf("round trip changed toml:\n%s", reencoded)
+	}
+}
@@ -12,8 +12,17 @@ const ValueInt = ValueType("integer")
 const ValueArray = ValueType("array")
 const ValuePtr = ValueType("ptr")
 const ValueFloat = ValueType("float")
+const ValueWString = ValueType("wstring")
+const ValueColor = ValueType("color")
+const ValueUint64 = ValueType("uint64")
+const ValueInt64 = ValueType("int64")
 
 func getType(val string) ValueType {
+	// most values aren't numbers, and are rejected without parsing, as a
+	// failed parse allocates its error
+	if !maybeNumber(val) {
+		return ValueString
+	}
 	switch true {
 	case isFloat(val):
 		return ValueFloat
@@ -39,3 +48,12 @@ func isFloat(val string) bool {
 	}
 	return false
 }
+
+// maybeNumber returns false for values that neither isInt nor isFloat
+// accept: numbers start with a digit, sign or decimal point, and have no spaces
+func maybeNumber(val string) bool {
+	if len(val) == 0 || strings.IndexByte("0123456789+-.", val[0]) < 0 {
+		return false
+	}
+	return strings.IndexByte(val, ' ') < 0
+}
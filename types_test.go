@@ -12,6 +12,9 @@ func Test_getType(t *testing.T) {
 	if getType("45.123") != ValueFloat {
 		t.Error("failed to determine string is a float")
 	}
+	if getType("0 148 205") != ValueString {
+		t.Error("failed to determine string is a string")
+	}
 }
 
 func Test_isInt(t *testing.T) {
@@ -0,0 +1,215 @@
+package vmf
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+)
+
+// SeparatorComma separates output fields in files written by older editors
+const SeparatorComma = ','
+
+// SeparatorEscape separates output fields in files written by newer editors,
+// which allows parameters to contain commas
+const SeparatorEscape = '\x1b'
+
+const targetWildcard = "*"
+const targetSpecialPrefix = "!"
+
+// Output is a single entity I/O connection, fired when the owning entity's
+// output Name fires
+type Output struct {
+	Name        string
+	Target      string
+	Input       string
+	Param       string
+	Delay       float64
+	TimesToFire int
+	// Separator is the rune the connection was written with. A zero
+	// Separator writes with SeparatorComma.
+	Separator rune
+}
+
+// ParseOutput parses a connection written as
+// "target,input,param,delay,timestofire", with either separator style.
+func ParseOutput(name string, value string) (output Output, err error) {
+	output.Name = name
+	output.Separator = SeparatorComma
+	if strings.ContainsRune(value, SeparatorEscape) {
+		output.Separator = SeparatorEscape
+	}
+
+	fields := strings.Split(value, string(output.Separator))
+	if len(fields) < 5 {
+		return output, errors.New("malformed output: " + value)
+	}
+	last := len(fields) - 1
+	output.Target = fields[0]
+	output.Input = fields[1]
+	// Older editors didn't escape commas in the parameter, so anything
+	// between the input and the delay belongs to it
+	output.Param = strings.Join(fields[2:last-1], string(output.Separator))
+	if output.Delay, err = strconv.ParseFloat(strings.TrimSpace(fields[last-1]), 64); err != nil {
+		return output, err
+	}
+	if output.TimesToFire, err = strconv.Atoi(strings.TrimSpace(fields[last])); err != nil {
+		return output, err
+	}
+	return output, nil
+}
+
+// Value returns the connection as written in a connections block, using the
+// output's Separator
+func (output *Output) Value() string {
+	separator := output.Separator
+	if separator == 0 {
+		separator = SeparatorComma
+	}
+	return output.Format(separator)
+}
+
+// Format returns the connection as written in a connections block, using
+// the given separator
+func (output *Output) Format(separator rune) string {
+	return strings.Join([]string{
+		output.Target,
+		output.Input,
+		output.Param,
+		strconv.FormatFloat(output.Delay, 'f', -1, 64),
+		strconv.Itoa(output.TimesToFire),
+	}, string(separator))
+}
+
+// SetOutputSeparator changes the separator every connection in the map is
+// written with
+func (vmf *Vmf) SetOutputSeparator(separator rune) {
+	vmf.eachEntity(func(entity *Entity) {
+		for idx := range entity.Connections {
+			entity.Connections[idx].Separator = separator
+		}
+	})
+}
+
+// eachEntity calls fn for the world, every entity and every hidden entity
+func (vmf *Vmf) eachEntity(fn func(entity *Entity)) {
+	fn(&vmf.World)
+	for idx := range vmf.Entities {
+		fn(&vmf.Entities[idx])
+	}
+	for idx := range vmf.Hidden {
+		for entIdx := range vmf.Hidden[idx].Entities {
+			fn(&vmf.Hidden[idx].Entities[entIdx])
+		}
+	}
+}
+
+// IOGraph links every entity output in a map to the entities it targets
+type IOGraph struct {
+	Links []Link
+}
+
+// Link is a single output, and every entity its target matched
+type Link struct {
+	Source  *Entity
+	Output  Output
+	Targets []*Entity
+}
+
+// Dangling returns whether the output targets nothing in the map.
+// Special targets such as !activator and !self are resolved at runtime, so
+// are never considered dangling.
+func (link *Link) Dangling() bool {
+	return len(link.Targets) == 0 && !strings.HasPrefix(link.Output.Target, targetSpecialPrefix)
+}
+
+// IOGraph builds the I/O graph of every entity in the map, including hidden
+// entities. Targets are matched against targetname and classname, not case
+// sensitive, with support for a trailing * wildcard like the engine.
+// Entities are indexed by name, so only wildcard targets are matched by
+// scanning every entity.
+func (vmf *Vmf) IOGraph() *IOGraph {
+	var entities []*Entity
+	byName := map[string][]*Entity{}
+	vmf.eachEntity(func(entity *Entity) {
+		entities = append(entities, entity)
+		name, _ := entity.Property("targetname")
+		name = strings.ToLower(name)
+		className := strings.ToLower(entity.ClassName)
+		if len(name) > 0 {
+			byName[name] = append(byName[name], entity)
+		}
+		if len(className) > 0 && className != name {
+			byName[className] = append(byName[className], entity)
+		}
+	})
+
+	graph := &IOGraph{}
+	for _, source := range entities {
+		for _, output := range source.Connections {
+			link := Link{Source: source, Output: output}
+			if strings.HasSuffix(output.Target, targetWildcard) {
+				for _, target := range entities {
+					if target.matches(output.Target) {
+						link.Targets = append(link.Targets, target)
+					}
+				}
+			} else {
+				link.Targets = append(link.Targets, byName[strings.ToLower(output.Target)]...)
+			}
+			graph.Links = append(graph.Links, link)
+		}
+	}
+	return graph
+}
+
+// Dangling returns every link whose target matches no entity
+func (graph *IOGraph) Dangling() (links []Link) {
+	for idx := range graph.Links {
+		if graph.Links[idx].Dangling() {
+			links = append(links, graph.Links[idx])
+		}
+	}
+	return links
+}
+
+// Outputs returns every link fired by entity
+func (graph *IOGraph) Outputs(entity *Entity) (links []Link) {
+	for idx := range graph.Links {
+		if graph.Links[idx].Source == entity {
+			links = append(links, graph.Links[idx])
+		}
+	}
+	return links
+}
+
+// Inputs returns every link that targets entity
+func (graph *IOGraph) Inputs(entity *Entity) (links []Link) {
+	for idx := range graph.Links {
+		for _, target := range graph.Links[idx].Targets {
+			if target == entity {
+				links = append(links, graph.Links[idx])
+				break
+			}
+		}
+	}
+	return links
+}
+
+// matches returns whether an output target refers to this entity
+func (entity *Entity) matches(target string) bool {
+	if len(target) == 0 {
+		return false
+	}
+	name, _ := entity.Property("targetname")
+	return matchesName(name, target) || matchesName(entity.ClassName, target)
+}
+
+func matchesName(name string, target string) bool {
+	if len(name) == 0 {
+		return false
+	}
+	if strings.HasSuffix(target, targetWildcard) {
+		return strings.HasPrefix(strings.ToLower(name), strings.ToLower(strings.TrimSuffix(target, targetWildcard)))
+	}
+	return strings.EqualFold(name, target)
+}
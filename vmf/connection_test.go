@@ -0,0 +1,137 @@
+package vmf
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseOutput(t *testing.T) {
+	output, err := ParseOutput("OnTrigger", "door,Open,,0.5,-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := Output{Name: "OnTrigger", Target: "door", Input: "Open", Delay: 0.5, TimesToFire: -1, Separator: SeparatorComma}
+	if output != expected {
+		t.Errorf("unexpected output: %+v", output)
+	}
+
+	output, err = ParseOutput("OnTrigger", "script\x1bRunScriptCode\x1bprint(1, 2)\x1b0\x1b1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if output.Separator != SeparatorEscape || output.Param != "print(1, 2)" || output.TimesToFire != 1 {
+		t.Errorf("unexpected output: %+v", output)
+	}
+	if output.Format(SeparatorComma) != "script,RunScriptCode,print(1, 2),0,1" {
+		t.Errorf("unexpected formatted output: %s", output.Format(SeparatorComma))
+	}
+
+	// unescaped commas in the parameter of an old style output
+	output, err = ParseOutput("OnTrigger", "script,RunScriptCode,print(1, 2),0,1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if output.Param != "print(1, 2)" || output.Value() != "script,RunScriptCode,print(1, 2),0,1" {
+		t.Errorf("unexpected output: %+v", output)
+	}
+
+	if _, err := ParseOutput("OnTrigger", "door,Open"); err == nil {
+		t.Error("expected error, but received none")
+	}
+}
+
+const sampleIOVmf = `world
+{
+	"id" "1"
+	"classname" "worldspawn"
+}
+entity
+{
+	"id" "2"
+	"classname" "logic_relay"
+	"targetname" "relay"
+	connections
+	{
+		"OnTrigger" "Door_*,Open,,0,-1"
+		"OnTrigger" "missing,Kill,,0,-1"
+		"OnTrigger" "!self,Disable,,0,1"
+	}
+}
+entity
+{
+	"id" "3"
+	"classname" "func_door"
+	"targetname" "door_a"
+}
+hidden
+{
+	entity
+	{
+		"id" "4"
+		"classname" "func_door"
+		"targetname" "door_b"
+		connections
+		{
+			"OnOpen" "relay` + "\x1b" + `Trigger` + "\x1b\x1b" + `0` + "\x1b" + `-1"
+		}
+	}
+}
+`
+
+func TestVmf_IOGraph(t *testing.T) {
+	vmf := readVmf(t, sampleIOVmf)
+	graph := vmf.IOGraph()
+	if len(graph.Links) != 4 {
+		t.Fatalf("unexpected number of links: %d", len(graph.Links))
+	}
+
+	if len(graph.Links[0].Targets) != 2 {
+		t.Errorf("wildcard target matched %d entities", len(graph.Links[0].Targets))
+	}
+
+	dangling := graph.Dangling()
+	if len(dangling) != 1 || dangling[0].Output.Target != "missing" {
+		t.Errorf("unexpected dangling links: %+v", dangling)
+	}
+
+	relay := &vmf.Entities[0]
+	if len(graph.Outputs(relay)) != 3 {
+		t.Error("unexpected number of outputs for relay")
+	}
+	if inputs := graph.Inputs(relay); len(inputs) != 1 || inputs[0].Source.ID != 4 {
+		t.Errorf("unexpected inputs for relay: %+v", inputs)
+	}
+}
+
+func TestVmf_IOGraph_TargetOrder(t *testing.T) {
+	vmf := readVmf(t, "entity\n{\n\t\"id\" \"1\"\n\t\"classname\" \"logic_relay\"\n\tconnections\n\t{\n\t\t\"OnTrigger\" \"Logic_Relay,Trigger,,0,-1\"\n\t}\n}\n"+
+		"entity\n{\n\t\"id\" \"2\"\n\t\"classname\" \"info_target\"\n\t\"targetname\" \"logic_relay\"\n}\n"+
+		"entity\n{\n\t\"id\" \"3\"\n\t\"classname\" \"logic_relay\"\n\t\"targetname\" \"LOGIC_RELAY\"\n}\n")
+	graph := vmf.IOGraph()
+	if len(graph.Links) != 1 {
+		t.Fatalf("unexpected number of links: %d", len(graph.Links))
+	}
+	var ids []int
+	for _, target := range graph.Links[0].Targets {
+		ids = append(ids, target.ID)
+	}
+	if len(ids) != 3 || ids[0] != 1 || ids[1] != 2 || ids[2] != 3 {
+		t.Errorf("expected each matching entity once, in map order, got %v", ids)
+	}
+}
+
+func TestVmf_SetOutputSeparator(t *testing.T) {
+	vmf := readVmf(t, sampleIOVmf)
+	vmf.SetOutputSeparator(SeparatorEscape)
+	written := writeVmf(t, vmf)
+	if strings.Contains(written, "Door_*,Open") || !strings.Contains(written, "Door_*\x1bOpen") {
+		t.Error("outputs were not written with the requested separator")
+	}
+}
+
+func TestOutput_FormatSmallDelay(t *testing.T) {
+	output := Output{Target: "door", Input: "Open", Delay: 0.00001, TimesToFire: -1}
+	if output.Value() != "door,Open,,0.00001,-1" {
+		t.Errorf("unexpected output: %s", output.Value())
+	}
+}
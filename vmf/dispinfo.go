@@ -0,0 +1,247 @@
+package vmf
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+
+	"github.com/galaco/KeyValues"
+)
+
+const keyNormals = "normals"
+const keyDistances = "distances"
+const keyOffsets = "offsets"
+const keyOffsetNormals = "offset_normals"
+const keyAlphas = "alphas"
+const keyTriangleTags = "triangle_tags"
+const keyAllowedVerts = "allowed_verts"
+const keyAllowedVertsRow = "10"
+const rowPrefix = "row"
+
+// DispInfo is the displacement of a single brush side
+// Per-vertex data is stored as rows of the displacement grid, which has
+// (2^Power)+1 rows and columns. TriangleTags has 2^Power rows of
+// 2*(2^Power) tags.
+type DispInfo struct {
+	Power         int
+	StartPosition Vector
+	Flags         int
+	Elevation     float64
+	Subdiv        bool
+	Normals       [][]Vector
+	Distances     [][]float64
+	Offsets       [][]Vector
+	OffsetNormals [][]Vector
+	Alphas        [][]float64
+	TriangleTags  [][]int
+	AllowedVerts  []int
+	Unknown       []*keyvalues.KeyValue
+
+	// missing holds the keys and blocks the displacement was decoded without
+	missing map[string]bool
+}
+
+// dispInfoKeys are the properties and blocks of a displacement, which are
+// only written back if it was decoded with them
+var dispInfoKeys = []string{
+	"power",
+	"startposition",
+	"flags",
+	"elevation",
+	"subdiv",
+	keyNormals,
+	keyDistances,
+	keyOffsets,
+	keyOffsetNormals,
+	keyAlphas,
+	keyTriangleTags,
+	keyAllowedVerts,
+}
+
+// Size returns the number of vertices along each edge of the displacement
+func (disp *DispInfo) Size() int {
+	return (1 << uint(disp.Power)) + 1
+}
+
+// ParseVectorRow parses a row of vectors written as "x y z x y z ..."
+func ParseVectorRow(value string) ([]Vector, error) {
+	floats, err := parseFloats(value)
+	if err != nil {
+		return nil, err
+	}
+	if len(floats)%3 != 0 {
+		return nil, errors.New("vector row must be a multiple of 3 components")
+	}
+	row := make([]Vector, len(floats)/3)
+	for idx := range row {
+		row[idx] = Vector{X: floats[idx*3], Y: floats[idx*3+1], Z: floats[idx*3+2]}
+	}
+	return row, nil
+}
+
+// ParseFloatRow parses a row of space separated floats
+func ParseFloatRow(value string) ([]float64, error) {
+	return parseFloats(value)
+}
+
+// ParseIntRow parses a row of space separated integers
+func ParseIntRow(value string) (row []int, err error) {
+	for _, field := range strings.Fields(value) {
+		i, err := strconv.Atoi(field)
+		if err != nil {
+			return nil, err
+		}
+		row = append(row, i)
+	}
+	return row, nil
+}
+
+// FormatVectorRow writes a row of vectors as "x y z x y z ..."
+func FormatVectorRow(row []Vector) string {
+	fields := make([]string, len(row))
+	for idx, vec := range row {
+		fields[idx] = vec.String()
+	}
+	return strings.Join(fields, " ")
+}
+
+// FormatFloatRow writes a row of space separated floats
+func FormatFloatRow(row []float64) string {
+	return formatFloats(row...)
+}
+
+// FormatIntRow writes a row of space separated integers
+func FormatIntRow(row []int) string {
+	fields := make([]string, len(row))
+	for idx, i := range row {
+		fields[idx] = strconv.Itoa(i)
+	}
+	return strings.Join(fields, " ")
+}
+
+func (disp *DispInfo) toKeyValue() *keyvalues.KeyValue {
+	node := keyvalues.NewKeyValueArray(keyDispInfo)
+	addPresent(node, disp.missing,
+		newInt("power", disp.Power),
+		keyvalues.NewKeyValue("startposition", "["+disp.StartPosition.String()+"]"),
+		newInt("flags", disp.Flags),
+		newFloat("elevation", disp.Elevation),
+		newBool("subdiv", disp.Subdiv),
+		rows(keyNormals, len(disp.Normals), func(idx int) string { return FormatVectorRow(disp.Normals[idx]) }),
+		rows(keyDistances, len(disp.Distances), func(idx int) string { return FormatFloatRow(disp.Distances[idx]) }),
+		rows(keyOffsets, len(disp.Offsets), func(idx int) string { return FormatVectorRow(disp.Offsets[idx]) }),
+		rows(keyOffsetNormals, len(disp.OffsetNormals), func(idx int) string { return FormatVectorRow(disp.OffsetNormals[idx]) }),
+		rows(keyAlphas, len(disp.Alphas), func(idx int) string { return FormatFloatRow(disp.Alphas[idx]) }),
+		rows(keyTriangleTags, len(disp.TriangleTags), func(idx int) string { return FormatIntRow(disp.TriangleTags[idx]) }),
+		keyvalues.NewKeyValueArray(keyAllowedVerts,
+			keyvalues.NewKeyValue(keyAllowedVertsRow, FormatIntRow(disp.AllowedVerts))))
+
+	addAll(node, disp.Unknown)
+	return node
+}
+
+// rows builds a block of "rowN" keys
+func rows(key string, count int, row func(idx int) string) *keyvalues.KeyValue {
+	node := keyvalues.NewKeyValueArray(key)
+	for idx := 0; idx < count; idx++ {
+		node.AddChild(keyvalues.NewKeyValue(rowPrefix+strconv.Itoa(idx), row(idx)))
+	}
+	return node
+}
+
+func (d *decoder) dispInfo(block *keyvalues.KeyValue) *DispInfo {
+	disp := &DispInfo{
+		missing: missingKeys(block, dispInfoKeys...),
+	}
+	for _, child := range children(block) {
+		switch strings.ToLower(child.Key()) {
+		case "power":
+			disp.Power = d.int(child)
+		case "startposition":
+			disp.StartPosition = d.vector(child)
+		case "flags":
+			disp.Flags = d.int(child)
+		case "elevation":
+			disp.Elevation = d.float(child)
+		case "subdiv":
+			disp.Subdiv = d.bool(child)
+		case keyNormals:
+			disp.Normals = d.vectorRows(child)
+		case keyDistances:
+			disp.Distances = d.floatRows(child)
+		case keyOffsets:
+			disp.Offsets = d.vectorRows(child)
+		case keyOffsetNormals:
+			disp.OffsetNormals = d.vectorRows(child)
+		case keyAlphas:
+			disp.Alphas = d.floatRows(child)
+		case keyTriangleTags:
+			disp.TriangleTags = d.intRows(child)
+		case keyAllowedVerts:
+			if row, err := child.Find(keyAllowedVertsRow); err == nil {
+				disp.AllowedVerts = d.intRow(row)
+			}
+		default:
+			disp.Unknown = append(disp.Unknown, child)
+		}
+	}
+	return disp
+}
+
+// rowNodes returns the "rowN" children of a block ordered by N
+func (d *decoder) rowNodes(block *keyvalues.KeyValue) []*keyvalues.KeyValue {
+	nodes := children(block)
+	ordered := make([]*keyvalues.KeyValue, len(nodes))
+	for _, node := range nodes {
+		idx, err := strconv.Atoi(strings.TrimPrefix(strings.ToLower(node.Key()), rowPrefix))
+		if err != nil || idx < 0 || idx >= len(ordered) || ordered[idx] != nil {
+			d.fail(node, errors.New("unexpected displacement row in "+block.Key()))
+			return nil
+		}
+		ordered[idx] = node
+	}
+	return ordered
+}
+
+func (d *decoder) vectorRows(block *keyvalues.KeyValue) [][]Vector {
+	nodes := d.rowNodes(block)
+	rows := make([][]Vector, len(nodes))
+	for idx, node := range nodes {
+		row, err := ParseVectorRow(d.string(node))
+		if err != nil {
+			d.fail(node, err)
+		}
+		rows[idx] = row
+	}
+	return rows
+}
+
+func (d *decoder) floatRows(block *keyvalues.KeyValue) [][]float64 {
+	nodes := d.rowNodes(block)
+	rows := make([][]float64, len(nodes))
+	for idx, node := range nodes {
+		row, err := ParseFloatRow(d.string(node))
+		if err != nil {
+			d.fail(node, err)
+		}
+		rows[idx] = row
+	}
+	return rows
+}
+
+func (d *decoder) intRows(block *keyvalues.KeyValue) [][]int {
+	nodes := d.rowNodes(block)
+	rows := make([][]int, len(nodes))
+	for idx, node := range nodes {
+		rows[idx] = d.intRow(node)
+	}
+	return rows
+}
+
+func (d *decoder) intRow(node *keyvalues.KeyValue) []int {
+	row, err := ParseIntRow(d.string(node))
+	if err != nil {
+		d.fail(node, err)
+	}
+	return row
+}
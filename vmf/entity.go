@@ -0,0 +1,358 @@
+package vmf
+
+import (
+	"strings"
+
+	"github.com/galaco/KeyValues"
+)
+
+const keySolid = "solid"
+const keySide = "side"
+const keyEditor = "editor"
+const keyGroup = "group"
+const keyConnections = "connections"
+const keyDispInfo = "dispinfo"
+
+// Entity is a single map entity, or the world
+// Every key that holds a value, other than id and classname, is an entity
+// property and kept in Properties in the order it was written.
+type Entity struct {
+	ID          int
+	ClassName   string
+	Properties  []Property
+	Connections []Output
+	Solids      []Solid
+	Hidden      []Hidden
+	// Groups holds the editor groups of the world. Other entities don't have any.
+	Groups  []Group
+	Editor  *Editor
+	Unknown []*keyvalues.KeyValue
+}
+
+// Property is a single key and value of an entity
+type Property struct {
+	Key   string
+	Value string
+}
+
+// Hidden holds solids and entities that have been hidden in the editor
+type Hidden struct {
+	Solids   []Solid
+	Entities []Entity
+	Unknown  []*keyvalues.KeyValue
+}
+
+// Group is an editor group that solids and entities can belong to
+type Group struct {
+	ID      int
+	Editor  *Editor
+	Unknown []*keyvalues.KeyValue
+}
+
+// Solid is a single brush
+type Solid struct {
+	ID      int
+	Sides   []Side
+	Editor  *Editor
+	Unknown []*keyvalues.KeyValue
+}
+
+// Side is a single face of a brush
+type Side struct {
+	ID              int
+	Plane           Plane
+	Material        string
+	UAxis           TextureAxis
+	VAxis           TextureAxis
+	Rotation        float64
+	LightmapScale   int
+	SmoothingGroups int
+	DispInfo        *DispInfo
+	Unknown         []*keyvalues.KeyValue
+
+	// missing holds the keys the side was decoded without
+	missing map[string]bool
+}
+
+// sideKeys are the properties of a side, which are only written back if the
+// side was decoded with them
+var sideKeys = []string{"id", "plane", "material", "uaxis", "vaxis", "rotation", "lightmapscale", "smoothing_groups"}
+
+// Editor holds the editor-only state of a solid, entity or group
+type Editor struct {
+	Color             string
+	VisGroupIDs       []int
+	GroupID           int
+	VisGroupShown     bool
+	VisGroupAutoShown bool
+	Comments          string
+	LogicalPos        string
+	Unknown           []*keyvalues.KeyValue
+}
+
+// Property returns the value of the first property that matches key.
+// Keys are not case sensitive.
+func (entity *Entity) Property(key string) (string, bool) {
+	for _, prop := range entity.Properties {
+		if strings.EqualFold(prop.Key, key) {
+			return prop.Value, true
+		}
+	}
+	return "", false
+}
+
+// SetProperty replaces the value of the first property that matches key,
+// or adds the property if it doesn't exist.
+func (entity *Entity) SetProperty(key string, value string) {
+	for idx := range entity.Properties {
+		if strings.EqualFold(entity.Properties[idx].Key, key) {
+			entity.Properties[idx].Value = value
+			return
+		}
+	}
+	entity.Properties = append(entity.Properties, Property{Key: key, Value: value})
+}
+
+// RemoveProperty removes every property that matches key
+func (entity *Entity) RemoveProperty(key string) {
+	props := entity.Properties[:0]
+	for _, prop := range entity.Properties {
+		if !strings.EqualFold(prop.Key, key) {
+			props = append(props, prop)
+		}
+	}
+	entity.Properties = props
+}
+
+func (entity *Entity) toKeyValue(key string) *keyvalues.KeyValue {
+	node := keyvalues.NewKeyValueArray(key,
+		newInt("id", entity.ID))
+	if len(entity.ClassName) > 0 {
+		node.AddChild(keyvalues.NewKeyValue("classname", entity.ClassName))
+	}
+	for _, prop := range entity.Properties {
+		node.AddChild(keyvalues.NewKeyValue(prop.Key, prop.Value))
+	}
+	if len(entity.Connections) > 0 {
+		connections := keyvalues.NewKeyValueArray(keyConnections)
+		for _, conn := range entity.Connections {
+			connections.AddChild(keyvalues.NewKeyValue(conn.Name, conn.Value()))
+		}
+		node.AddChild(connections)
+	}
+	for idx := range entity.Solids {
+		node.AddChild(entity.Solids[idx].toKeyValue())
+	}
+	for idx := range entity.Hidden {
+		node.AddChild(entity.Hidden[idx].toKeyValue())
+	}
+	for idx := range entity.Groups {
+		node.AddChild(entity.Groups[idx].toKeyValue())
+	}
+	if entity.Editor != nil {
+		node.AddChild(entity.Editor.toKeyValue())
+	}
+	addAll(node, entity.Unknown)
+	return node
+}
+
+func (hidden *Hidden) toKeyValue() *keyvalues.KeyValue {
+	node := keyvalues.NewKeyValueArray(keyHidden)
+	for idx := range hidden.Solids {
+		node.AddChild(hidden.Solids[idx].toKeyValue())
+	}
+	for idx := range hidden.Entities {
+		node.AddChild(hidden.Entities[idx].toKeyValue(keyEntity))
+	}
+	addAll(node, hidden.Unknown)
+	return node
+}
+
+func (group *Group) toKeyValue() *keyvalues.KeyValue {
+	node := keyvalues.NewKeyValueArray(keyGroup,
+		newInt("id", group.ID))
+	if group.Editor != nil {
+		node.AddChild(group.Editor.toKeyValue())
+	}
+	addAll(node, group.Unknown)
+	return node
+}
+
+func (solid *Solid) toKeyValue() *keyvalues.KeyValue {
+	node := keyvalues.NewKeyValueArray(keySolid,
+		newInt("id", solid.ID))
+	for idx := range solid.Sides {
+		node.AddChild(solid.Sides[idx].toKeyValue())
+	}
+	if solid.Editor != nil {
+		node.AddChild(solid.Editor.toKeyValue())
+	}
+	addAll(node, solid.Unknown)
+	return node
+}
+
+func (side *Side) toKeyValue() *keyvalues.KeyValue {
+	node := keyvalues.NewKeyValueArray(keySide)
+	addPresent(node, side.missing,
+		newInt("id", side.ID),
+		keyvalues.NewKeyValue("plane", side.Plane.String()),
+		keyvalues.NewKeyValue("material", side.Material),
+		keyvalues.NewKeyValue("uaxis", side.UAxis.String()),
+		keyvalues.NewKeyValue("vaxis", side.VAxis.String()),
+		newFloat("rotation", side.Rotation),
+		newInt("lightmapscale", side.LightmapScale),
+		newInt("smoothing_groups", side.SmoothingGroups))
+	if side.DispInfo != nil {
+		node.AddChild(side.DispInfo.toKeyValue())
+	}
+	addAll(node, side.Unknown)
+	return node
+}
+
+func (editor *Editor) toKeyValue() *keyvalues.KeyValue {
+	node := keyvalues.NewKeyValueArray(keyEditor,
+		keyvalues.NewKeyValue("color", editor.Color))
+	for _, id := range editor.VisGroupIDs {
+		node.AddChild(newInt("visgroupid", id))
+	}
+	if editor.GroupID != 0 {
+		node.AddChild(newInt("groupid", editor.GroupID))
+	}
+	node.AddChild(newBool("visgroupshown", editor.VisGroupShown))
+	node.AddChild(newBool("visgroupautoshown", editor.VisGroupAutoShown))
+	if len(editor.Comments) > 0 {
+		node.AddChild(keyvalues.NewKeyValue("comments", editor.Comments))
+	}
+	if len(editor.LogicalPos) > 0 {
+		node.AddChild(keyvalues.NewKeyValue("logicalpos", editor.LogicalPos))
+	}
+	addAll(node, editor.Unknown)
+	return node
+}
+
+func (d *decoder) entity(block *keyvalues.KeyValue) (entity Entity) {
+	for _, child := range children(block) {
+		key := strings.ToLower(child.Key())
+		switch {
+		case key == "id" && !child.HasChildren():
+			entity.ID = d.int(child)
+		case key == "classname" && !child.HasChildren():
+			entity.ClassName = d.string(child)
+		case !child.HasChildren():
+			entity.Properties = append(entity.Properties, Property{Key: child.Key(), Value: d.string(child)})
+		case key == keyConnections:
+			for _, conn := range children(child) {
+				entity.Connections = append(entity.Connections, d.output(conn))
+			}
+		case key == keySolid:
+			entity.Solids = append(entity.Solids, d.solid(child))
+		case key == keyHidden:
+			entity.Hidden = append(entity.Hidden, d.hidden(child))
+		case key == keyGroup:
+			entity.Groups = append(entity.Groups, d.group(child))
+		case key == keyEditor:
+			entity.Editor = d.editor(child)
+		default:
+			entity.Unknown = append(entity.Unknown, child)
+		}
+	}
+	return entity
+}
+
+func (d *decoder) hidden(block *keyvalues.KeyValue) (hidden Hidden) {
+	for _, child := range children(block) {
+		switch strings.ToLower(child.Key()) {
+		case keySolid:
+			hidden.Solids = append(hidden.Solids, d.solid(child))
+		case keyEntity:
+			hidden.Entities = append(hidden.Entities, d.entity(child))
+		default:
+			hidden.Unknown = append(hidden.Unknown, child)
+		}
+	}
+	return hidden
+}
+
+func (d *decoder) group(block *keyvalues.KeyValue) (group Group) {
+	for _, child := range children(block) {
+		switch strings.ToLower(child.Key()) {
+		case "id":
+			group.ID = d.int(child)
+		case keyEditor:
+			group.Editor = d.editor(child)
+		default:
+			group.Unknown = append(group.Unknown, child)
+		}
+	}
+	return group
+}
+
+func (d *decoder) solid(block *keyvalues.KeyValue) (solid Solid) {
+	for _, child := range children(block) {
+		switch strings.ToLower(child.Key()) {
+		case "id":
+			solid.ID = d.int(child)
+		case keySide:
+			solid.Sides = append(solid.Sides, d.side(child))
+		case keyEditor:
+			solid.Editor = d.editor(child)
+		default:
+			solid.Unknown = append(solid.Unknown, child)
+		}
+	}
+	return solid
+}
+
+func (d *decoder) side(block *keyvalues.KeyValue) (side Side) {
+	side.missing = missingKeys(block, sideKeys...)
+	for _, child := range children(block) {
+		switch strings.ToLower(child.Key()) {
+		case "id":
+			side.ID = d.int(child)
+		case "plane":
+			side.Plane = d.plane(child)
+		case "material":
+			side.Material = d.string(child)
+		case "uaxis":
+			side.UAxis = d.textureAxis(child)
+		case "vaxis":
+			side.VAxis = d.textureAxis(child)
+		case "rotation":
+			side.Rotation = d.float(child)
+		case "lightmapscale":
+			side.LightmapScale = d.int(child)
+		case "smoothing_groups":
+			side.SmoothingGroups = d.int(child)
+		case keyDispInfo:
+			side.DispInfo = d.dispInfo(child)
+		default:
+			side.Unknown = append(side.Unknown, child)
+		}
+	}
+	return side
+}
+
+func (d *decoder) editor(block *keyvalues.KeyValue) *Editor {
+	editor := &Editor{}
+	for _, child := range children(block) {
+		switch strings.ToLower(child.Key()) {
+		case "color":
+			editor.Color = d.string(child)
+		case "visgroupid":
+			editor.VisGroupIDs = append(editor.VisGroupIDs, d.int(child))
+		case "groupid":
+			editor.GroupID = d.int(child)
+		case "visgroupshown":
+			editor.VisGroupShown = d.bool(child)
+		case "visgroupautoshown":
+			editor.VisGroupAutoShown = d.bool(child)
+		case "comments":
+			editor.Comments = d.string(child)
+		case "logicalpos":
+			editor.LogicalPos = d.string(child)
+		default:
+			editor.Unknown = append(editor.Unknown, child)
+		}
+	}
+	return editor
+}
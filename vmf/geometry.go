@@ -0,0 +1,163 @@
+package vmf
+
+import (
+	"errors"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Vector is a point or direction in map space
+type Vector struct {
+	X float64
+	Y float64
+	Z float64
+}
+
+// Plane is a brush face plane, defined by 3 points on it in clockwise order
+type Plane [3]Vector
+
+// TextureAxis is a side's texture projection along a single axis
+// Written as "[x y z offset] scale".
+type TextureAxis struct {
+	Axis   Vector
+	Offset float64
+	Scale  float64
+}
+
+// ParseVector parses a vector written as "x y z", "(x y z)" or "[x y z]"
+func ParseVector(value string) (Vector, error) {
+	floats, err := parseFloats(strings.Trim(strings.TrimSpace(value), "()[]"))
+	if err != nil {
+		return Vector{}, err
+	}
+	if len(floats) != 3 {
+		return Vector{}, errors.New("vector must have 3 components: " + value)
+	}
+	return Vector{X: floats[0], Y: floats[1], Z: floats[2]}, nil
+}
+
+// ParsePlane parses a plane written as "(x y z) (x y z) (x y z)"
+func ParsePlane(value string) (plane Plane, err error) {
+	points := strings.Split(strings.TrimSpace(value), ")")
+	// the final split is whatever followed the last ")"
+	if len(points) != 4 || len(strings.TrimSpace(points[3])) != 0 {
+		return plane, errors.New("plane must have 3 points: " + value)
+	}
+	for idx := range plane {
+		point := strings.TrimSpace(points[idx])
+		if !strings.HasPrefix(point, "(") {
+			return plane, errors.New("malformed plane point: " + value)
+		}
+		if plane[idx], err = ParseVector(point[1:]); err != nil {
+			return plane, err
+		}
+	}
+	return plane, nil
+}
+
+// ParseTextureAxis parses a texture axis written as "[x y z offset] scale"
+func ParseTextureAxis(value string) (axis TextureAxis, err error) {
+	parts := strings.Split(strings.TrimSpace(value), "]")
+	if len(parts) != 2 || !strings.HasPrefix(parts[0], "[") {
+		return axis, errors.New("malformed texture axis: " + value)
+	}
+	floats, err := parseFloats(parts[0][1:])
+	if err != nil {
+		return axis, err
+	}
+	if len(floats) != 4 {
+		return axis, errors.New("texture axis must have 4 components: " + value)
+	}
+	axis.Axis = Vector{X: floats[0], Y: floats[1], Z: floats[2]}
+	axis.Offset = floats[3]
+	if axis.Scale, err = strconv.ParseFloat(strings.TrimSpace(parts[1]), 64); err != nil {
+		return axis, err
+	}
+	return axis, nil
+}
+
+// String returns the vector as "x y z"
+func (vec Vector) String() string {
+	return formatFloats(vec.X, vec.Y, vec.Z)
+}
+
+// String returns the plane as "(x y z) (x y z) (x y z)"
+func (plane Plane) String() string {
+	return "(" + plane[0].String() + ") (" + plane[1].String() + ") (" + plane[2].String() + ")"
+}
+
+// String returns the texture axis as "[x y z offset] scale"
+func (axis TextureAxis) String() string {
+	return "[" + formatFloats(axis.Axis.X, axis.Axis.Y, axis.Axis.Z, axis.Offset) + "] " + formatFloats(axis.Scale)
+}
+
+// Normal returns the plane's unit normal, computed the same way vbsp does.
+func (plane Plane) Normal() Vector {
+	a := plane[0].Sub(plane[1])
+	b := plane[2].Sub(plane[1])
+	return a.Cross(b).Normalize()
+}
+
+// Distance returns the plane's distance from the origin along its normal
+func (plane Plane) Distance() float64 {
+	return plane[0].Dot(plane.Normal())
+}
+
+// Add returns vec + other
+func (vec Vector) Add(other Vector) Vector {
+	return Vector{X: vec.X + other.X, Y: vec.Y + other.Y, Z: vec.Z + other.Z}
+}
+
+// Sub returns vec - other
+func (vec Vector) Sub(other Vector) Vector {
+	return Vector{X: vec.X - other.X, Y: vec.Y - other.Y, Z: vec.Z - other.Z}
+}
+
+// Scale returns vec multiplied by scalar
+func (vec Vector) Scale(scalar float64) Vector {
+	return Vector{X: vec.X * scalar, Y: vec.Y * scalar, Z: vec.Z * scalar}
+}
+
+// Dot returns the dot product of vec and other
+func (vec Vector) Dot(other Vector) float64 {
+	return vec.X*other.X + vec.Y*other.Y + vec.Z*other.Z
+}
+
+// Cross returns the cross product of vec and other
+func (vec Vector) Cross(other Vector) Vector {
+	return Vector{
+		X: vec.Y*other.Z - vec.Z*other.Y,
+		Y: vec.Z*other.X - vec.X*other.Z,
+		Z: vec.X*other.Y - vec.Y*other.X,
+	}
+}
+
+// Normalize returns vec scaled to unit length.
+// A zero vector is returned unchanged.
+func (vec Vector) Normalize() Vector {
+	length := vec.Dot(vec)
+	if length == 0 {
+		return vec
+	}
+	return vec.Scale(1 / math.Sqrt(length))
+}
+
+func parseFloats(value string) (floats []float64, err error) {
+	for _, field := range strings.Fields(value) {
+		f, err := strconv.ParseFloat(field, 64)
+		if err != nil {
+			return nil, err
+		}
+		floats = append(floats, f)
+	}
+	return floats, nil
+}
+
+func formatFloats(values ...float64) string {
+	fields := make([]string, len(values))
+	for idx, value := range values {
+		fields[idx] = strconv.FormatFloat(value, 'f', -1, 64)
+	}
+	return strings.Join(fields, " ")
+}
@@ -0,0 +1,199 @@
+package vmf
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/galaco/KeyValues"
+)
+
+func TestParsePlane(t *testing.T) {
+	plane, err := ParsePlane("(-64 64 64) (64 64 64) (64 -64 64.5)")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if plane[0] != (Vector{X: -64, Y: 64, Z: 64}) || plane[2] != (Vector{X: 64, Y: -64, Z: 64.5}) {
+		t.Errorf("unexpected plane: %v", plane)
+	}
+	if plane.String() != "(-64 64 64) (64 64 64) (64 -64 64.5)" {
+		t.Errorf("unexpected plane string: %s", plane.String())
+	}
+
+	flat, _ := ParsePlane("(-64 64 64) (64 64 64) (64 -64 64)")
+	if flat.Normal() != (Vector{X: 0, Y: 0, Z: 1}) || flat.Distance() != 64 {
+		t.Errorf("unexpected plane normal: %v", flat.Normal())
+	}
+
+	for _, malformed := range []string{"(0 0 0) (1 1 1)", "(0 0 0) (1 1 1) (2 2)", "0 0 0 1 1 1 2 2 2", "(0 0 0) (1 1 1) (2 2 2) (3 3 3)"} {
+		if _, err := ParsePlane(malformed); err == nil {
+			t.Errorf("expected error for %s, but received none", malformed)
+		}
+	}
+}
+
+func TestParseTextureAxis(t *testing.T) {
+	axis, err := ParseTextureAxis("[1 0 0 -32] 0.25")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if axis.Axis != (Vector{X: 1}) || axis.Offset != -32 || axis.Scale != 0.25 {
+		t.Errorf("unexpected texture axis: %+v", axis)
+	}
+	if axis.String() != "[1 0 0 -32] 0.25" {
+		t.Errorf("unexpected texture axis string: %s", axis.String())
+	}
+
+	for _, malformed := range []string{"[1 0 0] 0.25", "1 0 0 0 0.25", "[1 0 0 0]"} {
+		if _, err := ParseTextureAxis(malformed); err == nil {
+			t.Errorf("expected error for %s, but received none", malformed)
+		}
+	}
+}
+
+func TestParseVector(t *testing.T) {
+	for _, value := range []string{"1 2.5 -3", "(1 2.5 -3)", "[1 2.5 -3]"} {
+		vec, err := ParseVector(value)
+		if err != nil {
+			t.Error(err)
+		}
+		if vec != (Vector{X: 1, Y: 2.5, Z: -3}) {
+			t.Errorf("unexpected vector for %s: %v", value, vec)
+		}
+	}
+}
+
+func TestVector_String(t *testing.T) {
+	vec := Vector{X: 1000000, Y: 0.00001, Z: -2.5}
+	if vec.String() != "1000000 0.00001 -2.5" {
+		t.Errorf("unexpected vector string: %s", vec.String())
+	}
+}
+
+const sampleDispInfo = `world
+{
+	"id" "1"
+	"classname" "worldspawn"
+	solid
+	{
+		"id" "2"
+		side
+		{
+			"id" "1"
+			"plane" "(-64 64 64) (64 64 64) (64 -64 64)"
+			"material" "NATURE/BLENDGRASSGRAVEL001A"
+			"uaxis" "[1 0 0 0] 0.25"
+			"vaxis" "[0 -1 0 0] 0.25"
+			"rotation" "0"
+			"lightmapscale" "16"
+			"smoothing_groups" "0"
+			dispinfo
+			{
+				"power" "1"
+				"startposition" "[-64 -64 64]"
+				"flags" "0"
+				"elevation" "0"
+				"subdiv" "0"
+				normals
+				{
+					"row0" "0 0 1 0 0 1 0 0 1"
+					"row1" "0 0 1 0 0 1 0 0 1"
+					"row2" "0 0 1 0 0 1 0 0 1"
+				}
+				distances
+				{
+					"row0" "0 1.5 0"
+					"row1" "0 8 0"
+					"row2" "0 0 0"
+				}
+				offsets
+				{
+					"row0" "0 0 0 0 0 0 0 0 0"
+					"row1" "0 0 0 0 0 0 0 0 0"
+					"row2" "0 0 0 0 0 0 0 0 0"
+				}
+				offset_normals
+				{
+					"row0" "0 0 1 0 0 1 0 0 1"
+					"row1" "0 0 1 0 0 1 0 0 1"
+					"row2" "0 0 1 0 0 1 0 0 1"
+				}
+				alphas
+				{
+					"row0" "0 255 0"
+					"row1" "0 0 0"
+					"row2" "0 0 0"
+				}
+				triangle_tags
+				{
+					"row0" "9 9 1 9"
+					"row1" "9 9 9 9"
+				}
+				allowed_verts
+				{
+					"10" "-1 -1 -1 -1 -1 -1 -1 -1 -1 -1"
+				}
+			}
+		}
+	}
+}
+`
+
+func TestDispInfo(t *testing.T) {
+	vmf := readVmf(t, sampleDispInfo)
+	disp := vmf.World.Solids[0].Sides[0].DispInfo
+	if disp == nil {
+		t.Fatal("dispinfo was not parsed")
+	}
+	if disp.Size() != 3 || len(disp.Normals) != 3 || len(disp.Normals[0]) != 3 {
+		t.Errorf("unexpected normals: %v", disp.Normals)
+	}
+	if disp.StartPosition != (Vector{X: -64, Y: -64, Z: 64}) {
+		t.Errorf("unexpected start position: %v", disp.StartPosition)
+	}
+	if disp.Distances[1][1] != 8 || disp.Alphas[0][1] != 255 || disp.TriangleTags[0][2] != 1 {
+		t.Error("unexpected displacement values")
+	}
+	if len(disp.AllowedVerts) != 10 {
+		t.Errorf("unexpected allowed verts: %v", disp.AllowedVerts)
+	}
+
+	written := writeVmf(t, vmf)
+	for _, expected := range []string{
+		"\"startposition\" \"[-64 -64 64]\"",
+		"\"row0\" \"0 1.5 0\"",
+		"\"row1\" \"9 9 9 9\"",
+		"\"10\" \"-1 -1 -1 -1 -1 -1 -1 -1 -1 -1\"",
+	} {
+		if !strings.Contains(written, expected) {
+			t.Errorf("written dispinfo is missing %s", expected)
+		}
+	}
+	if writeVmf(t, readVmf(t, written)) != written {
+		t.Error("round trip is not stable")
+	}
+}
+
+func TestDispInfo_OnlyBlocksRead(t *testing.T) {
+	src := "\"side\"\n{\n\t\"id\" \"1\"\n\t\"plane\" \"(0 0 0) (1 0 0) (1 1 0)\"\n\t\"material\" \"TOOLS/TOOLSNODRAW\"\n" +
+		"\t\"dispinfo\"\n\t{\n\t\t\"power\" \"2\"\n\t\t\"normals\"\n\t\t{\n\t\t\t\"row0\" \"0 0 1\"\n\t\t}\n\t}\n}\n"
+	reader := keyvalues.NewReader(strings.NewReader(src))
+	kv, err := reader.Read()
+	if err != nil {
+		t.Fatal(err)
+	}
+	d := decoder{}
+	side := d.side(&kv)
+	if d.err != nil {
+		t.Fatal(d.err)
+	}
+
+	var buf bytes.Buffer
+	writer := keyvalues.NewWriter(&buf)
+	if err = writer.Write(side.toKeyValue()); err != nil {
+		t.Fatal(err)
+	}
+	if buf.String() != src {
+		t.Errorf("side changed in a round trip:\n%s", buf.String())
+	}
+}
@@ -0,0 +1,218 @@
+package vmf
+
+import (
+	"strconv"
+	"strings"
+)
+
+// sideReferenceKeys are entity properties that hold a space separated list
+// of side ids, such as those of info_overlay and env_cubemap
+var sideReferenceKeys = map[string]bool{
+	"sides":  true,
+	"sides2": true,
+}
+
+// idAllocator hands out ids that aren't used anywhere in a map yet.
+// Hammer numbers the world, entities, solids and groups from one counter,
+// sides from another, and visgroups from a third.
+// Every renumbered side, group and visgroup is recorded, so that references
+// to them can be updated afterwards.
+type idAllocator struct {
+	object   int
+	side     int
+	visGroup int
+
+	sides     map[int]int
+	groups    map[int]int
+	visGroups map[int]int
+}
+
+// newIDAllocator creates an allocator that continues on from the highest
+// ids used in vmf
+func newIDAllocator(vmf *Vmf) *idAllocator {
+	ids := &idAllocator{
+		sides:     map[int]int{},
+		groups:    map[int]int{},
+		visGroups: map[int]int{},
+	}
+	ids.observeEntity(&vmf.World)
+	for idx := range vmf.Entities {
+		ids.observeEntity(&vmf.Entities[idx])
+	}
+	for idx := range vmf.Hidden {
+		ids.observeHidden(&vmf.Hidden[idx])
+	}
+	if vmf.VisGroups != nil {
+		ids.observeVisGroups(vmf.VisGroups.Groups)
+	}
+	return ids
+}
+
+// clearMappings forgets every recorded renumbering, so that references in
+// the next map renumbered aren't updated with another map's ids
+func (ids *idAllocator) clearMappings() {
+	ids.sides = map[int]int{}
+	ids.groups = map[int]int{}
+	ids.visGroups = map[int]int{}
+}
+
+func (ids *idAllocator) observeEntity(entity *Entity) {
+	ids.object = maxInt(ids.object, entity.ID)
+	for idx := range entity.Solids {
+		ids.observeSolid(&entity.Solids[idx])
+	}
+	for idx := range entity.Hidden {
+		ids.observeHidden(&entity.Hidden[idx])
+	}
+	for idx := range entity.Groups {
+		ids.object = maxInt(ids.object, entity.Groups[idx].ID)
+	}
+}
+
+func (ids *idAllocator) observeHidden(hidden *Hidden) {
+	for idx := range hidden.Solids {
+		ids.observeSolid(&hidden.Solids[idx])
+	}
+	for idx := range hidden.Entities {
+		ids.observeEntity(&hidden.Entities[idx])
+	}
+}
+
+func (ids *idAllocator) observeSolid(solid *Solid) {
+	ids.object = maxInt(ids.object, solid.ID)
+	for idx := range solid.Sides {
+		ids.side = maxInt(ids.side, solid.Sides[idx].ID)
+	}
+}
+
+func (ids *idAllocator) observeVisGroups(groups []VisGroup) {
+	for idx := range groups {
+		ids.visGroup = maxInt(ids.visGroup, groups[idx].ID)
+		ids.observeVisGroups(groups[idx].Children)
+	}
+}
+
+func (ids *idAllocator) nextObject() int {
+	ids.object++
+	return ids.object
+}
+
+func (ids *idAllocator) nextSide() int {
+	ids.side++
+	return ids.side
+}
+
+func (ids *idAllocator) nextVisGroup() int {
+	ids.visGroup++
+	return ids.visGroup
+}
+
+// renumberWorld gives everything in the world new ids. The world itself
+// keeps its id, as a map only has one.
+func (ids *idAllocator) renumberWorld(world *Entity) {
+	ids.renumberContents(world)
+}
+
+// renumberEntity gives an entity, and everything it contains, new ids
+func (ids *idAllocator) renumberEntity(entity *Entity) {
+	entity.ID = ids.nextObject()
+	ids.renumberContents(entity)
+}
+
+func (ids *idAllocator) renumberContents(entity *Entity) {
+	for idx := range entity.Solids {
+		ids.renumberSolid(&entity.Solids[idx])
+	}
+	for idx := range entity.Hidden {
+		ids.renumberHidden(&entity.Hidden[idx])
+	}
+	for idx := range entity.Groups {
+		group := &entity.Groups[idx]
+		ids.groups[group.ID] = ids.nextObject()
+		group.ID = ids.groups[group.ID]
+	}
+}
+
+func (ids *idAllocator) renumberHidden(hidden *Hidden) {
+	for idx := range hidden.Solids {
+		ids.renumberSolid(&hidden.Solids[idx])
+	}
+	for idx := range hidden.Entities {
+		ids.renumberEntity(&hidden.Entities[idx])
+	}
+}
+
+func (ids *idAllocator) renumberSolid(solid *Solid) {
+	solid.ID = ids.nextObject()
+	for idx := range solid.Sides {
+		side := &solid.Sides[idx]
+		ids.sides[side.ID] = ids.nextSide()
+		side.ID = ids.sides[side.ID]
+	}
+}
+
+func (ids *idAllocator) renumberVisGroups(groups []VisGroup) {
+	for idx := range groups {
+		ids.visGroups[groups[idx].ID] = ids.nextVisGroup()
+		groups[idx].ID = ids.visGroups[groups[idx].ID]
+		ids.renumberVisGroups(groups[idx].Children)
+	}
+}
+
+// remapEntity updates every reference an entity, and everything it contains,
+// holds to a renumbered object
+func (ids *idAllocator) remapEntity(entity *Entity) {
+	for idx := range entity.Properties {
+		prop := &entity.Properties[idx]
+		if sideReferenceKeys[strings.ToLower(prop.Key)] {
+			prop.Value = ids.remapSideList(prop.Value)
+		}
+	}
+	ids.remapEditor(entity.Editor)
+	for idx := range entity.Solids {
+		ids.remapEditor(entity.Solids[idx].Editor)
+	}
+	for idx := range entity.Hidden {
+		for solidIdx := range entity.Hidden[idx].Solids {
+			ids.remapEditor(entity.Hidden[idx].Solids[solidIdx].Editor)
+		}
+	}
+	for idx := range entity.Groups {
+		ids.remapEditor(entity.Groups[idx].Editor)
+	}
+}
+
+func (ids *idAllocator) remapEditor(editor *Editor) {
+	if editor == nil {
+		return
+	}
+	for idx, id := range editor.VisGroupIDs {
+		if newID, ok := ids.visGroups[id]; ok {
+			editor.VisGroupIDs[idx] = newID
+		}
+	}
+	if newID, ok := ids.groups[editor.GroupID]; ok {
+		editor.GroupID = newID
+	}
+}
+
+func (ids *idAllocator) remapSideList(value string) string {
+	fields := strings.Fields(value)
+	for idx, field := range fields {
+		id, err := strconv.Atoi(field)
+		if err != nil {
+			continue
+		}
+		if newID, ok := ids.sides[id]; ok {
+			fields[idx] = strconv.Itoa(newID)
+		}
+	}
+	return strings.Join(fields, " ")
+}
+
+func maxInt(a int, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
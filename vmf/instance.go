@@ -0,0 +1,342 @@
+package vmf
+
+import (
+	"errors"
+	"io/fs"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/galaco/KeyValues"
+)
+
+const classInstance = "func_instance"
+const classInstanceParms = "func_instance_parms"
+const instanceAutoName = "AutoInstance"
+const instanceNameSeparator = "-"
+const replacePrefix = "replace"
+const parmPrefix = "parm"
+const materialVariablePrefix = "#"
+
+// FixupStyle is how the names of entities in an instance are made unique
+type FixupStyle int
+
+const (
+	// FixupPrefix names entities "instancename-name"
+	FixupPrefix = FixupStyle(0)
+	// FixupPostfix names entities "name-instancename"
+	FixupPostfix = FixupStyle(1)
+	// FixupNone leaves entity names as they are
+	FixupNone = FixupStyle(2)
+)
+
+// fixupKeys are entity properties that hold the name of another entity
+var fixupKeys = map[string]bool{
+	"targetname":     true,
+	"parentname":     true,
+	"target":         true,
+	"filtername":     true,
+	"damagefilter":   true,
+	"lightingorigin": true,
+}
+
+// instance is a single func_instance placed in a map
+type instance struct {
+	file      string
+	name      string
+	style     FixupStyle
+	transform Transform
+	// replacements maps $variable (or #variable for materials) to its value
+	replacements map[string]string
+}
+
+// CollapseInstances replaces every func_instance in the map with the
+// contents of the map it references, the same way vbsp does when compiling.
+// Instances are loaded from fsys relative to the directory of mapPath, and
+// are collapsed recursively.
+//
+// Instance solids are moved into the world, and instance entities into the
+// map, with origin and angles transforms, name fixup and $replace parameters
+// applied. Everything collapsed from an instance is given new ids. Instance
+// visgroups and editor groups are not carried over.
+func (vmf *Vmf) CollapseInstances(fsys fs.FS, mapPath string) error {
+	return vmf.collapseInstances(fsys, mapPath, []string{mapPath})
+}
+
+func (vmf *Vmf) collapseInstances(fsys fs.FS, mapPath string, stack []string) error {
+	ids := newIDAllocator(vmf)
+
+	entities := vmf.Entities
+	vmf.Entities = nil
+	count := 0
+	for idx := range entities {
+		if !strings.EqualFold(entities[idx].ClassName, classInstance) {
+			vmf.Entities = append(vmf.Entities, entities[idx])
+			continue
+		}
+		count++
+		inst, err := newInstance(&entities[idx], count)
+		if err != nil {
+			return err
+		}
+		if len(inst.file) == 0 {
+			continue
+		}
+
+		file := path.Join(path.Dir(mapPath), inst.file)
+		for _, parent := range stack {
+			if parent == file {
+				return errors.New("instance references itself: " + file)
+			}
+		}
+		child, err := load(fsys, file)
+		if err != nil {
+			return err
+		}
+		if err = child.collapseInstances(fsys, file, append(stack, file)); err != nil {
+			return err
+		}
+
+		inst.apply(child)
+		ids.clearMappings()
+		for solidIdx := range child.World.Solids {
+			ids.renumberSolid(&child.World.Solids[solidIdx])
+		}
+		for entIdx := range child.Entities {
+			ids.renumberEntity(&child.Entities[entIdx])
+		}
+		for entIdx := range child.Entities {
+			ids.remapEntity(&child.Entities[entIdx])
+		}
+		vmf.World.Solids = append(vmf.World.Solids, child.World.Solids...)
+		vmf.Entities = append(vmf.Entities, child.Entities...)
+	}
+
+	return nil
+}
+
+// newInstance reads the properties of a func_instance
+func newInstance(entity *Entity, count int) (inst *instance, err error) {
+	inst = &instance{
+		replacements: map[string]string{},
+	}
+
+	file, _ := entity.Property("file")
+	inst.file = strings.Replace(file, "\\", "/", -1)
+
+	inst.name, _ = entity.Property("targetname")
+	if len(inst.name) == 0 {
+		inst.name = instanceAutoName + strconv.Itoa(count)
+	}
+
+	if style, ok := entity.Property("fixup_style"); ok && len(style) > 0 {
+		i, err := strconv.Atoi(style)
+		if err != nil {
+			return nil, errors.New("invalid fixup_style for instance " + inst.name)
+		}
+		inst.style = FixupStyle(i)
+	}
+
+	originValue, _ := entity.Property("origin")
+	origin, err := parseOptionalVector(originValue)
+	if err != nil {
+		return nil, err
+	}
+	anglesValue, _ := entity.Property("angles")
+	angles, err := parseOptionalAngles(anglesValue)
+	if err != nil {
+		return nil, err
+	}
+	inst.transform = NewTransform(origin, angles)
+
+	for _, prop := range entity.Properties {
+		if !strings.HasPrefix(strings.ToLower(prop.Key), replacePrefix) {
+			continue
+		}
+		variable, value := splitParameter(prop.Value)
+		if len(variable) > 0 {
+			inst.replacements[variable] = value
+		}
+	}
+
+	return inst, nil
+}
+
+// apply replaces parameters, fixes up names and transforms everything in a
+// loaded instance map
+func (inst *instance) apply(child *Vmf) {
+	// func_instance_parms declares parameters and their defaults; it only
+	// exists for the editor
+	entities := child.Entities[:0]
+	for idx := range child.Entities {
+		if strings.EqualFold(child.Entities[idx].ClassName, classInstanceParms) {
+			inst.addDefaults(&child.Entities[idx])
+			continue
+		}
+		entities = append(entities, child.Entities[idx])
+	}
+	child.Entities = entities
+
+	variables := inst.variables()
+	for idx := range child.World.Solids {
+		inst.applySolid(&child.World.Solids[idx], variables)
+	}
+	for idx := range child.Entities {
+		inst.applyEntity(&child.Entities[idx], variables)
+	}
+}
+
+// addDefaults adds the default value of every declared parameter that the
+// func_instance doesn't replace. Parameters are declared as "$name type default".
+func (inst *instance) addDefaults(parms *Entity) {
+	for _, prop := range parms.Properties {
+		if !strings.HasPrefix(strings.ToLower(prop.Key), parmPrefix) {
+			continue
+		}
+		variable, rest := splitParameter(prop.Value)
+		if _, ok := inst.replacements[variable]; ok || len(variable) == 0 {
+			continue
+		}
+		_, defaultValue := splitParameter(rest)
+		if len(defaultValue) > 0 {
+			inst.replacements[variable] = defaultValue
+		}
+	}
+}
+
+// variables returns every replacement variable, longest first, so that
+// $foo doesn't replace the start of $foobar
+func (inst *instance) variables() []string {
+	variables := make([]string, 0, len(inst.replacements))
+	for variable := range inst.replacements {
+		variables = append(variables, variable)
+	}
+	sort.Slice(variables, func(i, j int) bool {
+		if len(variables[i]) != len(variables[j]) {
+			return len(variables[i]) > len(variables[j])
+		}
+		return variables[i] < variables[j]
+	})
+	return variables
+}
+
+func (inst *instance) replace(value string, variables []string) string {
+	for _, variable := range variables {
+		if strings.HasPrefix(variable, materialVariablePrefix) {
+			continue
+		}
+		value = strings.Replace(value, variable, inst.replacements[variable], -1)
+	}
+	return value
+}
+
+// fixup makes an entity name unique to this instance.
+// Names starting with @ are global, and names starting with ! are special
+// targets, so neither are changed.
+func (inst *instance) fixup(name string) string {
+	if len(name) == 0 || strings.HasPrefix(name, "@") || strings.HasPrefix(name, targetSpecialPrefix) {
+		return name
+	}
+	switch inst.style {
+	case FixupPrefix:
+		return inst.name + instanceNameSeparator + name
+	case FixupPostfix:
+		return name + instanceNameSeparator + inst.name
+	default:
+		return name
+	}
+}
+
+func (inst *instance) applyEntity(entity *Entity, variables []string) {
+	for idx := range entity.Properties {
+		prop := &entity.Properties[idx]
+		prop.Value = inst.replace(prop.Value, variables)
+		key := strings.ToLower(prop.Key)
+		switch {
+		case fixupKeys[key]:
+			prop.Value = inst.fixup(prop.Value)
+		case key == "origin":
+			if origin, err := ParseVector(prop.Value); err == nil {
+				prop.Value = inst.transform.Point(origin).String()
+			}
+		case key == "angles":
+			if angles, err := ParseAngles(prop.Value); err == nil {
+				prop.Value = inst.transform.Angles(angles).String()
+			}
+		}
+	}
+	for idx := range entity.Connections {
+		output := &entity.Connections[idx]
+		output.Target = inst.fixup(inst.replace(output.Target, variables))
+		output.Input = inst.replace(output.Input, variables)
+		output.Param = inst.replace(output.Param, variables)
+	}
+	for idx := range entity.Solids {
+		inst.applySolid(&entity.Solids[idx], variables)
+	}
+	// hidden objects are dropped, as the editor would never compile them
+	entity.Hidden = nil
+	if entity.Editor != nil {
+		entity.Editor.VisGroupIDs = nil
+		entity.Editor.GroupID = 0
+	}
+}
+
+func (inst *instance) applySolid(solid *Solid, variables []string) {
+	for idx := range solid.Sides {
+		side := &solid.Sides[idx]
+		for _, variable := range variables {
+			if strings.HasPrefix(variable, materialVariablePrefix) && strings.EqualFold(side.Material, variable) {
+				side.Material = inst.replacements[variable]
+			}
+		}
+		side.Plane = inst.transform.Plane(side.Plane)
+		side.UAxis = inst.transform.TextureAxis(side.UAxis)
+		side.VAxis = inst.transform.TextureAxis(side.VAxis)
+		if side.DispInfo != nil {
+			inst.applyDispInfo(side.DispInfo)
+		}
+	}
+	if solid.Editor != nil {
+		solid.Editor.VisGroupIDs = nil
+		solid.Editor.GroupID = 0
+	}
+}
+
+func (inst *instance) applyDispInfo(disp *DispInfo) {
+	disp.StartPosition = inst.transform.Point(disp.StartPosition)
+	for _, rows := range [][][]Vector{disp.Normals, disp.Offsets, disp.OffsetNormals} {
+		for _, row := range rows {
+			for idx := range row {
+				row[idx] = inst.transform.Direction(row[idx])
+			}
+		}
+	}
+}
+
+// splitParameter splits "$variable value" at the first space
+func splitParameter(value string) (variable string, rest string) {
+	value = strings.TrimSpace(value)
+	idx := strings.Index(value, " ")
+	if idx < 0 {
+		return value, ""
+	}
+	return value[:idx], strings.TrimSpace(value[idx+1:])
+}
+
+// load reads and converts a vmf from fsys
+func load(fsys fs.FS, name string) (*Vmf, error) {
+	file, err := fsys.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	reader := keyvalues.NewReader(file)
+	kv, err := reader.Read()
+	if err != nil {
+		return nil, err
+	}
+	return FromKeyValue(&kv)
+}
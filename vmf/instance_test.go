@@ -0,0 +1,204 @@
+package vmf
+
+import (
+	"strconv"
+	"testing"
+	"testing/fstest"
+)
+
+const sampleInstanceParent = `world
+{
+	"id" "1"
+	"classname" "worldspawn"
+}
+entity
+{
+	"id" "2"
+	"classname" "func_instance"
+	"targetname" "inst"
+	"file" "instances\relay.vmf"
+	"origin" "100 0 0"
+	"angles" "0 90 0"
+	"fixup_style" "0"
+	"replace01" "$target door"
+}
+`
+
+const sampleInstance = `world
+{
+	"id" "1"
+	"classname" "worldspawn"
+	solid
+	{
+		"id" "2"
+		side
+		{
+			"id" "1"
+			"plane" "(0 0 0) (1 0 0) (0 1 0)"
+			"material" "#floor"
+			"uaxis" "[1 0 0 0] 0.25"
+			"vaxis" "[0 -1 0 0] 0.25"
+			"rotation" "0"
+			"lightmapscale" "16"
+			"smoothing_groups" "0"
+		}
+	}
+}
+entity
+{
+	"id" "3"
+	"classname" "func_instance_parms"
+	"parm1" "$target string"
+	"parm2" "#floor material dev/dev_measuregeneric01"
+}
+entity
+{
+	"id" "4"
+	"classname" "logic_relay"
+	"targetname" "relay"
+	"origin" "10 0 0"
+	"angles" "0 0 0"
+	connections
+	{
+		"OnTrigger" "$target,Open,,0,-1"
+		"OnTrigger" "@global,Trigger,,0,-1"
+	}
+}
+entity
+{
+	"id" "5"
+	"classname" "func_instance"
+	"file" "nested.vmf"
+	"origin" "0 0 64"
+}
+`
+
+const sampleNestedInstance = `world
+{
+	"id" "1"
+	"classname" "worldspawn"
+}
+entity
+{
+	"id" "2"
+	"classname" "info_target"
+	"targetname" "target"
+	"origin" "0 0 0"
+}
+`
+
+func TestVmf_CollapseInstances(t *testing.T) {
+	fsys := fstest.MapFS{
+		"maps/instances/relay.vmf":  &fstest.MapFile{Data: []byte(sampleInstance)},
+		"maps/instances/nested.vmf": &fstest.MapFile{Data: []byte(sampleNestedInstance)},
+	}
+	vmf := readVmf(t, sampleInstanceParent)
+	if err := vmf.CollapseInstances(fsys, "maps/parent.vmf"); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(vmf.Entities) != 2 {
+		t.Fatalf("unexpected number of entities: %d", len(vmf.Entities))
+	}
+	relay := vmf.Entities[0]
+	if name, _ := relay.Property("targetname"); name != "inst-relay" {
+		t.Errorf("unexpected relay name: %s", name)
+	}
+	if origin, _ := relay.Property("origin"); origin != "100 10 0" {
+		t.Errorf("unexpected relay origin: %s", origin)
+	}
+	if angles, _ := relay.Property("angles"); angles != "0 90 0" {
+		t.Errorf("unexpected relay angles: %s", angles)
+	}
+	if relay.Connections[0].Target != "inst-door" || relay.Connections[1].Target != "@global" {
+		t.Errorf("unexpected relay outputs: %+v", relay.Connections)
+	}
+
+	nested := vmf.Entities[1]
+	if name, _ := nested.Property("targetname"); name != "inst-AutoInstance1-target" {
+		t.Errorf("unexpected nested entity name: %s", name)
+	}
+	if origin, _ := nested.Property("origin"); origin != "100 0 64" {
+		t.Errorf("unexpected nested entity origin: %s", origin)
+	}
+
+	if len(vmf.World.Solids) != 1 {
+		t.Fatal("instance solids were not moved into the world")
+	}
+	side := vmf.World.Solids[0].Sides[0]
+	if side.Material != "dev/dev_measuregeneric01" {
+		t.Errorf("unexpected material: %s", side.Material)
+	}
+	if side.Plane.String() != "(100 0 0) (100 1 0) (99 0 0)" {
+		t.Errorf("unexpected plane: %s", side.Plane.String())
+	}
+	if side.UAxis.String() != "[0 1 0 0] 0.25" {
+		t.Errorf("unexpected uaxis: %s", side.UAxis.String())
+	}
+
+	ids := map[int]bool{vmf.World.ID: true}
+	for _, entity := range vmf.Entities {
+		ids[entity.ID] = true
+	}
+	ids[vmf.World.Solids[0].ID] = true
+	if len(ids) != 4 {
+		t.Errorf("collapsed ids are not unique: %v", ids)
+	}
+}
+
+func TestVmf_CollapseInstances_Recursive(t *testing.T) {
+	fsys := fstest.MapFS{
+		"maps/loop.vmf": &fstest.MapFile{Data: []byte("world\n{\n\t\"id\" \"1\"\n}\nentity\n{\n\t\"id\" \"2\"\n\t\"classname\" \"func_instance\"\n\t\"file\" \"loop.vmf\"\n}\n")},
+	}
+	vmf, err := load(fsys, "maps/loop.vmf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := vmf.CollapseInstances(fsys, "maps/loop.vmf"); err == nil {
+		t.Error("expected error, but received none")
+	}
+}
+
+func TestVmf_CollapseInstances_SideReferences(t *testing.T) {
+	overlay := `world
+{
+	"id" "1"
+	"classname" "worldspawn"
+	solid
+	{
+		"id" "2"
+		side
+		{
+			"id" "1"
+			"plane" "(0 0 0) (1 0 0) (0 1 0)"
+		}
+		side
+		{
+			"id" "2"
+			"plane" "(0 0 0) (0 1 0) (1 0 0)"
+		}
+	}
+}
+entity
+{
+	"id" "3"
+	"classname" "info_overlay"
+	"sides" "1 2 99"
+	"origin" "0 0 0"
+}
+`
+	fsys := fstest.MapFS{
+		"maps/overlay.vmf": &fstest.MapFile{Data: []byte(overlay)},
+	}
+	vmf := readVmf(t, "world\n{\n\t\"id\" \"1\"\n\tsolid\n\t{\n\t\t\"id\" \"2\"\n\t\tside\n\t\t{\n\t\t\t\"id\" \"5\"\n\t\t}\n\t}\n}\n"+
+		"entity\n{\n\t\"id\" \"3\"\n\t\"classname\" \"func_instance\"\n\t\"file\" \"overlay.vmf\"\n}\n")
+	if err := vmf.CollapseInstances(fsys, "maps/parent.vmf"); err != nil {
+		t.Fatal(err)
+	}
+
+	sides := vmf.World.Solids[1].Sides
+	expected := strconv.Itoa(sides[0].ID) + " " + strconv.Itoa(sides[1].ID) + " 99"
+	if value, _ := vmf.Entities[0].Property("sides"); value != expected {
+		t.Errorf("expected sides %s, got %s", expected, value)
+	}
+}
@@ -0,0 +1,40 @@
+package vmf
+
+// Merge moves everything in other into vmf.
+// Every id in other is renumbered to follow on from the highest ids in vmf,
+// and every visgroupid, groupid and side list that referred to a renumbered
+// object is updated to match. Visgroups, world solids, editor groups,
+// entities and hidden objects are merged; the world properties, view
+// settings, cameras, cordons and unknown blocks of other are not.
+func (vmf *Vmf) Merge(other *Vmf) {
+	ids := newIDAllocator(vmf)
+
+	if other.VisGroups != nil {
+		ids.renumberVisGroups(other.VisGroups.Groups)
+	}
+	ids.renumberWorld(&other.World)
+	for idx := range other.Entities {
+		ids.renumberEntity(&other.Entities[idx])
+	}
+	for idx := range other.Hidden {
+		ids.renumberHidden(&other.Hidden[idx])
+	}
+	other.eachEntity(ids.remapEntity)
+	for idx := range other.Hidden {
+		for solidIdx := range other.Hidden[idx].Solids {
+			ids.remapEditor(other.Hidden[idx].Solids[solidIdx].Editor)
+		}
+	}
+
+	if other.VisGroups != nil {
+		if vmf.VisGroups == nil {
+			vmf.VisGroups = &VisGroups{}
+		}
+		vmf.VisGroups.Groups = append(vmf.VisGroups.Groups, other.VisGroups.Groups...)
+	}
+	vmf.World.Solids = append(vmf.World.Solids, other.World.Solids...)
+	vmf.World.Hidden = append(vmf.World.Hidden, other.World.Hidden...)
+	vmf.World.Groups = append(vmf.World.Groups, other.World.Groups...)
+	vmf.Entities = append(vmf.Entities, other.Entities...)
+	vmf.Hidden = append(vmf.Hidden, other.Hidden...)
+}
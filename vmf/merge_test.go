@@ -0,0 +1,132 @@
+package vmf
+
+import (
+	"testing"
+)
+
+const sampleMergeBase = `visgroups
+{
+	visgroup
+	{
+		"name" "Base"
+		"visgroupid" "1"
+		"color" "0 0 0"
+	}
+}
+world
+{
+	"id" "1"
+	"classname" "worldspawn"
+	solid
+	{
+		"id" "2"
+		side
+		{
+			"id" "1"
+			"plane" "(0 0 0) (1 0 0) (0 1 0)"
+			"material" "TOOLS/TOOLSNODRAW"
+			"uaxis" "[1 0 0 0] 0.25"
+			"vaxis" "[0 -1 0 0] 0.25"
+			"rotation" "0"
+			"lightmapscale" "16"
+			"smoothing_groups" "0"
+		}
+	}
+}
+entity
+{
+	"id" "3"
+	"classname" "info_target"
+}
+`
+
+const sampleMergePrefab = `visgroups
+{
+	visgroup
+	{
+		"name" "Prefab"
+		"visgroupid" "1"
+		"color" "0 0 0"
+	}
+}
+world
+{
+	"id" "1"
+	"classname" "worldspawn"
+	solid
+	{
+		"id" "2"
+		side
+		{
+			"id" "1"
+			"plane" "(0 0 0) (1 0 0) (0 1 0)"
+			"material" "TOOLS/TOOLSNODRAW"
+			"uaxis" "[1 0 0 0] 0.25"
+			"vaxis" "[0 -1 0 0] 0.25"
+			"rotation" "0"
+			"lightmapscale" "16"
+			"smoothing_groups" "0"
+		}
+		editor
+		{
+			"color" "0 0 0"
+			"visgroupid" "1"
+			"groupid" "3"
+			"visgroupshown" "1"
+			"visgroupautoshown" "1"
+		}
+	}
+	group
+	{
+		"id" "3"
+		editor
+		{
+			"color" "0 0 0"
+			"visgroupid" "1"
+			"visgroupshown" "1"
+			"visgroupautoshown" "1"
+		}
+	}
+}
+entity
+{
+	"id" "4"
+	"classname" "info_overlay"
+	"sides" "1"
+	editor
+	{
+		"color" "0 0 0"
+		"visgroupid" "1"
+		"visgroupshown" "1"
+		"visgroupautoshown" "1"
+	}
+}
+`
+
+func TestVmf_Merge(t *testing.T) {
+	vmf := readVmf(t, sampleMergeBase)
+	vmf.Merge(readVmf(t, sampleMergePrefab))
+
+	if len(vmf.VisGroups.Groups) != 2 || vmf.VisGroups.Groups[1].ID != 2 {
+		t.Fatalf("unexpected visgroups: %+v", vmf.VisGroups)
+	}
+	if vmf.World.ID != 1 || len(vmf.World.Solids) != 2 || len(vmf.World.Groups) != 1 || len(vmf.Entities) != 2 {
+		t.Fatal("prefab contents were not merged")
+	}
+
+	solid := vmf.World.Solids[1]
+	group := vmf.World.Groups[0]
+	overlay := vmf.Entities[1]
+	if solid.ID != 4 || solid.Sides[0].ID != 2 || group.ID != 5 || overlay.ID != 6 {
+		t.Errorf("unexpected ids. solid: %d, side: %d, group: %d, overlay: %d", solid.ID, solid.Sides[0].ID, group.ID, overlay.ID)
+	}
+	if solid.Editor.VisGroupIDs[0] != 2 || solid.Editor.GroupID != group.ID {
+		t.Errorf("solid editor was not updated: %+v", solid.Editor)
+	}
+	if group.Editor.VisGroupIDs[0] != 2 || overlay.Editor.VisGroupIDs[0] != 2 {
+		t.Error("editor visgroups were not updated")
+	}
+	if sides, _ := overlay.Property("sides"); sides != "2" {
+		t.Errorf("overlay sides were not updated: %s", sides)
+	}
+}
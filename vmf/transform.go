@@ -0,0 +1,165 @@
+package vmf
+
+import (
+	"errors"
+	"math"
+	"strings"
+)
+
+// transformPrecision is the number of decimal places transformed values
+// are rounded to, so that rotations don't write values like 6.1e-17
+const transformPrecision = 1e6
+
+// Angles is an orientation in degrees, as written in an entity's angles key
+type Angles struct {
+	Pitch float64
+	Yaw   float64
+	Roll  float64
+}
+
+// ParseAngles parses angles written as "pitch yaw roll"
+func ParseAngles(value string) (Angles, error) {
+	floats, err := parseFloats(value)
+	if err != nil {
+		return Angles{}, err
+	}
+	if len(floats) != 3 {
+		return Angles{}, errors.New("angles must have 3 components: " + value)
+	}
+	return Angles{Pitch: floats[0], Yaw: floats[1], Roll: floats[2]}, nil
+}
+
+// String returns the angles as "pitch yaw roll"
+func (angles Angles) String() string {
+	return formatFloats(angles.Pitch, angles.Yaw, angles.Roll)
+}
+
+// Transform is a rotation followed by a translation
+type Transform struct {
+	Origin   Vector
+	rotation [3][3]float64
+}
+
+// NewTransform creates a Transform that rotates by angles, then moves by origin
+func NewTransform(origin Vector, angles Angles) Transform {
+	return Transform{
+		Origin:   origin,
+		rotation: angleMatrix(angles),
+	}
+}
+
+// Point transforms a position
+func (transform *Transform) Point(point Vector) Vector {
+	return roundVector(transform.rotate(point).Add(transform.Origin))
+}
+
+// Direction rotates a direction, ignoring the translation
+func (transform *Transform) Direction(direction Vector) Vector {
+	return roundVector(transform.rotate(direction))
+}
+
+// Angles rotates an orientation
+func (transform *Transform) Angles(angles Angles) Angles {
+	local := angleMatrix(angles)
+	var combined [3][3]float64
+	for i := 0; i < 3; i++ {
+		for j := 0; j < 3; j++ {
+			for k := 0; k < 3; k++ {
+				combined[i][j] += transform.rotation[i][k] * local[k][j]
+			}
+		}
+	}
+	return matrixAngles(combined)
+}
+
+// Plane transforms every point of a plane
+func (transform *Transform) Plane(plane Plane) Plane {
+	for idx := range plane {
+		plane[idx] = transform.Point(plane[idx])
+	}
+	return plane
+}
+
+// TextureAxis rotates a texture axis, and shifts its offset so the texture
+// stays locked to the transformed face
+func (transform *Transform) TextureAxis(axis TextureAxis) TextureAxis {
+	axis.Axis = transform.Direction(axis.Axis)
+	if axis.Scale != 0 {
+		axis.Offset = round(axis.Offset - transform.Origin.Dot(axis.Axis)/axis.Scale)
+	}
+	return axis
+}
+
+func (transform *Transform) rotate(vec Vector) Vector {
+	m := &transform.rotation
+	return Vector{
+		X: m[0][0]*vec.X + m[0][1]*vec.Y + m[0][2]*vec.Z,
+		Y: m[1][0]*vec.X + m[1][1]*vec.Y + m[1][2]*vec.Z,
+		Z: m[2][0]*vec.X + m[2][1]*vec.Y + m[2][2]*vec.Z,
+	}
+}
+
+// angleMatrix builds a rotation matrix the same way the engine's AngleMatrix
+// does; columns are the forward, left and up vectors
+func angleMatrix(angles Angles) (m [3][3]float64) {
+	sp, cp := math.Sincos(angles.Pitch * math.Pi / 180)
+	sy, cy := math.Sincos(angles.Yaw * math.Pi / 180)
+	sr, cr := math.Sincos(angles.Roll * math.Pi / 180)
+
+	m[0][0] = cp * cy
+	m[1][0] = cp * sy
+	m[2][0] = -sp
+	m[0][1] = sr*sp*cy - cr*sy
+	m[1][1] = sr*sp*sy + cr*cy
+	m[2][1] = sr * cp
+	m[0][2] = cr*sp*cy + sr*sy
+	m[1][2] = cr*sp*sy - sr*cy
+	m[2][2] = cr * cp
+	return m
+}
+
+// matrixAngles is the inverse of angleMatrix, matching the engine's MatrixAngles
+func matrixAngles(m [3][3]float64) (angles Angles) {
+	xyDist := math.Sqrt(m[0][0]*m[0][0] + m[1][0]*m[1][0])
+	angles.Pitch = math.Atan2(-m[2][0], xyDist)
+	if xyDist > 0.001 {
+		angles.Yaw = math.Atan2(m[1][0], m[0][0])
+		angles.Roll = math.Atan2(m[2][1], m[2][2])
+	} else {
+		angles.Yaw = math.Atan2(-m[0][1], m[1][1])
+	}
+	return Angles{
+		Pitch: round(angles.Pitch * 180 / math.Pi),
+		Yaw:   round(angles.Yaw * 180 / math.Pi),
+		Roll:  round(angles.Roll * 180 / math.Pi),
+	}
+}
+
+func roundVector(vec Vector) Vector {
+	return Vector{X: round(vec.X), Y: round(vec.Y), Z: round(vec.Z)}
+}
+
+// round limits a value to transformPrecision, and never returns negative zero
+func round(value float64) float64 {
+	value = math.Round(value*transformPrecision) / transformPrecision
+	if value == 0 {
+		return 0
+	}
+	return value
+}
+
+// parseOptionalVector parses a vector, treating an empty value as the origin
+func parseOptionalVector(value string) (Vector, error) {
+	if len(strings.TrimSpace(value)) == 0 {
+		return Vector{}, nil
+	}
+	return ParseVector(value)
+}
+
+// parseOptionalAngles parses angles, treating an empty value as no rotation
+func parseOptionalAngles(value string) (Angles, error) {
+	if len(strings.TrimSpace(value)) == 0 {
+		return Angles{}, nil
+	}
+	return ParseAngles(value)
+}
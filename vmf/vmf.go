@@ -0,0 +1,543 @@
+package vmf
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+
+	"github.com/galaco/KeyValues"
+)
+
+const keyVersionInfo = "versioninfo"
+const keyVisGroups = "visgroups"
+const keyVisGroup = "visgroup"
+const keyViewSettings = "viewsettings"
+const keyWorld = "world"
+const keyEntity = "entity"
+const keyHidden = "hidden"
+const keyCameras = "cameras"
+const keyCamera = "camera"
+const keyCordons = "cordons"
+const keyCordon = "cordon"
+
+// Vmf is a typed model of a Hammer map file
+// Blocks and keys that aren't part of the model are kept in Unknown, so
+// that converting back to a KeyValue tree loses nothing.
+// The editor blocks are nil if the file didn't have them, and are only
+// written back if they are set.
+type Vmf struct {
+	VersionInfo  VersionInfo
+	VisGroups    *VisGroups
+	ViewSettings *ViewSettings
+	World        Entity
+	Entities     []Entity
+	Hidden       []Hidden
+	Cameras      *Cameras
+	Cordons      *Cordons
+	Unknown      []*keyvalues.KeyValue
+}
+
+// VersionInfo describes the editor that wrote a map
+type VersionInfo struct {
+	EditorVersion int
+	EditorBuild   int
+	MapVersion    int
+	FormatVersion int
+	Prefab        bool
+	Unknown       []*keyvalues.KeyValue
+}
+
+// VisGroups are the editor's visgroups
+type VisGroups struct {
+	Groups  []VisGroup
+	Unknown []*keyvalues.KeyValue
+}
+
+// VisGroup is a named, nestable group used to toggle visibility in the editor
+type VisGroup struct {
+	Name     string
+	ID       int
+	Color    string
+	Children []VisGroup
+	Unknown  []*keyvalues.KeyValue
+}
+
+// ViewSettings are the editor's grid settings
+type ViewSettings struct {
+	SnapToGrid      bool
+	ShowGrid        bool
+	ShowLogicalGrid bool
+	GridSpacing     int
+	Show3DGrid      bool
+	Unknown         []*keyvalues.KeyValue
+}
+
+// Cameras are the editor's saved 3d view cameras
+type Cameras struct {
+	ActiveCamera int
+	Cameras      []Camera
+	Unknown      []*keyvalues.KeyValue
+}
+
+// Camera is a single saved 3d view camera
+type Camera struct {
+	Position string
+	Look     string
+	Unknown  []*keyvalues.KeyValue
+}
+
+// Cordons are the editor's cordon volumes
+// Older editors write a single top-level cordon block instead; that block
+// is kept in Vmf.Unknown.
+type Cordons struct {
+	Active  bool
+	Cordons []Cordon
+	Unknown []*keyvalues.KeyValue
+}
+
+// Cordon is a single named set of cordon boxes
+type Cordon struct {
+	Name    string
+	Active  bool
+	Boxes   []Box
+	Unknown []*keyvalues.KeyValue
+}
+
+// Box is an axis-aligned volume
+type Box struct {
+	Mins    string
+	Maxs    string
+	Unknown []*keyvalues.KeyValue
+}
+
+// FromKeyValue builds a Vmf from a parsed KeyValue tree.
+// The tree is expected to be as returned by Reader: a `$root` node holding
+// every top-level block of the file.
+func FromKeyValue(root *keyvalues.KeyValue) (*Vmf, error) {
+	blocks := []*keyvalues.KeyValue{root}
+	if root.Key() == keyvalues.RootNodeKey {
+		blocks, _ = root.Children()
+	}
+
+	d := decoder{}
+	vmf := &Vmf{}
+	for _, block := range blocks {
+		switch strings.ToLower(block.Key()) {
+		case keyVersionInfo:
+			vmf.VersionInfo = d.versionInfo(block)
+		case keyVisGroups:
+			vmf.VisGroups = d.visGroups(block)
+		case keyViewSettings:
+			vmf.ViewSettings = d.viewSettings(block)
+		case keyWorld:
+			vmf.World = d.entity(block)
+		case keyEntity:
+			vmf.Entities = append(vmf.Entities, d.entity(block))
+		case keyHidden:
+			vmf.Hidden = append(vmf.Hidden, d.hidden(block))
+		case keyCameras:
+			vmf.Cameras = d.cameras(block)
+		case keyCordons:
+			vmf.Cordons = d.cordons(block)
+		default:
+			vmf.Unknown = append(vmf.Unknown, block)
+		}
+	}
+
+	if d.err != nil {
+		return nil, d.err
+	}
+	return vmf, nil
+}
+
+// ToKeyValue converts a Vmf back into a KeyValue tree that can be written
+// with Writer. Blocks are emitted in the order Hammer writes them.
+func (vmf *Vmf) ToKeyValue() *keyvalues.KeyValue {
+	root := keyvalues.NewKeyValueArray(keyvalues.RootNodeKey)
+
+	root.AddChild(vmf.VersionInfo.toKeyValue())
+	if vmf.VisGroups != nil {
+		root.AddChild(vmf.VisGroups.toKeyValue())
+	}
+	if vmf.ViewSettings != nil {
+		root.AddChild(vmf.ViewSettings.toKeyValue())
+	}
+	root.AddChild(vmf.World.toKeyValue(keyWorld))
+	for idx := range vmf.Entities {
+		root.AddChild(vmf.Entities[idx].toKeyValue(keyEntity))
+	}
+	for idx := range vmf.Hidden {
+		root.AddChild(vmf.Hidden[idx].toKeyValue())
+	}
+	if vmf.Cameras != nil {
+		root.AddChild(vmf.Cameras.toKeyValue())
+	}
+	if vmf.Cordons != nil {
+		root.AddChild(vmf.Cordons.toKeyValue())
+	}
+	addAll(root, vmf.Unknown)
+
+	return root
+}
+
+func (info *VersionInfo) toKeyValue() *keyvalues.KeyValue {
+	node := keyvalues.NewKeyValueArray(keyVersionInfo,
+		newInt("editorversion", info.EditorVersion),
+		newInt("editorbuild", info.EditorBuild),
+		newInt("mapversion", info.MapVersion),
+		newInt("formatversion", info.FormatVersion),
+		newBool("prefab", info.Prefab))
+	addAll(node, info.Unknown)
+	return node
+}
+
+func (groups *VisGroups) toKeyValue() *keyvalues.KeyValue {
+	node := keyvalues.NewKeyValueArray(keyVisGroups)
+	for idx := range groups.Groups {
+		node.AddChild(groups.Groups[idx].toKeyValue())
+	}
+	addAll(node, groups.Unknown)
+	return node
+}
+
+func (group *VisGroup) toKeyValue() *keyvalues.KeyValue {
+	node := keyvalues.NewKeyValueArray(keyVisGroup,
+		keyvalues.NewKeyValue("name", group.Name),
+		newInt("visgroupid", group.ID),
+		keyvalues.NewKeyValue("color", group.Color))
+	for idx := range group.Children {
+		node.AddChild(group.Children[idx].toKeyValue())
+	}
+	addAll(node, group.Unknown)
+	return node
+}
+
+func (settings *ViewSettings) toKeyValue() *keyvalues.KeyValue {
+	node := keyvalues.NewKeyValueArray(keyViewSettings,
+		newBool("bSnapToGrid", settings.SnapToGrid),
+		newBool("bShowGrid", settings.ShowGrid),
+		newBool("bShowLogicalGrid", settings.ShowLogicalGrid),
+		newInt("nGridSpacing", settings.GridSpacing),
+		newBool("bShow3DGrid", settings.Show3DGrid))
+	addAll(node, settings.Unknown)
+	return node
+}
+
+func (cameras *Cameras) toKeyValue() *keyvalues.KeyValue {
+	node := keyvalues.NewKeyValueArray(keyCameras,
+		newInt("activecamera", cameras.ActiveCamera))
+	for idx := range cameras.Cameras {
+		camera := keyvalues.NewKeyValueArray(keyCamera,
+			keyvalues.NewKeyValue("position", cameras.Cameras[idx].Position),
+			keyvalues.NewKeyValue("look", cameras.Cameras[idx].Look))
+		addAll(camera, cameras.Cameras[idx].Unknown)
+		node.AddChild(camera)
+	}
+	addAll(node, cameras.Unknown)
+	return node
+}
+
+func (cordons *Cordons) toKeyValue() *keyvalues.KeyValue {
+	node := keyvalues.NewKeyValueArray(keyCordons,
+		newBool("active", cordons.Active))
+	for idx := range cordons.Cordons {
+		cordon := &cordons.Cordons[idx]
+		child := keyvalues.NewKeyValueArray(keyCordon,
+			keyvalues.NewKeyValue("name", cordon.Name),
+			newBool("active", cordon.Active))
+		for boxIdx := range cordon.Boxes {
+			box := keyvalues.NewKeyValueArray("box",
+				keyvalues.NewKeyValue("mins", cordon.Boxes[boxIdx].Mins),
+				keyvalues.NewKeyValue("maxs", cordon.Boxes[boxIdx].Maxs))
+			addAll(box, cordon.Boxes[boxIdx].Unknown)
+			child.AddChild(box)
+		}
+		addAll(child, cordon.Unknown)
+		node.AddChild(child)
+	}
+	addAll(node, cordons.Unknown)
+	return node
+}
+
+// decoder converts KeyValue nodes into model types.
+// The first malformed value encountered is recorded in err, so that
+// decoding functions don't each need to return an error.
+type decoder struct {
+	err error
+}
+
+func (d *decoder) string(node *keyvalues.KeyValue) string {
+	value, err := node.Value()
+	if err != nil {
+		d.fail(node, err)
+	}
+	return value
+}
+
+func (d *decoder) int(node *keyvalues.KeyValue) int {
+	value := d.string(node)
+	if len(value) == 0 {
+		return 0
+	}
+	i, err := strconv.Atoi(value)
+	if err != nil {
+		d.fail(node, err)
+	}
+	return i
+}
+
+func (d *decoder) float(node *keyvalues.KeyValue) float64 {
+	value := d.string(node)
+	if len(value) == 0 {
+		return 0
+	}
+	f, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		d.fail(node, err)
+	}
+	return f
+}
+
+func (d *decoder) vector(node *keyvalues.KeyValue) Vector {
+	vec, err := ParseVector(d.string(node))
+	if err != nil {
+		d.fail(node, err)
+	}
+	return vec
+}
+
+func (d *decoder) plane(node *keyvalues.KeyValue) Plane {
+	plane, err := ParsePlane(d.string(node))
+	if err != nil {
+		d.fail(node, err)
+	}
+	return plane
+}
+
+func (d *decoder) textureAxis(node *keyvalues.KeyValue) TextureAxis {
+	axis, err := ParseTextureAxis(d.string(node))
+	if err != nil {
+		d.fail(node, err)
+	}
+	return axis
+}
+
+func (d *decoder) output(node *keyvalues.KeyValue) Output {
+	output, err := ParseOutput(node.Key(), d.string(node))
+	if err != nil {
+		d.fail(node, err)
+	}
+	return output
+}
+
+func (d *decoder) bool(node *keyvalues.KeyValue) bool {
+	return d.int(node) != 0
+}
+
+func (d *decoder) fail(node *keyvalues.KeyValue, err error) {
+	if d.err == nil {
+		d.err = errors.New("invalid value for key " + node.Key() + ": " + err.Error())
+	}
+}
+
+func (d *decoder) versionInfo(block *keyvalues.KeyValue) (info VersionInfo) {
+	for _, child := range children(block) {
+		switch strings.ToLower(child.Key()) {
+		case "editorversion":
+			info.EditorVersion = d.int(child)
+		case "editorbuild":
+			info.EditorBuild = d.int(child)
+		case "mapversion":
+			info.MapVersion = d.int(child)
+		case "formatversion":
+			info.FormatVersion = d.int(child)
+		case "prefab":
+			info.Prefab = d.bool(child)
+		default:
+			info.Unknown = append(info.Unknown, child)
+		}
+	}
+	return info
+}
+
+func (d *decoder) visGroups(block *keyvalues.KeyValue) *VisGroups {
+	groups := &VisGroups{}
+	for _, child := range children(block) {
+		if strings.ToLower(child.Key()) == keyVisGroup {
+			groups.Groups = append(groups.Groups, d.visGroup(child))
+		} else {
+			groups.Unknown = append(groups.Unknown, child)
+		}
+	}
+	return groups
+}
+
+func (d *decoder) visGroup(block *keyvalues.KeyValue) (group VisGroup) {
+	for _, child := range children(block) {
+		switch strings.ToLower(child.Key()) {
+		case "name":
+			group.Name = d.string(child)
+		case "visgroupid":
+			group.ID = d.int(child)
+		case "color":
+			group.Color = d.string(child)
+		case keyVisGroup:
+			group.Children = append(group.Children, d.visGroup(child))
+		default:
+			group.Unknown = append(group.Unknown, child)
+		}
+	}
+	return group
+}
+
+func (d *decoder) viewSettings(block *keyvalues.KeyValue) *ViewSettings {
+	settings := &ViewSettings{}
+	for _, child := range children(block) {
+		switch strings.ToLower(child.Key()) {
+		case "bsnaptogrid":
+			settings.SnapToGrid = d.bool(child)
+		case "bshowgrid":
+			settings.ShowGrid = d.bool(child)
+		case "bshowlogicalgrid":
+			settings.ShowLogicalGrid = d.bool(child)
+		case "ngridspacing":
+			settings.GridSpacing = d.int(child)
+		case "bshow3dgrid":
+			settings.Show3DGrid = d.bool(child)
+		default:
+			settings.Unknown = append(settings.Unknown, child)
+		}
+	}
+	return settings
+}
+
+func (d *decoder) cameras(block *keyvalues.KeyValue) *Cameras {
+	cameras := &Cameras{}
+	for _, child := range children(block) {
+		switch strings.ToLower(child.Key()) {
+		case "activecamera":
+			cameras.ActiveCamera = d.int(child)
+		case keyCamera:
+			camera := Camera{}
+			for _, prop := range children(child) {
+				switch strings.ToLower(prop.Key()) {
+				case "position":
+					camera.Position = d.string(prop)
+				case "look":
+					camera.Look = d.string(prop)
+				default:
+					camera.Unknown = append(camera.Unknown, prop)
+				}
+			}
+			cameras.Cameras = append(cameras.Cameras, camera)
+		default:
+			cameras.Unknown = append(cameras.Unknown, child)
+		}
+	}
+	return cameras
+}
+
+func (d *decoder) cordons(block *keyvalues.KeyValue) *Cordons {
+	cordons := &Cordons{}
+	for _, child := range children(block) {
+		switch strings.ToLower(child.Key()) {
+		case "active":
+			cordons.Active = d.bool(child)
+		case keyCordon:
+			cordons.Cordons = append(cordons.Cordons, d.cordon(child))
+		default:
+			cordons.Unknown = append(cordons.Unknown, child)
+		}
+	}
+	return cordons
+}
+
+func (d *decoder) cordon(block *keyvalues.KeyValue) (cordon Cordon) {
+	for _, child := range children(block) {
+		switch strings.ToLower(child.Key()) {
+		case "name":
+			cordon.Name = d.string(child)
+		case "active":
+			cordon.Active = d.bool(child)
+		case "box":
+			box := Box{}
+			for _, prop := range children(child) {
+				switch strings.ToLower(prop.Key()) {
+				case "mins":
+					box.Mins = d.string(prop)
+				case "maxs":
+					box.Maxs = d.string(prop)
+				default:
+					box.Unknown = append(box.Unknown, prop)
+				}
+			}
+			cordon.Boxes = append(cordon.Boxes, box)
+		default:
+			cordon.Unknown = append(cordon.Unknown, child)
+		}
+	}
+	return cordon
+}
+
+// children returns a node's children, or nothing if it holds a value
+func children(node *keyvalues.KeyValue) []*keyvalues.KeyValue {
+	c, _ := node.Children()
+	return c
+}
+
+// missingKeys returns which of keys block has no child for, so that they
+// aren't written back with zero values
+func missingKeys(block *keyvalues.KeyValue, keys ...string) map[string]bool {
+	missing := map[string]bool{}
+	for _, key := range keys {
+		if _, err := block.Find(key); err != nil {
+			missing[key] = true
+		}
+	}
+	return missing
+}
+
+// addPresent adds the children whose keys aren't missing to node
+func addPresent(node *keyvalues.KeyValue, missing map[string]bool, children ...*keyvalues.KeyValue) {
+	for _, child := range children {
+		if !missing[child.Key()] {
+			node.AddChild(child)
+		}
+	}
+}
+
+// addAll adds copies of children to node, so that the tree they were
+// decoded from is left as it was
+func addAll(node *keyvalues.KeyValue, children []*keyvalues.KeyValue) {
+	for _, child := range children {
+		node.AddChild(clone(child))
+	}
+}
+
+// clone returns a copy of node and everything it contains
+func clone(node *keyvalues.KeyValue) *keyvalues.KeyValue {
+	if !node.HasChildren() {
+		value, _ := node.Value()
+		return keyvalues.NewKeyValuePair(node.Key(), value, node.Type())
+	}
+	copied := keyvalues.NewKeyValueArray(node.Key())
+	for _, child := range children(node) {
+		copied.AddChild(clone(child))
+	}
+	return copied
+}
+
+func newInt(key string, value int) *keyvalues.KeyValue {
+	return keyvalues.NewKeyValue(key, strconv.Itoa(value))
+}
+
+func newFloat(key string, value float64) *keyvalues.KeyValue {
+	return keyvalues.NewKeyValue(key, strconv.FormatFloat(value, 'f', -1, 64))
+}
+
+func newBool(key string, value bool) *keyvalues.KeyValue {
+	if value {
+		return keyvalues.NewKeyValue(key, "1")
+	}
+	return keyvalues.NewKeyValue(key, "0")
+}
@@ -0,0 +1,249 @@
+package vmf
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/galaco/KeyValues"
+)
+
+const sampleVmf = `versioninfo
+{
+	"editorversion" "400"
+	"editorbuild" "8864"
+	"mapversion" "3"
+	"formatversion" "100"
+	"prefab" "0"
+}
+visgroups
+{
+	visgroup
+	{
+		"name" "Detail"
+		"visgroupid" "1"
+		"color" "65 45 0"
+		visgroup
+		{
+			"name" "Props"
+			"visgroupid" "2"
+			"color" "0 128 0"
+		}
+	}
+}
+viewsettings
+{
+	"bSnapToGrid" "1"
+	"bShowGrid" "1"
+	"bShowLogicalGrid" "0"
+	"nGridSpacing" "64"
+	"bShow3DGrid" "0"
+}
+world
+{
+	"id" "1"
+	"mapversion" "3"
+	"classname" "worldspawn"
+	"skyname" "sky_day01_01"
+	solid
+	{
+		"id" "2"
+		side
+		{
+			"id" "1"
+			"plane" "(-64 64 64) (64 64 64) (64 -64 64)"
+			"material" "DEV/DEV_MEASUREGENERIC01"
+			"uaxis" "[1 0 0 0] 0.25"
+			"vaxis" "[0 -1 0 0] 0.25"
+			"rotation" "0"
+			"lightmapscale" "16"
+			"smoothing_groups" "0"
+		}
+		editor
+		{
+			"color" "0 180 0"
+			"visgroupid" "1"
+			"visgroupshown" "1"
+			"visgroupautoshown" "1"
+		}
+	}
+	"customblock"
+	{
+		"foo" "bar"
+	}
+}
+entity
+{
+	"id" "3"
+	"classname" "logic_relay"
+	"targetname" "relay"
+	connections
+	{
+		"OnTrigger" "door,Open,,0,-1"
+	}
+	editor
+	{
+		"color" "220 30 220"
+		"visgroupshown" "1"
+		"visgroupautoshown" "1"
+		"logicalpos" "[0 500]"
+	}
+}
+hidden
+{
+	entity
+	{
+		"id" "4"
+		"classname" "info_target"
+	}
+}
+cameras
+{
+	"activecamera" "0"
+	camera
+	{
+		"position" "[0 0 0]"
+		"look" "[0 1 0]"
+	}
+}
+cordons
+{
+	"active" "0"
+}
+`
+
+func readVmf(t *testing.T, data string) *Vmf {
+	reader := keyvalues.NewReader(strings.NewReader(data))
+	kv, err := reader.Read()
+	if err != nil {
+		t.Fatal(err)
+	}
+	vmf, err := FromKeyValue(&kv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return vmf
+}
+
+func writeVmf(t *testing.T, vmf *Vmf) string {
+	var buf bytes.Buffer
+	writer := keyvalues.NewWriter(&buf)
+	if err := writer.Write(vmf.ToKeyValue()); err != nil {
+		t.Fatal(err)
+	}
+	return buf.String()
+}
+
+func TestFromKeyValue(t *testing.T) {
+	vmf := readVmf(t, sampleVmf)
+
+	if vmf.VersionInfo.EditorBuild != 8864 || vmf.VersionInfo.FormatVersion != 100 {
+		t.Errorf("unexpected versioninfo: %+v", vmf.VersionInfo)
+	}
+	if len(vmf.VisGroups.Groups) != 1 || len(vmf.VisGroups.Groups[0].Children) != 1 || vmf.VisGroups.Groups[0].Children[0].Name != "Props" {
+		t.Errorf("unexpected visgroups: %+v", vmf.VisGroups)
+	}
+	if !vmf.ViewSettings.SnapToGrid || vmf.ViewSettings.GridSpacing != 64 {
+		t.Errorf("unexpected viewsettings: %+v", vmf.ViewSettings)
+	}
+	if vmf.World.ClassName != "worldspawn" || len(vmf.World.Solids) != 1 {
+		t.Errorf("unexpected world: %+v", vmf.World)
+	}
+	if sky, ok := vmf.World.Property("SkyName"); !ok || sky != "sky_day01_01" {
+		t.Error("world property not found")
+	}
+	if len(vmf.World.Unknown) != 1 || vmf.World.Unknown[0].Key() != "customblock" {
+		t.Error("unknown block was not kept")
+	}
+	side := vmf.World.Solids[0].Sides[0]
+	if side.Material != "DEV/DEV_MEASUREGENERIC01" || side.LightmapScale != 16 {
+		t.Errorf("unexpected side: %+v", side)
+	}
+	if editor := vmf.World.Solids[0].Editor; editor == nil || len(editor.VisGroupIDs) != 1 || editor.VisGroupIDs[0] != 1 {
+		t.Errorf("unexpected solid editor: %+v", editor)
+	}
+	if len(vmf.Entities) != 1 || len(vmf.Entities[0].Connections) != 1 {
+		t.Errorf("unexpected entities: %+v", vmf.Entities)
+	}
+	if len(vmf.Hidden) != 1 || len(vmf.Hidden[0].Entities) != 1 || vmf.Hidden[0].Entities[0].ID != 4 {
+		t.Errorf("unexpected hidden: %+v", vmf.Hidden)
+	}
+	if len(vmf.Cameras.Cameras) != 1 || vmf.Cameras.Cameras[0].Look != "[0 1 0]" {
+		t.Errorf("unexpected cameras: %+v", vmf.Cameras)
+	}
+}
+
+func TestFromKeyValue_InvalidValue(t *testing.T) {
+	reader := keyvalues.NewReader(strings.NewReader("world\n{\n\t\"id\" \"one\"\n}\nentity\n{\n}\n"))
+	kv, _ := reader.Read()
+	if _, err := FromKeyValue(&kv); err == nil {
+		t.Error("expected error, but received none")
+	}
+}
+
+func TestVmf_ToKeyValue(t *testing.T) {
+	written := writeVmf(t, readVmf(t, sampleVmf))
+	rewritten := writeVmf(t, readVmf(t, written))
+	if written != rewritten {
+		t.Errorf("round trip is not stable. first:\n%s\nsecond:\n%s", written, rewritten)
+	}
+	if !strings.Contains(written, "\"customblock\"") {
+		t.Error("unknown block was not written")
+	}
+	if !strings.Contains(written, "\"OnTrigger\" \"door,Open,,0,-1\"") {
+		t.Error("connections were not written")
+	}
+}
+
+func TestVmf_ToKeyValue_LeavesSourceTree(t *testing.T) {
+	reader := keyvalues.NewReader(strings.NewReader(sampleVmf))
+	kv, err := reader.Read()
+	if err != nil {
+		t.Fatal(err)
+	}
+	vmf, err := FromKeyValue(&kv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	unknown := vmf.World.Unknown[0]
+	parent := unknown.Parent()
+
+	first := vmf.ToKeyValue()
+	second := vmf.ToKeyValue()
+	if unknown.Parent() != parent {
+		t.Error("unknown block was moved out of the source tree")
+	}
+	firstWorld, _ := first.Find("world")
+	secondWorld, _ := second.Find("world")
+	firstBlock, _ := firstWorld.Find("customblock")
+	secondBlock, _ := secondWorld.Find("customblock")
+	if firstBlock == unknown || firstBlock == secondBlock {
+		t.Error("converted trees share nodes")
+	}
+}
+
+func TestVmf_ToKeyValue_VisGroupsUnknown(t *testing.T) {
+	vmf := readVmf(t, "visgroups\n{\n\t\"custom\" \"1\"\n}\nworld\n{\n\t\"id\" \"1\"\n}\n")
+	if vmf.VisGroups == nil || len(vmf.VisGroups.Unknown) != 1 {
+		t.Fatalf("unexpected visgroups: %+v", vmf.VisGroups)
+	}
+	if written := writeVmf(t, vmf); !strings.Contains(written, "\"custom\" \"1\"") {
+		t.Errorf("unknown visgroups key was not written:\n%s", written)
+	}
+}
+
+func TestVmf_ToKeyValue_OnlyBlocksRead(t *testing.T) {
+	vmf := readVmf(t, "world\n{\n\t\"id\" \"1\"\n}\n")
+	written := writeVmf(t, vmf)
+	for _, key := range []string{keyVisGroups, keyViewSettings, keyCameras, keyCordons} {
+		if strings.Contains(written, "\""+key+"\"") {
+			t.Errorf("%s was written, but not read:\n%s", key, written)
+		}
+	}
+}
+
+func TestNewFloat(t *testing.T) {
+	if value, _ := newFloat("rotation", 0.00001).Value(); value != "0.00001" {
+		t.Errorf("unexpected value: %s", value)
+	}
+}
@@ -0,0 +1,171 @@
+package vmt
+
+import (
+	"path"
+	"strings"
+
+	"github.com/galaco/KeyValues"
+)
+
+const keyProxies = "proxies"
+const valueEnvCubemap = "env_cubemap"
+
+// textureKeys are material parameters whose value is a texture path
+var textureKeys = map[string]bool{
+	"$basetexture":              true,
+	"$basetexture2":             true,
+	"$basetexture3":             true,
+	"$basetexture4":             true,
+	"$bumpmap":                  true,
+	"$bumpmap2":                 true,
+	"$normalmap":                true,
+	"$normalmap2":               true,
+	"$envmap":                   true,
+	"$envmapmask":               true,
+	"$envmapmask2":              true,
+	"$detail":                   true,
+	"$detail2":                  true,
+	"$selfillummask":            true,
+	"$selfillumtexture":         true,
+	"$blendmodulatetexture":     true,
+	"$phongexponenttexture":     true,
+	"$phongwarptexture":         true,
+	"$lightwarptexture":         true,
+	"$ambientoccltexture":       true,
+	"$iris":                     true,
+	"$corneatexture":            true,
+	"$dudvmap":                  true,
+	"$refracttexture":           true,
+	"$reflecttexture":           true,
+	"$refracttinttexture":       true,
+	"$texture2":                 true,
+	"$flowmap":                  true,
+	"$flow_noise_texture":       true,
+	"$fleshinteriortexture":     true,
+	"$fleshbordertexture1d":     true,
+	"$fleshnormaltexture":       true,
+	"$fleshsubsurfacetexture":   true,
+	"$emissiveblendtexture":     true,
+	"$emissiveblendbasetexture": true,
+	"$emissiveblendflowtexture": true,
+	"$tintmasktexture":          true,
+	"%tooltexture":              true,
+}
+
+// materialKeys are material parameters whose value is another material
+var materialKeys = map[string]bool{
+	"include":            true,
+	"$bottommaterial":    true,
+	"$underwateroverlay": true,
+	"$fallbackmaterial":  true,
+	"$crackmaterial":     true,
+}
+
+// Dependencies returns every asset referenced by a parsed material.
+// Textures are normalized to materials/*.vtf paths, and referenced materials
+// (such as a patch material's include) to materials/*.vmt paths.
+// Each path appears once, in the order it was first referenced.
+func Dependencies(material *keyvalues.KeyValue) []string {
+	deps := dependencyList{seen: map[string]bool{}}
+	deps.walk(material, false)
+	return deps.paths
+}
+
+// Textures returns only the texture dependencies of a parsed material,
+// normalized to materials/*.vtf paths.
+func Textures(material *keyvalues.KeyValue) []string {
+	return filter(Dependencies(material), ".vtf")
+}
+
+// Materials returns only the material dependencies of a parsed material,
+// normalized to materials/*.vmt paths.
+func Materials(material *keyvalues.KeyValue) []string {
+	return filter(Dependencies(material), ".vmt")
+}
+
+// NormalizeTexturePath converts a texture reference as written in a material
+// into a materials/*.vtf path.
+func NormalizeTexturePath(texture string) string {
+	return normalize(texture, ".vtf")
+}
+
+// NormalizeMaterialPath converts a material reference into a materials/*.vmt path.
+func NormalizeMaterialPath(material string) string {
+	return normalize(material, ".vmt")
+}
+
+// dependencyList collects unique dependency paths in discovery order
+type dependencyList struct {
+	paths []string
+	seen  map[string]bool
+}
+
+func (deps *dependencyList) add(p string) {
+	if deps.seen[p] {
+		return
+	}
+	deps.seen[p] = true
+	deps.paths = append(deps.paths, p)
+}
+
+// walk visits every node below node.
+// Proxy blocks don't use the material parameter names, so any proxy key that
+// refers to a texture, and doesn't just name another material variable, is
+// treated as a texture path.
+func (deps *dependencyList) walk(node *keyvalues.KeyValue, inProxies bool) {
+	if !node.HasChildren() {
+		deps.visit(node, inProxies)
+		return
+	}
+	children, _ := node.Children()
+	for _, child := range children {
+		deps.walk(child, inProxies || strings.ToLower(child.Key()) == keyProxies)
+	}
+}
+
+func (deps *dependencyList) visit(node *keyvalues.KeyValue, inProxies bool) {
+	value, err := node.AsString()
+	if err != nil || len(value) == 0 {
+		return
+	}
+	key := strings.ToLower(node.Key())
+
+	switch {
+	case materialKeys[key]:
+		deps.add(NormalizeMaterialPath(value))
+	case textureKeys[key]:
+		if strings.ToLower(value) == valueEnvCubemap {
+			return
+		}
+		deps.add(NormalizeTexturePath(value))
+	case inProxies && strings.Contains(key, "texture"):
+		if strings.HasPrefix(value, "$") {
+			return
+		}
+		deps.add(NormalizeTexturePath(value))
+	}
+}
+
+// normalize lowercases a path, converts separators, and ensures it is
+// rooted in materials/ with the given extension.
+// Valve tools accept references with or without the materials/ prefix and
+// file extension, so both are optional here.
+func normalize(p string, extension string) string {
+	p = strings.ToLower(strings.TrimSpace(p))
+	p = strings.Replace(p, "\\", "/", -1)
+	p = path.Clean("/" + p)
+	p = strings.TrimPrefix(p, "/")
+	p = strings.TrimPrefix(p, "materials/")
+	p = strings.TrimSuffix(p, extension)
+
+	return "materials/" + p + extension
+}
+
+func filter(paths []string, extension string) (filtered []string) {
+	for _, p := range paths {
+		if strings.HasSuffix(p, extension) {
+			filtered = append(filtered, p)
+		}
+	}
+	return filtered
+}
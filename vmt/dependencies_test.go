@@ -0,0 +1,83 @@
+package vmt
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/galaco/KeyValues"
+)
+
+func readMaterial(t *testing.T, data string) *keyvalues.KeyValue {
+	reader := keyvalues.NewReader(strings.NewReader(data))
+	kv, err := reader.Read()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &kv
+}
+
+func TestDependencies(t *testing.T) {
+	material := readMaterial(t, `"LightmappedGeneric"
+{
+	"$basetexture" "Concrete\Floor01.vtf"
+	"$bumpmap" "materials/concrete/floor01_normal"
+	"$envmap" "env_cubemap"
+	"$envmapmask" "concrete/floor01_mask"
+	"%tooltexture" "concrete/floor01"
+	"$surfaceprop" "concrete"
+	"Proxies"
+	{
+		"AnimatedTexture"
+		{
+			"animatedtexturevar" "$basetexture"
+			"animatedtextureframerate" "10"
+		}
+		"CustomToggle"
+		{
+			"toggletexture" "concrete/floor01_alt"
+		}
+	}
+}
+`)
+	expected := []string{
+		"materials/concrete/floor01.vtf",
+		"materials/concrete/floor01_normal.vtf",
+		"materials/concrete/floor01_mask.vtf",
+		"materials/concrete/floor01_alt.vtf",
+	}
+	actual := Dependencies(material)
+	if strings.Join(actual, ",") != strings.Join(expected, ",") {
+		t.Errorf("unexpected dependencies. expected %v, received: %v", expected, actual)
+	}
+}
+
+func TestDependencies_Patch(t *testing.T) {
+	material := readMaterial(t, `"patch"
+{
+	"include" "materials/concrete/floor01.vmt"
+	"insert"
+	{
+		"$detail" "detail\\noise_detail_01"
+	}
+}
+`)
+	if materials := Materials(material); len(materials) != 1 || materials[0] != "materials/concrete/floor01.vmt" {
+		t.Errorf("unexpected included materials: %v", materials)
+	}
+	if textures := Textures(material); len(textures) != 1 || textures[0] != "materials/detail/noise_detail_01.vtf" {
+		t.Errorf("unexpected textures: %v", textures)
+	}
+}
+
+func TestNormalizeTexturePath(t *testing.T) {
+	for input, expected := range map[string]string{
+		"foo/bar":                 "materials/foo/bar.vtf",
+		"Foo\\Bar.vtf":            "materials/foo/bar.vtf",
+		"materials/foo//bar":      "materials/foo/bar.vtf",
+		"/materials/foo/bar.VTF ": "materials/foo/bar.vtf",
+	} {
+		if actual := NormalizeTexturePath(input); actual != expected {
+			t.Errorf("unexpected path for %s. expected %s, received: %s", input, expected, actual)
+		}
+	}
+}
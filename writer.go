@@ -0,0 +1,103 @@
+package keyvalues
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"strings"
+)
+
+const tokenNewLine = "\n"
+
+// Writer is used for writing a KeyValue tree back out in text format
+// Output is laid out the same way Hammer writes files: every key and value
+// is quoted, and every scope opens on its own line.
+// The format has no escape sequences, and Write returns an error for keys and
+// values that wouldn't read back the same: those containing quotes, line
+// breaks, tabs or closing braces, or with whitespace at either end, and the
+// keys of values that contain spaces.
+// Backslashes are written as they are, as in Windows paths.
+type Writer struct {
+	file io.Writer
+}
+
+// NewWriter Return a new KeyValue Writer
+func NewWriter(file io.Writer) Writer {
+	writer := Writer{}
+	writer.file = file
+	return writer
+}
+
+// Write serializes a KeyValue tree to the underlying stream
+// A root node with the Key `$root` (as created by Reader for files with
+// multiple root nodes) is not written itself; its children are written as
+// root nodes instead.
+func (writer *Writer) Write(keyvalue *KeyValue) error {
+	bufWriter := bufio.NewWriter(writer.file)
+
+	if keyvalue.Key() == RootNodeKey && keyvalue.HasChildren() {
+		children, _ := keyvalue.Children()
+		for _, child := range children {
+			if err := writeNode(bufWriter, child, 0); err != nil {
+				return err
+			}
+		}
+	} else if err := writeNode(bufWriter, keyvalue, 0); err != nil {
+		return err
+	}
+
+	return bufWriter.Flush()
+}
+
+// writeNode writes a single node, and all of its children, at the given
+// indentation depth
+func writeNode(writer *bufio.Writer, node *KeyValue, depth int) (err error) {
+	indent := strings.Repeat(tokenTab, depth)
+
+	if reason := unwritable(node.Key()); reason != "" {
+		return errors.New("cannot write key " + quote(node.Key()) + " containing " + reason)
+	}
+	if !node.HasChildren() {
+		if strings.Contains(node.Key(), tokenSeparator) {
+			return errors.New("cannot write key " + quote(node.Key()) + " containing a space, which ends the key of a value")
+		}
+		value, _ := node.Value()
+		if reason := unwritable(value); reason != "" {
+			return errors.New("cannot write value of " + node.Key() + " containing " + reason)
+		}
+		_, err = writer.WriteString(indent + quote(node.Key()) + tokenSeparator + quote(value) + tokenNewLine)
+		return err
+	}
+
+	if _, err = writer.WriteString(indent + quote(node.Key()) + tokenNewLine + indent + tokenEnterScope + tokenNewLine); err != nil {
+		return err
+	}
+	children, _ := node.Children()
+	for _, child := range children {
+		if err = writeNode(writer, child, depth+1); err != nil {
+			return err
+		}
+	}
+	_, err = writer.WriteString(indent + tokenExitScope + tokenNewLine)
+	return err
+}
+
+// unwritable returns why text would not read back the same once quoted, or
+// an empty string if it would
+func unwritable(text string) string {
+	switch {
+	case strings.ContainsAny(text, tokenEscape+"\r\n"):
+		return "a quote or line break"
+	case strings.Contains(text, tokenTab):
+		return "a tab, which is read as a space"
+	case strings.Contains(text, tokenExitScope):
+		return "a closing brace, which ends a block even in quotes"
+	case strings.TrimSpace(text) != text:
+		return "leading or trailing whitespace"
+	}
+	return ""
+}
+
+func quote(value string) string {
+	return tokenEscape + value + tokenEscape
+}
@@ -0,0 +1,100 @@
+package keyvalues
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestWriter_Write(t *testing.T) {
+	kv := NewKeyValueArray("foo",
+		NewKeyValue("bar", "hello world"),
+		NewKeyValueArray("baz",
+			NewKeyValue("bat", "123")))
+
+	expected := "\"foo\"\n{\n\t\"bar\" \"hello world\"\n\t\"baz\"\n\t{\n\t\t\"bat\" \"123\"\n\t}\n}\n"
+
+	var buf bytes.Buffer
+	writer := NewWriter(&buf)
+	if err := writer.Write(kv); err != nil {
+		t.Error(err)
+	}
+	if buf.String() != expected {
+		t.Errorf("unexpected output. expected:\n%s\nreceived:\n%s", expected, buf.String())
+	}
+}
+
+func TestWriter_Write_MultipleRoots(t *testing.T) {
+	data := "\"foo\"\n{\n\t\"bar\" \"1\"\n}\n\"baz\"\n{\n\t\"bat\" \"2.5\"\n}\n"
+	reader := NewReader(strings.NewReader(data))
+	kv, err := reader.Read()
+	if err != nil {
+		t.Error(err)
+	}
+
+	var buf bytes.Buffer
+	writer := NewWriter(&buf)
+	if err := writer.Write(&kv); err != nil {
+		t.Error(err)
+	}
+	if buf.String() != data {
+		t.Errorf("unexpected output. expected:\n%s\nreceived:\n%s", data, buf.String())
+	}
+}
+
+func TestWriter_Write_RoundTrip(t *testing.T) {
+	kv := NewKeyValueArray("entity",
+		NewKeyValue("file", `instances\relay.vmf`),
+		NewKeyValue("message", "hello world"),
+		NewKeyValue("origin", "0 0 64.5"),
+		NewKeyValue("brace", "{"),
+		NewKeyValueArray("my block", NewKeyValue("a", "b")))
+
+	var buf bytes.Buffer
+	writer := NewWriter(&buf)
+	if err := writer.Write(kv); err != nil {
+		t.Fatal(err)
+	}
+	reader := NewReader(&buf)
+	read, err := reader.Read()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = read.Find("my block"); err != nil {
+		t.Error(err)
+	}
+	for _, key := range []string{"file", "message", "origin", "brace"} {
+		expected, _ := kv.Find(key)
+		actual, err := read.Find(key)
+		if err != nil {
+			t.Error(err)
+			continue
+		}
+		expectedValue, _ := expected.Value()
+		actualValue, _ := actual.Value()
+		if actualValue != expectedValue {
+			t.Errorf("%s: expected %q, got %q", key, expectedValue, actualValue)
+		}
+	}
+}
+
+func TestWriter_Write_Unwritable(t *testing.T) {
+	for _, kv := range []*KeyValue{
+		NewKeyValueArray("foo", NewKeyValue("b", `say "hi"`)),
+		NewKeyValueArray("foo", NewKeyValue("b", "two\nlines")),
+		NewKeyValueArray("foo", NewKeyValue(`"b"`, "c")),
+		NewKeyValueArray(`fo"o`),
+		NewKeyValueArray("foo", NewKeyValue("b", "a}b")),
+		NewKeyValueArray("foo", NewKeyValueArray("}")),
+		NewKeyValueArray("foo", NewKeyValue("my key", "c")),
+		NewKeyValueArray("foo", NewKeyValue("b", " padded ")),
+		NewKeyValueArray("foo", NewKeyValue("b", "a\tb")),
+		NewKeyValueArray(" foo"),
+	} {
+		var buf bytes.Buffer
+		writer := NewWriter(&buf)
+		if err := writer.Write(kv); err == nil {
+			t.Errorf("expected error, but received none for:\n%s", buf.String())
+		}
+	}
+}
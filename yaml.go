@@ -0,0 +1,493 @@
+package keyvalues
+
+import (
+	"bytes"
+	"errors"
+	"math"
+	"strconv"
+	"strings"
+)
+
+const yamlIndent = "  "
+
+// ToYAML converts the tree into a YAML mapping containing this KeyValue, or
+// the children of a `$root` node.
+// Blocks become mappings in their original key order, and the values of a
+// key that appears more than once are collapsed into a sequence, as in
+// JSONFriendly. Numbers are typed according to their ValueType; strings that
+// YAML would read as another type are quoted.
+func (node *KeyValue) ToYAML() ([]byte, error) {
+	var buf bytes.Buffer
+	root := toFriendlyRoot(node, friendlyTypedLeaf)
+	if len(root) == 0 {
+		buf.WriteString("{}\n")
+		return buf.Bytes(), nil
+	}
+	if err := writeYAMLMapping(&buf, root, 0); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// FromYAML parses a YAML mapping into a KeyValue tree, the same way FromJSON
+// does in JSONFriendly mode.
+// Only the subset of YAML that ToYAML writes is supported: a single document
+// of block mappings and sequences, single line plain, single quoted and
+// double quoted scalars, empty flow collections, and comments. Flow
+// collections with items, block scalars, multi-line scalars, anchors,
+// aliases, tags, explicit keys, directives and multiple documents return an
+// error rather than being misread.
+func FromYAML(data []byte) (*KeyValue, error) {
+	p, err := newYAMLParser(string(data))
+	if err != nil {
+		return nil, err
+	}
+	if len(p.lines) == 0 || len(p.lines) == 1 && p.lines[0].text == "{}" {
+		return fromFriendlyRoot(OrderedMap{})
+	}
+
+	value, err := p.readBlock(p.lines[0].indent)
+	if err != nil {
+		return nil, err
+	}
+	if p.pos < len(p.lines) {
+		return nil, p.error("unexpected indentation")
+	}
+	root, ok := value.(OrderedMap)
+	if !ok {
+		return nil, errors.New("yaml root must be a mapping")
+	}
+	return fromFriendlyRoot(root)
+}
+
+func writeYAMLMapping(buf *bytes.Buffer, m OrderedMap, depth int) error {
+	for _, item := range m {
+		buf.WriteString(strings.Repeat(yamlIndent, depth) + formatYAMLString(item.Key) + ":")
+		if err := writeYAMLValue(buf, item.Value, depth); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func writeYAMLSequence(buf *bytes.Buffer, items []interface{}, depth int) error {
+	for _, item := range items {
+		buf.WriteString(strings.Repeat(yamlIndent, depth) + "-")
+		// collections start on the same line as their dash, at the indentation
+		// of the next depth
+		var nested bytes.Buffer
+		var err error
+		switch v := item.(type) {
+		case OrderedMap:
+			if len(v) == 0 {
+				buf.WriteString(" {}\n")
+				continue
+			}
+			err = writeYAMLMapping(&nested, v, depth+1)
+		case []interface{}:
+			if len(v) == 0 {
+				buf.WriteString(" []\n")
+				continue
+			}
+			err = writeYAMLSequence(&nested, v, depth+1)
+		default:
+			err = writeYAMLValue(buf, item, depth)
+			if err != nil {
+				return err
+			}
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		buf.WriteString(" ")
+		buf.Write(nested.Bytes()[len(yamlIndent)*(depth+1):])
+	}
+	return nil
+}
+
+// writeYAMLValue writes the value of a mapping key or sequence item, after
+// its key or dash
+func writeYAMLValue(buf *bytes.Buffer, value interface{}, depth int) error {
+	switch v := value.(type) {
+	case OrderedMap:
+		if len(v) == 0 {
+			buf.WriteString(" {}\n")
+			return nil
+		}
+		buf.WriteString("\n")
+		return writeYAMLMapping(buf, v, depth+1)
+	case []interface{}:
+		if len(v) == 0 {
+			buf.WriteString(" []\n")
+			return nil
+		}
+		buf.WriteString("\n")
+		return writeYAMLSequence(buf, v, depth+1)
+	}
+
+	scalar, err := formatYAMLScalar(value)
+	if err != nil {
+		return err
+	}
+	buf.WriteString(" " + scalar + "\n")
+	return nil
+}
+
+func formatYAMLScalar(value interface{}) (string, error) {
+	switch v := value.(type) {
+	case nil:
+		return "null", nil
+	case bool:
+		return strconv.FormatBool(v), nil
+	case string:
+		return formatYAMLString(v), nil
+	case int64:
+		return strconv.FormatInt(v, 10), nil
+	case uint64:
+		return strconv.FormatUint(v, 10), nil
+	case float64:
+		switch {
+		case math.IsNaN(v):
+			return ".nan", nil
+		case math.IsInf(v, 1):
+			return ".inf", nil
+		case math.IsInf(v, -1):
+			return "-.inf", nil
+		}
+		return formatFriendlyFloat(v, 64), nil
+	}
+	return "", errors.New("unsupported yaml value")
+}
+
+// formatYAMLString returns value as a plain scalar if YAML would read it
+// back as the same string, or double quoted otherwise
+func formatYAMLString(value string) string {
+	if len(value) == 0 ||
+		value != strings.TrimSpace(value) ||
+		strings.ContainsAny(value[:1], "-?:,[]{}#&*!|>'\"%@`") ||
+		strings.Contains(value, ": ") || strings.Contains(value, " #") || strings.HasSuffix(value, ":") {
+		return strconv.Quote(value)
+	}
+	for _, char := range value {
+		if char < ' ' || char == 0x7f {
+			return strconv.Quote(value)
+		}
+	}
+	// words that YAML 1.1 reads as booleans are quoted too, for older readers
+	switch strings.ToLower(value) {
+	case "yes", "no", "on", "off", "y", "n":
+		return strconv.Quote(value)
+	}
+	if _, ok := parseYAMLPlain(value).(string); !ok {
+		return strconv.Quote(value)
+	}
+	return value
+}
+
+// parseYAMLPlain resolves the type of a plain scalar
+func parseYAMLPlain(value string) interface{} {
+	switch value {
+	case "", "~", "null", "Null", "NULL":
+		return nil
+	case "true", "True", "TRUE":
+		return true
+	case "false", "False", "FALSE":
+		return false
+	case ".inf", ".Inf", ".INF", "+.inf", "+.Inf", "+.INF":
+		return math.Inf(1)
+	case "-.inf", "-.Inf", "-.INF":
+		return math.Inf(-1)
+	case ".nan", ".NaN", ".NAN":
+		return math.NaN()
+	}
+	if i, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return i
+	}
+	if u, err := strconv.ParseUint(value, 10, 64); err == nil {
+		return u
+	}
+	if strings.HasPrefix(value, "0x") || strings.HasPrefix(value, "0o") {
+		if i, err := strconv.ParseInt(value, 0, 64); err == nil {
+			return i
+		}
+	}
+	if strings.Trim(value, "0123456789.eE+-") == "" {
+		if f, err := strconv.ParseFloat(value, 64); err == nil {
+			return f
+		}
+	}
+	return value
+}
+
+// yamlLine is a single line of a YAML document with its comment removed
+type yamlLine struct {
+	indent int
+	text   string
+	number int
+}
+
+// yamlParser holds the lines of a YAML document, and the next line to parse
+type yamlParser struct {
+	lines []yamlLine
+	pos   int
+}
+
+func newYAMLParser(data string) (*yamlParser, error) {
+	p := &yamlParser{}
+	ended := false
+	for idx, raw := range strings.Split(data, "\n") {
+		text := strings.TrimRight(stripYAMLComment(strings.TrimSuffix(raw, "\r")), " \t")
+		trimmed := strings.TrimLeft(text, " ")
+		number := " on line " + strconv.Itoa(idx+1)
+		switch {
+		case len(trimmed) == 0:
+			continue
+		case text == "---" && len(p.lines) == 0 && !ended:
+			continue
+		case text == "...":
+			ended = true
+			continue
+		case ended, text == "---":
+			return nil, errors.New("multiple documents are not supported" + number)
+		case strings.HasPrefix(text, "--- "):
+			return nil, errors.New("values on the document start line are not supported" + number)
+		case text[0] == '%':
+			return nil, errors.New("directives are not supported" + number)
+		case trimmed[0] == '\t':
+			return nil, errors.New("tabs cannot be used for indentation" + number)
+		}
+		p.lines = append(p.lines, yamlLine{
+			indent: len(text) - len(trimmed),
+			text:   trimmed,
+			number: idx + 1,
+		})
+	}
+	return p, nil
+}
+
+// readBlock reads the mapping or sequence starting at the current line
+func (p *yamlParser) readBlock(indent int) (interface{}, error) {
+	if isYAMLSequenceItem(p.lines[p.pos].text) {
+		return p.readSequence(indent)
+	}
+	return p.readMapping(indent)
+}
+
+func (p *yamlParser) readMapping(indent int) (OrderedMap, error) {
+	m := OrderedMap{}
+	for p.pos < len(p.lines) {
+		line := p.lines[p.pos]
+		if line.indent < indent || line.indent == indent && isYAMLSequenceItem(line.text) {
+			break
+		}
+		if line.indent > indent {
+			return nil, p.error("unexpected indentation")
+		}
+		if err := checkYAMLKey(line.text); err != nil {
+			return nil, p.error(err.Error())
+		}
+		key, rest, ok := splitYAMLKey(line.text)
+		if !ok {
+			return nil, p.error("expected a key")
+		}
+		value, err := p.readValue(rest, indent)
+		if err != nil {
+			return nil, err
+		}
+		m = append(m, MapItem{Key: key, Value: value})
+	}
+	return m, nil
+}
+
+func (p *yamlParser) readSequence(indent int) ([]interface{}, error) {
+	items := []interface{}{}
+	for p.pos < len(p.lines) {
+		line := p.lines[p.pos]
+		if line.indent < indent || !isYAMLSequenceItem(line.text) {
+			break
+		}
+		if line.indent > indent {
+			return nil, p.error("unexpected indentation")
+		}
+
+		rest := strings.TrimLeft(line.text[1:], " ")
+		if _, _, isKey := splitYAMLKey(rest); isKey || isYAMLSequenceItem(rest) {
+			// a collection starting on the same line as the dash is read as if
+			// it started on its own line, at the same column
+			offset := len(line.text) - len(rest)
+			p.lines[p.pos] = yamlLine{indent: indent + offset, text: rest, number: line.number}
+			item, err := p.readBlock(indent + offset)
+			if err != nil {
+				return nil, err
+			}
+			items = append(items, item)
+			continue
+		}
+
+		item, err := p.readValue(rest, indent)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+	return items, nil
+}
+
+// readValue reads the value after a key or dash on the current line, which
+// is a nested block if there is nothing else on the line
+func (p *yamlParser) readValue(rest string, indent int) (interface{}, error) {
+	line := p.lines[p.pos]
+	p.pos++
+	if len(rest) > 0 {
+		value, err := parseYAMLScalar(rest)
+		if err != nil {
+			return nil, errors.New(err.Error() + " on line " + strconv.Itoa(line.number))
+		}
+		// a more indented line continues the scalar
+		if p.pos < len(p.lines) && p.lines[p.pos].indent > indent {
+			return nil, p.error("multi-line scalars are not supported")
+		}
+		return value, nil
+	}
+	if p.pos == len(p.lines) {
+		return nil, nil
+	}
+	next := p.lines[p.pos]
+	// sequences may be at the same indentation as their key
+	if next.indent > indent || next.indent == indent && isYAMLSequenceItem(next.text) && !isYAMLSequenceItem(line.text) {
+		return p.readBlock(next.indent)
+	}
+	return nil, nil
+}
+
+func (p *yamlParser) error(message string) error {
+	line := p.lines[len(p.lines)-1].number
+	if p.pos < len(p.lines) {
+		line = p.lines[p.pos].number
+	}
+	return errors.New(message + " on line " + strconv.Itoa(line))
+}
+
+func isYAMLSequenceItem(text string) bool {
+	return text == "-" || strings.HasPrefix(text, "- ")
+}
+
+// splitYAMLKey splits a mapping line into its key, and the rest of the line
+// after the colon
+func splitYAMLKey(text string) (key string, rest string, ok bool) {
+	if len(text) == 0 {
+		return "", "", false
+	}
+	if text[0] == '"' || text[0] == '\'' {
+		end := endOfYAMLQuoted(text)
+		if end < 0 || end == len(text) || text[end] != ':' {
+			return "", "", false
+		}
+		if end+1 < len(text) && text[end+1] != ' ' {
+			return "", "", false
+		}
+		key, err := parseYAMLScalar(text[:end])
+		if err != nil {
+			return "", "", false
+		}
+		return key.(string), strings.TrimSpace(text[end+1:]), true
+	}
+
+	idx := strings.Index(text, ": ")
+	if idx < 0 {
+		if !strings.HasSuffix(text, ":") {
+			return "", "", false
+		}
+		idx = len(text) - 1
+	}
+	return strings.TrimSpace(text[:idx]), strings.TrimSpace(text[idx+1:]), true
+}
+
+// checkYAMLKey returns an error if a mapping line starts with an indicator
+// that splitYAMLKey would otherwise read as part of a plain key
+func checkYAMLKey(text string) error {
+	switch text[0] {
+	case '{', '[':
+		return errors.New("flow collections are not supported")
+	case '&', '*', '!':
+		return errors.New("anchors, aliases and tags are not supported")
+	case '|', '>':
+		return errors.New("block scalars are not supported")
+	}
+	if text == "?" || strings.HasPrefix(text, "? ") {
+		return errors.New("explicit keys are not supported")
+	}
+	return nil
+}
+
+// parseYAMLScalar parses a plain, quoted or empty flow scalar
+func parseYAMLScalar(text string) (interface{}, error) {
+	if text == "?" || strings.HasPrefix(text, "? ") {
+		return nil, errors.New("explicit keys are not supported")
+	}
+	switch text[0] {
+	case '"', '\'':
+		if endOfYAMLQuoted(text) != len(text) {
+			return nil, errors.New("invalid quoted string")
+		}
+		if text[0] == '\'' {
+			return strings.ReplaceAll(text[1:len(text)-1], "''", "'"), nil
+		}
+		value, err := strconv.Unquote(text)
+		if err != nil {
+			return nil, errors.New("invalid quoted string")
+		}
+		return value, nil
+	case '{', '[':
+		switch text {
+		case "{}":
+			return OrderedMap{}, nil
+		case "[]":
+			return []interface{}{}, nil
+		}
+		return nil, errors.New("flow collections are not supported")
+	case '|', '>':
+		return nil, errors.New("block scalars are not supported")
+	case '&', '*', '!':
+		return nil, errors.New("anchors, aliases and tags are not supported")
+	}
+	return parseYAMLPlain(text), nil
+}
+
+// endOfYAMLQuoted returns the index after the closing quote of the quoted
+// string at the start of text, or -1 if it isn't closed
+func endOfYAMLQuoted(text string) int {
+	quote := text[0]
+	for idx := 1; idx < len(text); idx++ {
+		switch {
+		case quote == '"' && text[idx] == '\\':
+			idx++
+		case quote == '\'' && text[idx] == '\'' && idx+1 < len(text) && text[idx+1] == '\'':
+			idx++
+		case text[idx] == quote:
+			return idx + 1
+		}
+	}
+	return -1
+}
+
+// stripYAMLComment removes a comment from a line, ignoring # in quoted
+// strings. Quotes only start a string at the start of a scalar.
+func stripYAMLComment(line string) string {
+	var quote byte
+	for idx := 0; idx < len(line); idx++ {
+		char := line[idx]
+		switch {
+		case quote == 0 && (char == '"' || char == '\'') && (idx == 0 || line[idx-1] == ' '):
+			quote = char
+		case quote == '"' && char == '\\':
+			idx++
+		case quote != 0 && char == quote:
+			quote = 0
+		case quote == 0 && char == '#' && (idx == 0 || line[idx-1] == ' ' || line[idx-1] == '\t'):
+			return line[:idx]
+		}
+	}
+	return line
+}
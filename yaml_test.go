@@ -0,0 +1,159 @@
+package keyvalues
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestKeyValue_ToYAML(t *testing.T) {
+	kv := readJSONSample(t)
+	kv.AddChild(NewKeyValuePair("label", "yes: no", ValueString))
+	kv.AddChild(NewKeyValueArray("side", NewKeyValue("id", "1"), NewKeyValue("material", "dev/dev_measurewall01a")))
+	kv.AddChild(NewKeyValueArray("side", NewKeyValue("id", "2")))
+	kv.AddChild(NewKeyValueArray("empty"))
+
+	data, err := kv.ToYAML()
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := `root:
+  name: test
+  count: 3
+  scale: 0.5
+  solid:
+    - 1
+    - 2
+  child:
+    id: 7
+  steamid: 76561197960287930
+  label: "yes: no"
+  side:
+    - id: 1
+      material: dev/dev_measurewall01a
+    - id: 2
+  empty: {}
+`
+	if string(data) != expected {
+		t.Errorf("unexpected yaml:\n%s", data)
+	}
+}
+
+func TestFromYAML(t *testing.T) {
+	data := []byte(`# a comment
+root:
+  name: test # trailing comment
+  quoted: "007"
+  single: 'it''s'
+  scale: 1.0
+  solid:
+  - 1
+  - 2
+  side:
+    - id: 1
+      material: dev/dev_measurewall01a
+    -
+      id: 2
+  enabled: true
+other: {}
+`)
+	kv, err := FromYAML(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if kv.Key() != RootNodeKey {
+		t.Fatalf("expected $root node, got %s", kv.Key())
+	}
+	root, _ := kv.Find("root")
+	if name, _ := root.Find("name"); name == nil {
+		t.Fatal("missing name")
+	} else if value, _ := name.Value(); value != "test" {
+		t.Errorf("unexpected name: %s", value)
+	}
+	if quoted, _ := root.Find("quoted"); quoted.Type() != ValueString {
+		t.Errorf("unexpected quoted type: %s", quoted.Type())
+	}
+	if single, _ := root.Find("single"); single == nil {
+		t.Error("missing single quoted string")
+	} else if value, _ := single.Value(); value != "it's" {
+		t.Errorf("unexpected single quoted string: %s", value)
+	}
+	if scale, _ := root.Find("scale"); scale.Type() != ValueFloat {
+		t.Errorf("unexpected scale type: %s", scale.Type())
+	}
+	if solids, _ := root.FindAll("solid"); len(solids) != 2 {
+		t.Errorf("unexpected number of solids: %d", len(solids))
+	}
+	sides, _ := root.FindAll("side")
+	if len(sides) != 2 {
+		t.Fatalf("unexpected number of sides: %d", len(sides))
+	}
+	if id, _ := sides[1].Find("id"); id == nil {
+		t.Error("missing id of second side")
+	}
+
+	if _, err = FromYAML([]byte("root:\n  a: 1\n    b: 2\n")); err == nil {
+		t.Error("expected error for bad indentation")
+	}
+	if _, err = FromYAML([]byte("- 1\n- 2\n")); err == nil {
+		t.Error("expected error for yaml root that isn't a mapping")
+	}
+}
+
+func TestFromYAML_Documents(t *testing.T) {
+	kv, err := FromYAML([]byte("---\na: 1\nb: 2\n...\n# done\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b, _ := kv.Find("b"); b == nil {
+		t.Error("missing b")
+	}
+}
+
+func TestFromYAML_Unsupported(t *testing.T) {
+	samples := map[string]string{
+		"a: [1, 2]\n":                 "flow collections are not supported on line 1",
+		"a: {b: 1}\n":                 "flow collections are not supported on line 1",
+		"{a: 1}\n":                    "flow collections are not supported on line 1",
+		"a:\n  - {b: 1}\n":            "flow collections are not supported on line 2",
+		"a: |\n  text\n":              "block scalars are not supported on line 1",
+		"a: >-\n  text\n":             "block scalars are not supported on line 1",
+		"a: first\n  second\n":        "multi-line scalars are not supported on line 2",
+		"a:\n  - first\n    second\n": "multi-line scalars are not supported on line 3",
+		"a: &anchor 1\nb: *anchor\n":  "anchors, aliases and tags are not supported on line 1",
+		"*alias : 1\n":                "anchors, aliases and tags are not supported on line 1",
+		"a: !!str 1\n":                "anchors, aliases and tags are not supported on line 1",
+		"? a\n: 1\n":                  "explicit keys are not supported on line 1",
+		"a:\n  - ? b\n":               "explicit keys are not supported on line 2",
+		"%YAML 1.2\n---\na: 1\n":      "directives are not supported on line 1",
+		"a: 1\n---\nb: 2\n":           "multiple documents are not supported on line 2",
+		"a: 1\n...\nb: 2\n":           "multiple documents are not supported on line 3",
+		"--- a\n":                     "values on the document start line are not supported on line 1",
+	}
+	for sample, expected := range samples {
+		_, err := FromYAML([]byte(sample))
+		if err == nil {
+			t.Errorf("expected error for %q", sample)
+		} else if err.Error() != expected {
+			t.Errorf("unexpected error for %q: %s", sample, err)
+		}
+	}
+}
+
+func TestKeyValue_ToYAML_RoundTrip(t *testing.T) {
+	kv := readJSONSample(t)
+	data, err := kv.ToYAML()
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, err := FromYAML(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	reencoded, err := result.ToYAML()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(data, reencoded) {
+		t.Errorf("round trip changed yaml:\n%s", reencoded)
+	}
+}